@@ -1,7 +1,81 @@
-package cleardb
+package main
 
-// TODO
-// очистка юзеров без подтверждения
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/config"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/db/cache"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/db/storage"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/services"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/logger"
+)
+
+// очистка БД, запускается по расписанию (cron, k8s CronJob):
+//
+//	неподтвержденные пользователи старше CLEANUP_UNVERIFIED_AFTER - delete/archive по CLEANUP_UNVERIFIED_MODE
+//	аккаунты с истекшим льготным периодом удаления (AUTH_ACCOUNT_DELETION_GRACE) - обезличиваются
+//	индексы сессий user_id:* в Redis - ссылки на истекшие сессии и сессии удаленных пользователей
+//
+// -dry-run ничего не меняет, только показывает отчет. Код выхода 1, если были ошибки
 func main() {
+	var configEnv string
+	var dryRun bool
+
+	flag.StringVar(&configEnv, "configEnv", "", "Path to env-file")
+	flag.BoolVar(&dryRun, "dry-run", false, "Only report what would be cleaned")
+	flag.Parse()
+
+	// os.Exit не выполняет defer, поэтому ресурсы закрываются внутри run
+	os.Exit(run(configEnv, dryRun))
+}
+
+func run(configEnv string, dryRun bool) int {
+	fmt.Println("============ start clear_db ============")
+	cfg := config.Mustload(configEnv)
+	Log, errFile := logger.InitLogger(cfg.ENV, cfg.LOG_ERROR_PATH)
+	defer errFile.Close()
+
+	if cfg.CLEANUP_UNVERIFIED_MODE != services.CleanupUnverifiedDelete &&
+		cfg.CLEANUP_UNVERIFIED_MODE != services.CleanupUnverifiedArchive &&
+		cfg.CLEANUP_UNVERIFIED_MODE != services.CleanupUnverifiedOff {
+		panic("CLEANUP_UNVERIFIED_MODE must be delete, archive or off")
+	}
+
+	dsn := "postgres://" + cfg.POSTGRES_USER + ":" + cfg.POSTGRES_PASSWORD + "@" + cfg.POSTGRES_HOST + ":" + cfg.POSTGRES_PORT + "/" + cfg.POSTGRES_DB + "?sslmode=disable"
+
+	ctxDB, cancel := context.WithTimeout(context.Background(), cfg.POSTGRES_TIMEOUT)
+	defer cancel()
+
+	storage, err := storage.NewStorage(ctxDB, dsn, Log)
+	if err != nil {
+		panic(err)
+	}
+	defer storage.Close()
+
+	sessionStorage, err := cache.InitSession(ctxDB, cfg.REDIS_HOST, cfg.REDIS_PORT, cfg.REDIS_SESSION_DB, Log)
+	if err != nil {
+		panic(err)
+	}
+
+	cleanupService := services.NewCleanupService(Log, storage, sessionStorage, cfg)
+	report, err := cleanupService.Run(context.Background(), dryRun)
+
+	b, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(b))
 
+	if err != nil {
+		Log.Error("clear_db failed", slog.String("err", err.Error()))
+		return 1
+	}
+	if report.Errors > 0 {
+		Log.Error("clear_db finished with errors", slog.Int("errors", report.Errors))
+		return 1
+	}
+	fmt.Println("============ clear_db finished ============")
+	return 0
 }
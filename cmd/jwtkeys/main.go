@@ -0,0 +1,212 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/config"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
+)
+
+// управление набором ключей подписи в AUTH_JWT_KEYS_DIR:
+//
+//	generate - новый ключ, публикуется в JWKS, но пока не подписывает (если активного нет - сразу активный)
+//	promote  - ключ -kid становится активным, прежний принимается еще -verifyHours
+//	retire   - удаляет ключи с истекшим сроком проверки, с -kid - выводит ключ из проверки сразу
+//	legacy   - при переходе с AUTH_SECRET_KEY: прежний HS256-секрет проверяет токены без kid еще -verifyHours
+//	list     - показать набор
+//
+// -verifyHours по умолчанию и минимум - AUTH_REFRESH_TOKEN_EXP_HOURS, иначе выданные refresh-токены перестанут проверяться
+func main() {
+	var configEnv, dir, action, alg, kid string
+	var verifyHours int
+
+	flag.StringVar(&configEnv, "configEnv", "", "Path to env-file")
+	flag.StringVar(&dir, "dir", "_keys", "Keyset directory (AUTH_JWT_KEYS_DIR)")
+	flag.StringVar(&action, "action", "list", "generate, promote, retire, legacy or list")
+	flag.StringVar(&alg, "alg", "EdDSA", "Algorithm for generate - EdDSA or RS256")
+	flag.StringVar(&kid, "kid", "", "Key id for promote or retire")
+	flag.IntVar(&verifyHours, "verifyHours", 0, "How long the previous key is accepted after promote or legacy, default and minimum - AUTH_REFRESH_TOKEN_EXP_HOURS")
+	flag.Parse()
+
+	cfg := config.Mustload(configEnv)
+	if verifyHours == 0 {
+		verifyHours = cfg.AUTH_REFRESH_TOKEN_EXP_HOURS
+	}
+	if verifyHours < cfg.AUTH_REFRESH_TOKEN_EXP_HOURS {
+		panic(fmt.Sprintf("verifyHours %d is less than AUTH_REFRESH_TOKEN_EXP_HOURS %d", verifyHours, cfg.AUTH_REFRESH_TOKEN_EXP_HOURS))
+	}
+
+	fmt.Println("jwtkeys start - keyset directory:", dir)
+
+	manifest, err := lib.ReadJWTKeysetManifest(dir)
+	if err != nil && !os.IsNotExist(err) {
+		panic(err)
+	}
+
+	switch action {
+	case "generate":
+		err = generate(dir, &manifest, alg)
+	case "promote":
+		err = promote(&manifest, kid, time.Duration(verifyHours)*time.Hour)
+	case "retire":
+		err = retire(dir, &manifest, kid)
+	case "legacy":
+		err = legacy(dir, &manifest, cfg.AUTH_SECRET_KEY, time.Duration(verifyHours)*time.Hour)
+	case "list":
+		list(manifest)
+		return
+	default:
+		err = fmt.Errorf("unknown action: %s", action)
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	if err := lib.WriteJWTKeysetManifest(dir, manifest); err != nil {
+		panic(err)
+	}
+	list(manifest)
+	fmt.Println("keyset saved")
+}
+
+func generate(dir string, manifest *lib.JWTKeysetManifest, alg string) error {
+	pemData, err := lib.GenerateJWTPrivateKeyPEM(alg)
+	if err != nil {
+		return err
+	}
+	key, err := lib.ParseJWTPrivateKey(alg, pemData, "")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	file := key.Kid + ".pem"
+	if err := os.WriteFile(filepath.Join(dir, file), pemData, 0600); err != nil {
+		return err
+	}
+
+	manifest.Keys = append(manifest.Keys, lib.JWTKeysetEntry{
+		Kid:       key.Kid,
+		Alg:       alg,
+		File:      file,
+		CreatedAt: time.Now(),
+	})
+	if manifest.Active == "" {
+		manifest.Active = key.Kid
+	}
+	fmt.Println("generated key:", key.Kid)
+	return nil
+}
+
+func promote(manifest *lib.JWTKeysetManifest, kid string, verifyTTL time.Duration) error {
+	if kid == "" {
+		return fmt.Errorf("kid is required for promote")
+	}
+	if kid == manifest.Active {
+		return fmt.Errorf("key %s is already active", kid)
+	}
+
+	now := time.Now()
+	found := false
+	for i := range manifest.Keys {
+		entry := &manifest.Keys[i]
+		if entry.Kid == kid {
+			if entry.VerifyUntil != nil && entry.VerifyUntil.Before(now) {
+				return fmt.Errorf("key %s is retired", kid)
+			}
+			entry.RetiredAt = nil
+			entry.VerifyUntil = nil
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("key %s not found", kid)
+	}
+
+	// прежний ключ перестает подписывать, но выпущенные им refresh-токены живут до истечения TTL
+	verifyUntil := now.Add(verifyTTL)
+	for i := range manifest.Keys {
+		entry := &manifest.Keys[i]
+		if entry.Kid == manifest.Active {
+			entry.RetiredAt = &now
+			entry.VerifyUntil = &verifyUntil
+		}
+	}
+	manifest.Active = kid
+	return nil
+}
+
+// legacy сохраняет прежний HS256-секрет как ключ только для проверки токенов без kid
+func legacy(dir string, manifest *lib.JWTKeysetManifest, secret string, verifyTTL time.Duration) error {
+	if secret == "" {
+		return fmt.Errorf("AUTH_SECRET_KEY is empty")
+	}
+	for _, entry := range manifest.Keys {
+		if entry.Kid == lib.JWTLegacyKid {
+			return fmt.Errorf("legacy key already in keyset")
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	file := lib.JWTLegacyKid + ".secret"
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(secret), 0600); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	verifyUntil := now.Add(verifyTTL)
+	manifest.Keys = append(manifest.Keys, lib.JWTKeysetEntry{
+		Kid:         lib.JWTLegacyKid,
+		Alg:         "HS256",
+		File:        file,
+		CreatedAt:   now,
+		RetiredAt:   &now,
+		VerifyUntil: &verifyUntil,
+	})
+	fmt.Println("legacy key added, verify until:", verifyUntil.Format(time.RFC3339))
+	return nil
+}
+
+func retire(dir string, manifest *lib.JWTKeysetManifest, kid string) error {
+	if kid != "" && kid == manifest.Active {
+		return fmt.Errorf("active key %s cannot be retired, promote another key first", kid)
+	}
+
+	now := time.Now()
+	keys := make([]lib.JWTKeysetEntry, 0, len(manifest.Keys))
+	for _, entry := range manifest.Keys {
+		expired := entry.VerifyUntil != nil && entry.VerifyUntil.Before(now)
+		if entry.Kid == kid || expired {
+			if err := os.Remove(filepath.Join(dir, entry.File)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			fmt.Println("retired key:", entry.Kid)
+			continue
+		}
+		keys = append(keys, entry)
+	}
+	manifest.Keys = keys
+	return nil
+}
+
+func list(manifest lib.JWTKeysetManifest) {
+	for _, entry := range manifest.Keys {
+		status := "verify"
+		if entry.Kid == manifest.Active {
+			status = "active"
+		}
+		verifyUntil := "-"
+		if entry.VerifyUntil != nil {
+			verifyUntil = entry.VerifyUntil.Format(time.RFC3339)
+		}
+		fmt.Printf("%-45s %-6s %-6s created %s verify until %s\n", entry.Kid, entry.Alg, status, entry.CreatedAt.Format(time.RFC3339), verifyUntil)
+	}
+}
@@ -22,11 +22,110 @@ type Config struct {
 	REDIS_PORT       string `env:"REDIS_PORT,required"`
 	REDIS_SESSION_DB int    `env:"REDIS_SESSION_DB,required"`
 	REDIS_OTP_DB     int    `env:"REDIS_OTP_DB,required"`
+	REDIS_LIMIT_DB   int    `env:"REDIS_LIMIT_DB" envDefault:"2"`
 
 	AUTH_SECRET_KEY               string `env:"AUTH_SECRET_KEY,required"  json:"-"` // для шифрования в БД
 	AUTH_ACCESS_TOKEN_EXP_MINUTES int    `env:"AUTH_ACCESS_TOKEN_EXP_MINUTES,required"`
 	AUTH_REFRESH_TOKEN_EXP_HOURS  int    `env:"AUTH_REFRESH_TOKEN_EXP_HOURS,required"`
 	AUTH_OTP_TTL_MINUTES          int    `env:"AUTH_OTP_TTL_MINUTES,required"`
+	AUTH_OTP_MAX_ATTEMPTS         int    `env:"AUTH_OTP_MAX_ATTEMPTS" envDefault:"5"` // после N неверных вводов код удаляется
+
+	// пауза между отправками кода на один адрес и лимиты отправок, 0 - без лимита
+	AUTH_OTP_RESEND_COOLDOWN time.Duration `env:"AUTH_OTP_RESEND_COOLDOWN" envDefault:"1m"`
+	AUTH_OTP_HOURLY_LIMIT    int           `env:"AUTH_OTP_HOURLY_LIMIT" envDefault:"5"`
+	AUTH_OTP_DAILY_LIMIT     int           `env:"AUTH_OTP_DAILY_LIMIT" envDefault:"10"`
+	AUTH_OTP_IP_HOURLY_LIMIT int           `env:"AUTH_OTP_IP_HOURLY_LIMIT" envDefault:"20"`
+	AUTH_OTP_IP_DAILY_LIMIT  int           `env:"AUTH_OTP_IP_DAILY_LIMIT" envDefault:"50"`
+
+	// подпись токенов: HS256 (AUTH_SECRET_KEY), RS256 или EdDSA (приватный ключ в PEM)
+	AUTH_JWT_ALG              string `env:"AUTH_JWT_ALG" envDefault:"HS256"`
+	AUTH_JWT_PRIVATE_KEY_PATH string `env:"AUTH_JWT_PRIVATE_KEY_PATH"`
+	AUTH_JWT_KID              string `env:"AUTH_JWT_KID"` // по умолчанию thumbprint публичного ключа
+	// ротация: каталог с keyset.json и PEM-ключами (cmd/jwtkeys), заменяет три параметра выше
+	AUTH_JWT_KEYS_DIR             string        `env:"AUTH_JWT_KEYS_DIR"`
+	AUTH_JWT_KEYS_RELOAD_INTERVAL time.Duration `env:"AUTH_JWT_KEYS_RELOAD_INTERVAL" envDefault:"1m"`
+
+	// блокировка входа после неудачных попыток: по email/телефону и по IP, длительность удваивается до MAX
+	AUTH_LOGIN_MAX_ATTEMPTS    int           `env:"AUTH_LOGIN_MAX_ATTEMPTS" envDefault:"5"`
+	AUTH_LOGIN_IP_MAX_ATTEMPTS int           `env:"AUTH_LOGIN_IP_MAX_ATTEMPTS" envDefault:"20"`
+	AUTH_LOGIN_ATTEMPTS_WINDOW time.Duration `env:"AUTH_LOGIN_ATTEMPTS_WINDOW" envDefault:"15m"`
+	AUTH_LOGIN_LOCK_BASE       time.Duration `env:"AUTH_LOGIN_LOCK_BASE" envDefault:"1m"`
+	AUTH_LOGIN_LOCK_MAX        time.Duration `env:"AUTH_LOGIN_LOCK_MAX" envDefault:"1h"`
+
+	// 2FA (TOTP): время на ввод кода после пароля, неверные коды блокируют как AUTH_LOGIN_*
+	AUTH_MFA_CHALLENGE_TTL time.Duration `env:"AUTH_MFA_CHALLENGE_TTL" envDefault:"5m"`
+	AUTH_MFA_MAX_ATTEMPTS  int           `env:"AUTH_MFA_MAX_ATTEMPTS" envDefault:"5"`
+	AUTH_TOTP_ISSUER       string        `env:"AUTH_TOTP_ISSUER"` // название в приложении-аутентификаторе, по умолчанию SERVICE_NAME
+	// ключ шифрования секретов TOTP в БД, отдельный от AUTH_SECRET_KEY (подпись токенов и ссылок)
+	AUTH_TOTP_ENCRYPTION_KEY string `env:"AUTH_TOTP_ENCRYPTION_KEY,required" json:"-"`
+
+	// удаление аккаунта пользователем: в льготный период вход восстанавливает аккаунт, затем строка обезличивается
+	AUTH_ACCOUNT_DELETION_GRACE time.Duration `env:"AUTH_ACCOUNT_DELETION_GRACE" envDefault:"720h"`
+	AUTH_ACCOUNT_PURGE_INTERVAL time.Duration `env:"AUTH_ACCOUNT_PURGE_INTERVAL" envDefault:"1h"` // 0 - очистка в сервере отключена
+
+	// cmd/clear_db: пользователи без подтвержденного email/телефона старше AFTER удаляются (delete),
+	// обезличиваются (archive) или остаются (off). По умолчанию off, удаление включается явно
+	CLEANUP_UNVERIFIED_AFTER time.Duration `env:"CLEANUP_UNVERIFIED_AFTER" envDefault:"168h"`
+	CLEANUP_UNVERIFIED_MODE  string        `env:"CLEANUP_UNVERIFIED_MODE" envDefault:"off"`
+
+	// выгрузка персональных данных: сколько хранится архив и действует ссылка на скачивание
+	DATA_EXPORT_TTL time.Duration `env:"DATA_EXPORT_TTL" envDefault:"24h"`
+
+	// уведомления о смене пароля, входе с нового устройства и отзыве сессии:
+	// BASE_URL - публичный адрес API для ссылки "это был не я", REVOKE_LINK_TTL - сколько действует ссылка
+	SECURITY_NOTIFY_BASE_URL        string        `env:"SECURITY_NOTIFY_BASE_URL" envDefault:"http://localhost:3199"`
+	SECURITY_NOTIFY_REVOKE_LINK_TTL time.Duration `env:"SECURITY_NOTIFY_REVOKE_LINK_TTL" envDefault:"72h"`
+
+	// ограничение частоты запросов (скользящее окно в Redis), LIMIT=0 - отключено
+	RATE_LIMIT_API_LIMIT    int           `env:"RATE_LIMIT_API_LIMIT" envDefault:"300"`
+	RATE_LIMIT_API_WINDOW   time.Duration `env:"RATE_LIMIT_API_WINDOW" envDefault:"1m"`
+	RATE_LIMIT_API_KEY_BY   string        `env:"RATE_LIMIT_API_KEY_BY" envDefault:"ip"` // ip или api_key
+	RATE_LIMIT_API_KEYS     []string      `env:"RATE_LIMIT_API_KEYS"`                   // выданные ключи X-API-Key, остальные считаются по IP
+	RATE_LIMIT_AUTH_LIMIT   int           `env:"RATE_LIMIT_AUTH_LIMIT" envDefault:"30"`
+	RATE_LIMIT_AUTH_WINDOW  time.Duration `env:"RATE_LIMIT_AUTH_WINDOW" envDefault:"1m"`
+	RATE_LIMIT_ADMIN_LIMIT  int           `env:"RATE_LIMIT_ADMIN_LIMIT" envDefault:"120"`
+	RATE_LIMIT_ADMIN_WINDOW time.Duration `env:"RATE_LIMIT_ADMIN_WINDOW" envDefault:"1m"`
+
+	// кэш проверки отзыва access-токена (middleware.RequireSession), на это время отзыв может запаздывать
+	AUTH_SESSION_CHECK_CACHE_TTL time.Duration `env:"AUTH_SESSION_CHECK_CACHE_TTL" envDefault:"5s"`
+
+	RBAC_CACHE_TTL time.Duration `env:"RBAC_CACHE_TTL" envDefault:"1m"`
+
+	OAUTH_STATE_TTL_MINUTES int    `env:"OAUTH_STATE_TTL_MINUTES" envDefault:"10"`
+	OAUTH_REDIRECT_BASE_URL string `env:"OAUTH_REDIRECT_BASE_URL" envDefault:"http://localhost:3199/api/auth/oauth"` // к нему добавляется /<provider>/callback
+
+	GOOGLE_CLIENT_ID     string `env:"GOOGLE_CLIENT_ID"`
+	GOOGLE_CLIENT_SECRET string `env:"GOOGLE_CLIENT_SECRET" json:"-"`
+	// адреса переопределяются для тестов с локальным OIDC-сервером
+	GOOGLE_AUTH_URL     string `env:"GOOGLE_AUTH_URL" envDefault:"https://accounts.google.com/o/oauth2/v2/auth"`
+	GOOGLE_TOKEN_URL    string `env:"GOOGLE_TOKEN_URL" envDefault:"https://oauth2.googleapis.com/token"`
+	GOOGLE_USERINFO_URL string `env:"GOOGLE_USERINFO_URL" envDefault:"https://openidconnect.googleapis.com/v1/userinfo"`
+
+	YANDEX_CLIENT_ID       string `env:"YANDEX_CLIENT_ID"`
+	YANDEX_CLIENT_SECRET   string `env:"YANDEX_CLIENT_SECRET" json:"-"`
+	VK_CLIENT_ID           string `env:"VK_CLIENT_ID"`
+	VK_CLIENT_SECRET       string `env:"VK_CLIENT_SECRET" json:"-"`
+	FACEBOOK_CLIENT_ID     string `env:"FACEBOOK_CLIENT_ID"`
+	FACEBOOK_CLIENT_SECRET string `env:"FACEBOOK_CLIENT_SECRET" json:"-"`
+
+	// произвольный OpenID Connect провайдер
+	OIDC_NAME          string   `env:"OIDC_NAME" envDefault:"oidc"`
+	OIDC_CLIENT_ID     string   `env:"OIDC_CLIENT_ID"`
+	OIDC_CLIENT_SECRET string   `env:"OIDC_CLIENT_SECRET" json:"-"`
+	OIDC_AUTH_URL      string   `env:"OIDC_AUTH_URL"`
+	OIDC_TOKEN_URL     string   `env:"OIDC_TOKEN_URL"`
+	OIDC_USERINFO_URL  string   `env:"OIDC_USERINFO_URL"`
+	OIDC_SCOPES        []string `env:"OIDC_SCOPES" envDefault:"openid,email,profile"`
+
+	// WebAuthn / passkeys: RP_ID - домен без схемы и порта, пусто - отключено
+	WEBAUTHN_RP_ID         string        `env:"WEBAUTHN_RP_ID"`
+	WEBAUTHN_RP_NAME       string        `env:"WEBAUTHN_RP_NAME"` // по умолчанию SERVICE_NAME
+	WEBAUTHN_RP_ORIGINS    []string      `env:"WEBAUTHN_RP_ORIGINS"`
+	WEBAUTHN_CHALLENGE_TTL time.Duration `env:"WEBAUTHN_CHALLENGE_TTL" envDefault:"5m"`
+
+	// офлайн-база MaxMind (GeoLite2-City.mmdb) для страны/города в сессиях, пусто - отключено
+	GEOIP_DB_PATH string `env:"GEOIP_DB_PATH"`
+	GEOIP_LANG    string `env:"GEOIP_LANG" envDefault:"ru"`
 
 	SMTP_HOST       string `env:"SMTP_HOST,required"`
 	SMTP_PORT       int    `env:"SMTP_PORT,required"`
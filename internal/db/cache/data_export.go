@@ -0,0 +1,196 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/redis/go-redis/v9"
+)
+
+// DataExportData - состояние выгрузки персональных данных, сам архив хранится отдельным ключом
+type DataExportData struct {
+	Id        string    `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Status    string    `json:"status"` // pending, ready, failed
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// DataExportClaim - текущая выгрузка пользователя и через сколько можно запросить новую
+type DataExportClaim struct {
+	Id         string
+	RetryAfter time.Duration
+}
+
+// выгрузка закрепляется за пользователем, только если у него нет другой, иначе возвращается текущая
+var claimDataExportScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return {ARGV[1], tonumber(ARGV[2])}
+end
+return {redis.call('GET', KEYS[1]), redis.call('PTTL', KEYS[1])}
+`)
+
+// ClaimDataExport закрепляет за пользователем выгрузку id на ttl - не больше одной выгрузки за ttl.
+// Если у пользователя уже есть выгрузка, возвращает ее id вместо переданного
+func (c *SessionStorage) ClaimDataExport(ctx context.Context, userId int64, id string, ttl time.Duration) (DataExportClaim, *errorsApp.DbError) {
+	op := "cache.SessionStorage.ClaimDataExport"
+	log := c.log.With(slog.String("op", op))
+
+	claim := DataExportClaim{}
+
+	res, err := claimDataExportScript.Run(ctx, c.RDB, []string{"data_export_user:" + strconv.FormatInt(userId, 10)}, id, ttl.Milliseconds()).Slice()
+	if err == nil && len(res) != 2 {
+		err = errors.New("unexpected claim data export result")
+	}
+	if err != nil {
+		log.Error("error claim data export", slog.Any("err", err))
+		return claim, &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "user_id",
+			Message: "internal error claim data export",
+			Error:   err,
+		}
+	}
+
+	claim.Id, _ = res[0].(string)
+	if pttl, ok := res[1].(int64); ok && pttl > 0 {
+		claim.RetryAfter = time.Duration(pttl) * time.Millisecond
+	}
+	return claim, nil
+}
+
+var releaseDataExportScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// ReleaseDataExport снимает закрепление выгрузки id (например, при ошибке сборки), чтобы можно было запросить новую
+func (c *SessionStorage) ReleaseDataExport(ctx context.Context, userId int64, id string) *errorsApp.DbError {
+	op := "cache.SessionStorage.ReleaseDataExport"
+	log := c.log.With(slog.String("op", op))
+
+	err := releaseDataExportScript.Run(ctx, c.RDB, []string{"data_export_user:" + strconv.FormatInt(userId, 10)}, id).Err()
+	if err != nil {
+		log.Error("error release data export", slog.String("err", err.Error()))
+		return &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "user_id",
+			Message: "internal error release data export",
+			Error:   err,
+		}
+	}
+	return nil
+}
+
+// SaveDataExport сохраняет (или обновляет) состояние выгрузки до data.ExpiresAt
+func (c *SessionStorage) SaveDataExport(ctx context.Context, data DataExportData) *errorsApp.DbError {
+	op := "cache.SessionStorage.SaveDataExport"
+	log := c.log.With(slog.String("op", op))
+
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		log.Error("error marshal data export", slog.String("err", err.Error()))
+		return &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "data",
+			Message: "internal error marshal data export",
+			Error:   err,
+		}
+	}
+
+	err = c.RDB.Set(ctx, "data_export:"+data.Id, jsonData, time.Until(data.ExpiresAt)).Err()
+	if err != nil {
+		log.Error("error save data export", slog.String("err", err.Error()))
+		return &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "data",
+			Message: "internal error save data export",
+			Error:   err,
+		}
+	}
+	return nil
+}
+
+func (c *SessionStorage) GetDataExport(ctx context.Context, id string) (DataExportData, *errorsApp.DbError) {
+	op := "cache.SessionStorage.GetDataExport"
+	log := c.log.With(slog.String("op", op))
+
+	data := DataExportData{}
+
+	val, err := c.RDB.Get(ctx, "data_export:"+id).Bytes()
+	if err != nil {
+		log.Warn("error get data export", slog.String("err", err.Error()))
+		return data, &errorsApp.DbError{
+			Type:    "not_found",
+			Field:   "id",
+			Message: "data export not found",
+			Error:   err,
+		}
+	}
+
+	err = json.Unmarshal(val, &data)
+	if err != nil {
+		log.Error("error unmarshal data export", slog.String("err", err.Error()))
+		return data, &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "data",
+			Message: "internal error unmarshal data export",
+			Error:   err,
+		}
+	}
+	return data, nil
+}
+
+func (c *SessionStorage) SaveDataExportFile(ctx context.Context, id string, file []byte, expiresAt time.Time) *errorsApp.DbError {
+	op := "cache.SessionStorage.SaveDataExportFile"
+	log := c.log.With(slog.String("op", op))
+
+	err := c.RDB.Set(ctx, "data_export_file:"+id, file, time.Until(expiresAt)).Err()
+	if err != nil {
+		log.Error("error save data export file", slog.String("err", err.Error()))
+		return &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "file",
+			Message: "internal error save data export file",
+			Error:   err,
+		}
+	}
+	return nil
+}
+
+// PopDataExportFile атомарно читает и удаляет архив вместе с состоянием - ссылка одноразовая
+func (c *SessionStorage) PopDataExportFile(ctx context.Context, id string) ([]byte, *errorsApp.DbError) {
+	op := "cache.SessionStorage.PopDataExportFile"
+	log := c.log.With(slog.String("op", op))
+
+	file, err := c.RDB.GetDel(ctx, "data_export_file:"+id).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, &errorsApp.DbError{
+				Type:    "not_found",
+				Field:   "id",
+				Message: "data export file not found",
+				Error:   err,
+			}
+		}
+		log.Error("error get data export file", slog.String("err", err.Error()))
+		return nil, &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "file",
+			Message: "internal error get data export file",
+			Error:   err,
+		}
+	}
+
+	if err := c.RDB.Del(ctx, "data_export:"+id).Err(); err != nil {
+		log.Warn("error delete data export", slog.String("err", err.Error()))
+	}
+	return file, nil
+}
@@ -0,0 +1,33 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LimitStorage - счетчики неудачных попыток и ограничения частоты запросов
+type LimitStorage struct {
+	RDB *redis.Client
+	log *slog.Logger
+}
+
+func InitLimit(ctx context.Context, host string, port string, number int, log *slog.Logger) (*LimitStorage, error) {
+	RDB := redis.NewClient(&redis.Options{
+		Addr:     host + ":" + port,
+		Password: "",
+		DB:       number,
+	})
+
+	// Проверка соединения
+	if err := RDB.Ping(ctx).Err(); err != nil {
+		log.Error(fmt.Sprintf("Failed to connect to Redis: %v", err))
+		return nil, fmt.Errorf("failed to connect to Redis: %v", err)
+	}
+
+	log.Info("Redis limit storage initialized")
+
+	return &LimitStorage{RDB: RDB, log: log}, nil
+}
@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+)
+
+// GetLoginLock возвращает оставшееся время блокировки входа по ключу, 0 - блокировки нет
+func (c *LimitStorage) GetLoginLock(ctx context.Context, key string) (time.Duration, *errorsApp.DbError) {
+	op := "cache.LimitStorage.GetLoginLock"
+	log := c.log.With(slog.String("op", op))
+
+	ttl, err := c.RDB.PTTL(ctx, "login_lock:"+key).Result()
+	if err != nil {
+		log.Error("error get login lock", slog.String("err", err.Error()))
+		return 0, &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "key",
+			Message: "internal error get login lock",
+			Error:   err,
+		}
+	}
+	// -2 - ключа нет, -1 - ключ без TTL (не должно быть)
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// AddLoginFailure увеличивает счетчик неудачных попыток, счетчик живет window с последней ошибки.
+// Начиная с maxAttempts ставит блокировку lockBase, удваивая ее на каждую следующую ошибку до lockMax.
+// Возвращает длительность поставленной блокировки, 0 - без блокировки
+func (c *LimitStorage) AddLoginFailure(ctx context.Context, key string, window time.Duration, maxAttempts int, lockBase time.Duration, lockMax time.Duration) (time.Duration, *errorsApp.DbError) {
+	op := "cache.LimitStorage.AddLoginFailure"
+	log := c.log.With(slog.String("op", op))
+
+	failKey := "login_fail:" + key
+
+	pipe := c.RDB.TxPipeline()
+	incr := pipe.Incr(ctx, failKey)
+	pipe.Expire(ctx, failKey, window)
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		log.Error("error incr login failures", slog.String("err", err.Error()))
+		return 0, &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "key",
+			Message: "internal error incr login failures",
+			Error:   err,
+		}
+	}
+
+	failures := int(incr.Val())
+	if maxAttempts <= 0 || failures < maxAttempts {
+		return 0, nil
+	}
+
+	lock := lockBase
+	for i := maxAttempts; i < failures && lock < lockMax; i++ {
+		lock *= 2
+	}
+	lock = min(lock, lockMax)
+
+	err = c.RDB.Set(ctx, "login_lock:"+key, failures, lock).Err()
+	if err != nil {
+		log.Error("error set login lock", slog.String("err", err.Error()))
+		return 0, &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "key",
+			Message: "internal error set login lock",
+			Error:   err,
+		}
+	}
+	log.Warn("login locked", slog.String("key", key), slog.Int("failures", failures), slog.Duration("lock", lock))
+
+	return lock, nil
+}
+
+// ResetLoginFailures сбрасывает счетчики и блокировки по ключам
+func (c *LimitStorage) ResetLoginFailures(ctx context.Context, keys ...string) *errorsApp.DbError {
+	op := "cache.LimitStorage.ResetLoginFailures"
+	log := c.log.With(slog.String("op", op))
+
+	redisKeys := make([]string, 0, len(keys)*2)
+	for _, key := range keys {
+		redisKeys = append(redisKeys, "login_fail:"+key, "login_lock:"+key)
+	}
+
+	err := c.RDB.Del(ctx, redisKeys...).Err()
+	if err != nil {
+		log.Error("error reset login failures", slog.String("err", err.Error()))
+		return &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "key",
+			Message: "internal error reset login failures",
+			Error:   err,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+)
+
+// MfaChallengeData - вход с верным паролем, ожидающий второй фактор
+type MfaChallengeData struct {
+	Token     string    `json:"token"`
+	UserID    int64     `json:"user_id"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (c *SessionStorage) SaveMfaChallenge(ctx context.Context, data MfaChallengeData, ttl time.Duration) *errorsApp.DbError {
+	op := "cache.SessionStorage.SaveMfaChallenge"
+	log := c.log.With(slog.String("op", op))
+
+	data.CreatedAt = time.Now()
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		log.Error("error marshal mfa challenge", slog.String("err", err.Error()))
+		return &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "data",
+			Message: "internal error marshal mfa challenge",
+			Error:   err,
+		}
+	}
+
+	err = c.RDB.Set(ctx, "mfa_challenge:"+data.Token, jsonData, ttl).Err()
+	if err != nil {
+		log.Error("error save mfa challenge", slog.String("err", err.Error()))
+		return &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "data",
+			Message: "internal error save mfa challenge",
+			Error:   err,
+		}
+	}
+	return nil
+}
+
+func (c *SessionStorage) GetMfaChallenge(ctx context.Context, token string) (MfaChallengeData, *errorsApp.DbError) {
+	op := "cache.SessionStorage.GetMfaChallenge"
+	log := c.log.With(slog.String("op", op))
+
+	data := MfaChallengeData{}
+
+	val, err := c.RDB.Get(ctx, "mfa_challenge:"+token).Bytes()
+	if err != nil {
+		log.Warn("error get mfa challenge", slog.String("err", err.Error()))
+		return data, &errorsApp.DbError{
+			Type:    "not_found",
+			Field:   "token",
+			Message: "mfa challenge not found",
+			Error:   err,
+		}
+	}
+
+	err = json.Unmarshal(val, &data)
+	if err != nil {
+		log.Error("error unmarshal mfa challenge", slog.String("err", err.Error()))
+		return data, &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "data",
+			Message: "internal error unmarshal mfa challenge",
+			Error:   err,
+		}
+	}
+	return data, nil
+}
+
+// DeleteMfaChallenge удаляет challenge после успешной проверки. false - его уже удалил
+// параллельный запрос, тогда токены выдавать нельзя
+func (c *SessionStorage) DeleteMfaChallenge(ctx context.Context, token string) (bool, *errorsApp.DbError) {
+	op := "cache.SessionStorage.DeleteMfaChallenge"
+	log := c.log.With(slog.String("op", op))
+
+	deleted, err := c.RDB.Del(ctx, "mfa_challenge:"+token).Result()
+	if err != nil {
+		log.Error("error delete mfa challenge", slog.String("err", err.Error()))
+		return false, &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "token",
+			Message: "internal error delete mfa challenge",
+			Error:   err,
+		}
+	}
+	return deleted == 1, nil
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+)
+
+// OauthStateData - данные одного oauth-входа между редиректом и callback
+type OauthStateData struct {
+	State    string `json:"state"`
+	Provider string `json:"provider"`
+	Verifier string `json:"verifier"` // PKCE code_verifier
+	UserID   int64  `json:"user_id"`  // не 0 - привязка провайдера к уже вошедшему пользователю
+	// sha256 значения cookie, выданной браузеру, начавшему вход, callback принимается только из него
+	BrowserHash string    `json:"browser_hash"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (c *SessionStorage) SaveOauthState(ctx context.Context, data OauthStateData, ttlMinutes int) *errorsApp.DbError {
+	op := "cache.SessionStorage.SaveOauthState"
+	log := c.log.With(slog.String("op", op))
+
+	data.CreatedAt = time.Now()
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		log.Error("error marshal oauth state", slog.String("err", err.Error()))
+		return &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "data",
+			Message: "internal error marshal oauth state",
+			Error:   err,
+		}
+	}
+
+	err = c.RDB.Set(ctx, "oauth_state:"+data.State, jsonData, time.Duration(ttlMinutes)*time.Minute).Err()
+	if err != nil {
+		log.Error("error save oauth state", slog.String("err", err.Error()))
+		return &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "data",
+			Message: "internal error save oauth state",
+			Error:   err,
+		}
+	}
+	return nil
+}
+
+// PopOauthState возвращает и сразу удаляет state, чтобы его нельзя было использовать повторно
+func (c *SessionStorage) PopOauthState(ctx context.Context, state string) (OauthStateData, *errorsApp.DbError) {
+	op := "cache.SessionStorage.PopOauthState"
+	log := c.log.With(slog.String("op", op))
+
+	data := OauthStateData{}
+
+	val, err := c.RDB.GetDel(ctx, "oauth_state:"+state).Bytes()
+	if err != nil {
+		log.Warn("error get oauth state", slog.String("err", err.Error()))
+		return data, &errorsApp.DbError{
+			Type:    "not_found",
+			Field:   "state",
+			Message: "oauth state not found",
+			Error:   err,
+		}
+	}
+
+	err = json.Unmarshal(val, &data)
+	if err != nil {
+		log.Error("error unmarshal oauth state", slog.String("err", err.Error()))
+		return data, &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "data",
+			Message: "internal error unmarshal oauth state",
+			Error:   err,
+		}
+	}
+	return data, nil
+}
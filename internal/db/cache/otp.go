@@ -98,8 +98,9 @@ func (c *OtpStorage) DeleteOtp(
 	log := c.log.With(slog.String("op", op))
 
 	key := fmt.Sprintf("otp:%s:%s", typeM, address)
+	attemptsKey := fmt.Sprintf("otp_attempts:%s:%s", typeM, address)
 
-	_, err := c.RDB.Del(ctx, key).Result()
+	_, err := c.RDB.Del(ctx, key, attemptsKey).Result()
 	if err != nil {
 		log.Warn("delete otp", slog.Any("err", err))
 		return &errorsApp.DbError{
@@ -146,3 +147,46 @@ func (c *OtpStorage) GetOtp(
 
 	return otpData, nil
 }
+
+// счетчик живет столько же, сколько сам код; -1 - кода нет
+var otpAttemptScript = redis.NewScript(`
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl <= 0 then
+	return -1
+end
+local attempts = redis.call('INCR', KEYS[2])
+redis.call('PEXPIRE', KEYS[2], ttl)
+return attempts
+`)
+
+// IncrOtpAttempts увеличивает счетчик попыток ввода кода, возвращает номер попытки
+func (c *OtpStorage) IncrOtpAttempts(
+	ctx context.Context,
+	address string,
+	typeM string) (int64, *errorsApp.DbError) {
+
+	op := "cache.OtpStorage.IncrOtpAttempts"
+	log := c.log.With(slog.String("op", op))
+
+	key := fmt.Sprintf("otp:%s:%s", typeM, address)
+	attemptsKey := fmt.Sprintf("otp_attempts:%s:%s", typeM, address)
+
+	attempts, err := otpAttemptScript.Run(ctx, c.RDB, []string{key, attemptsKey}).Int64()
+	if err != nil {
+		log.Error("incr otp attempts", slog.Any("err", err))
+		return 0, &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "data",
+			Message: "internal error incr otp attempts",
+			Error:   err,
+		}
+	}
+	if attempts < 0 {
+		return 0, &errorsApp.DbError{
+			Type:    "not_found",
+			Field:   "data",
+			Message: "otp not found",
+		}
+	}
+	return attempts, nil
+}
@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/redis/go-redis/v9"
+)
+
+// OtpQuota - лимит отправок кодов по ключу в фиксированном окне, отсчитываемом с первой отправки
+type OtpQuota struct {
+	Key    string
+	Window time.Duration
+	Limit  int
+}
+
+// OtpQuotaResult - результат учета отправки по набору лимитов
+type OtpQuotaResult struct {
+	Allowed bool
+	Key     string        // исчерпанный лимит, если не Allowed
+	Reset   time.Duration // время до сброса окна исчерпанного лимита
+}
+
+// пауза ставится только если ее еще нет, иначе возвращается остаток
+var claimOtpCooldownScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], 1, 'NX', 'PX', ARGV[1]) then
+	return 0
+end
+return redis.call('PTTL', KEYS[1])
+`)
+
+// ClaimOtpCooldown занимает паузу между отправками на адрес. Возвращает 0, если пауза поставлена
+// и можно отправлять, иначе сколько осталось до разрешения повторной отправки
+func (c *OtpStorage) ClaimOtpCooldown(ctx context.Context, address string, cooldown time.Duration) (time.Duration, *errorsApp.DbError) {
+	op := "cache.OtpStorage.ClaimOtpCooldown"
+	log := c.log.With(slog.String("op", op))
+
+	if cooldown <= 0 {
+		return 0, nil
+	}
+
+	ttl, err := claimOtpCooldownScript.Run(ctx, c.RDB, []string{"otp_cooldown:" + address}, cooldown.Milliseconds()).Int64()
+	if err != nil {
+		log.Error("error claim otp cooldown", slog.String("err", err.Error()))
+		return 0, &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "address",
+			Message: "internal error claim otp cooldown",
+			Error:   err,
+		}
+	}
+	return time.Duration(max(ttl, 0)) * time.Millisecond, nil
+}
+
+// сначала проверяются все счетчики, увеличиваются только если ни один лимит не исчерпан.
+// возвращает номер исчерпанного лимита (0 - все прошли) и его остаток окна
+var hitOtpQuotasScript = redis.NewScript(`
+for i, key in ipairs(KEYS) do
+	local count = tonumber(redis.call('GET', key) or '0')
+	if count >= tonumber(ARGV[i * 2 - 1]) then
+		return {i, redis.call('PTTL', key)}
+	end
+end
+for i, key in ipairs(KEYS) do
+	redis.call('INCR', key)
+	if redis.call('PTTL', key) < 0 then
+		redis.call('PEXPIRE', key, ARGV[i * 2])
+	end
+end
+return {0, 0}
+`)
+
+// HitOtpQuotas учитывает отправку во всех лимитах сразу, если ни один из них не исчерпан.
+// Лимиты проверяются в переданном порядке, Limit <= 0 - без ограничения
+func (c *OtpStorage) HitOtpQuotas(ctx context.Context, quotas []OtpQuota) (OtpQuotaResult, *errorsApp.DbError) {
+	op := "cache.OtpStorage.HitOtpQuotas"
+	log := c.log.With(slog.String("op", op))
+
+	result := OtpQuotaResult{}
+
+	keys := make([]string, 0, len(quotas))
+	args := make([]any, 0, len(quotas)*2)
+	active := make([]OtpQuota, 0, len(quotas))
+	for _, quota := range quotas {
+		if quota.Limit <= 0 {
+			continue
+		}
+		keys = append(keys, "otp_quota:"+quota.Key)
+		args = append(args, quota.Limit, quota.Window.Milliseconds())
+		active = append(active, quota)
+	}
+	if len(keys) == 0 {
+		result.Allowed = true
+		return result, nil
+	}
+
+	res, err := hitOtpQuotasScript.Run(ctx, c.RDB, keys, args...).Int64Slice()
+	if err != nil || len(res) != 2 || res[0] < 0 || res[0] > int64(len(active)) {
+		log.Error("error run otp quotas script", slog.Any("err", err))
+		return result, &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "key",
+			Message: "internal error hit otp quotas",
+			Error:   err,
+		}
+	}
+
+	if res[0] == 0 {
+		result.Allowed = true
+		return result, nil
+	}
+	result.Key = active[res[0]-1].Key
+	result.Reset = time.Duration(max(res[1], 0)) * time.Millisecond
+	return result, nil
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// RateLimitResult - результат учета запроса в скользящем окне
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	Reset     time.Duration // через сколько освободится место в окне
+}
+
+// sliding window log: в ZSET хранятся отметки времени запросов за последнее окно
+var rateLimitScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, 0, now - window)
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', key, now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', key, window)
+
+local reset = window
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+return {allowed, count, reset}
+`)
+
+// RateLimitHit учитывает запрос по ключу, если в окне window меньше limit запросов
+func (c *LimitStorage) RateLimitHit(ctx context.Context, key string, limit int, window time.Duration) (RateLimitResult, *errorsApp.DbError) {
+	op := "cache.LimitStorage.RateLimitHit"
+	log := c.log.With(slog.String("op", op))
+
+	result := RateLimitResult{}
+
+	res, err := rateLimitScript.Run(ctx, c.RDB, []string{"rate_limit:" + key},
+		time.Now().UnixMilli(), window.Milliseconds(), limit, uuid.NewString()).Int64Slice()
+	if err != nil || len(res) != 3 {
+		log.Error("error run rate limit script", slog.Any("err", err))
+		return result, &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "key",
+			Message: "internal error rate limit",
+			Error:   err,
+		}
+	}
+
+	result.Allowed = res[0] == 1
+	result.Remaining = max(limit-int(res[1]), 0)
+	result.Reset = time.Duration(res[2]) * time.Millisecond
+	return result, nil
+}
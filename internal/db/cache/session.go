@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/redis/go-redis/v9"
 )
 
 type SessionData struct {
@@ -19,6 +20,16 @@ type SessionData struct {
 	UserAgent string    `json:"user_agent"`
 	IP        string    `json:"ip"`
 	CreatedAt time.Time `json:"created_at"`
+	// семейство токенов: начинается при входе (jti первой сессии), сохраняется при каждом refresh
+	FamilyID  string `json:"family_id"`
+	ParentJti string `json:"parent_jti"`
+	// разобранный UserAgent и геолокация IP последнего использования
+	Browser    string    `json:"browser"`
+	OS         string    `json:"os"`
+	Device     string    `json:"device"`
+	Country    string    `json:"country"`
+	City       string    `json:"city"`
+	LastUsedAt time.Time `json:"last_used_at"`
 }
 
 func (c *SessionStorage) SaveSession(ctx context.Context, jti string, data SessionData, ttlHours int) *errorsApp.DbError {
@@ -26,6 +37,7 @@ func (c *SessionStorage) SaveSession(ctx context.Context, jti string, data Sessi
 	log := c.log.With(slog.String("op", op))
 
 	data.CreatedAt = time.Now()
+	data.LastUsedAt = data.CreatedAt
 	data.Jti = jti
 	jsonData, err := json.Marshal(data)
 	if err != nil {
@@ -38,9 +50,14 @@ func (c *SessionStorage) SaveSession(ctx context.Context, jti string, data Sessi
 		}
 	}
 
+	ttl := time.Duration(ttlHours) * time.Hour
 	pipe := c.RDB.TxPipeline()
-	pipe.Set(ctx, "jti:"+jti, jsonData, time.Duration(ttlHours)*time.Hour).Err()
+	pipe.Set(ctx, "jti:"+jti, jsonData, ttl).Err()
 	pipe.SAdd(ctx, "user_id:"+strconv.FormatInt(data.UserID, 10), jti).Err()
+	if data.FamilyID != "" {
+		pipe.SAdd(ctx, "family:"+data.FamilyID, jti)
+		pipe.Expire(ctx, "family:"+data.FamilyID, ttl)
+	}
 	_, err = pipe.Exec(ctx)
 	if err != nil {
 		log.Error("error save session", slog.String("err", err.Error()))
@@ -169,3 +186,90 @@ func (c *SessionStorage) DeleteSessionByJti(ctx context.Context, jti string) *er
 	}
 	return nil
 }
+
+func (c *SessionStorage) DeleteSessionsByUserId(ctx context.Context, userId int64) *errorsApp.DbError {
+	_, dbError := c.DeleteSessionsByUserIdExcept(ctx, userId, "")
+	return dbError
+}
+
+// все ключи передаются в KEYS: KEYS[1] - индекс user_id:<id>, KEYS[i+1] - jti:<ARGV[i]>
+var deleteUserSessionsScript = redis.NewScript(`
+local deleted = 0
+for i, jti in ipairs(ARGV) do
+	deleted = deleted + redis.call('DEL', KEYS[i + 1])
+	redis.call('SREM', KEYS[1], jti)
+end
+return deleted
+`)
+
+// за один проход удаляются сессии, прочитанные из индекса; сессия, созданная или ротированная
+// параллельно, попадает в индекс и удаляется следующим проходом
+const deleteUserSessionsMaxRounds = 10
+
+// DeleteSessionsByUserIdExcept удаляет все сессии пользователя, кроме keepJti
+// (пустой keepJti - удалить все). Возвращает число удаленных живых сессий
+func (c *SessionStorage) DeleteSessionsByUserIdExcept(ctx context.Context, userId int64, keepJti string) (int64, *errorsApp.DbError) {
+
+	op := "cache.SessionStorage.DeleteSessionsByUserIdExcept"
+	log := c.log.With(slog.String("op", op))
+
+	indexKey := "user_id:" + fmt.Sprintf("%d", userId)
+
+	var deleted int64
+	for range deleteUserSessionsMaxRounds {
+		jtis, err := c.RDB.SMembers(ctx, indexKey).Result()
+		if err != nil {
+			log.Error("error get user sessions index", slog.String("err", err.Error()))
+			return deleted, &errorsApp.DbError{
+				Type:    "internal_error",
+				Field:   "data",
+				Message: "internal error delete sessions by user id",
+				Error:   err,
+			}
+		}
+
+		keys := []string{indexKey}
+		args := make([]any, 0, len(jtis))
+		for _, jti := range jtis {
+			if jti != keepJti {
+				keys = append(keys, "jti:"+jti)
+				args = append(args, jti)
+			}
+		}
+		if len(args) == 0 {
+			return deleted, nil
+		}
+
+		n, err := deleteUserSessionsScript.Run(ctx, c.RDB, keys, args...).Int64()
+		if err != nil {
+			log.Error("error delete sessions by user id", slog.String("err", err.Error()))
+			return deleted, &errorsApp.DbError{
+				Type:    "internal_error",
+				Field:   "data",
+				Message: "internal error delete sessions by user id",
+				Error:   err,
+			}
+		}
+		deleted += n
+	}
+	log.Warn("user sessions keep appearing, stop", slog.Int64("user_id", userId), slog.Int64("deleted", deleted))
+	return deleted, nil
+}
+
+// SessionExists - сессия с jti еще жива (не отозвана и не ротирована)
+func (c *SessionStorage) SessionExists(ctx context.Context, jti string) (bool, *errorsApp.DbError) {
+	op := "cache.SessionStorage.SessionExists"
+	log := c.log.With(slog.String("op", op))
+
+	n, err := c.RDB.Exists(ctx, "jti:"+jti).Result()
+	if err != nil {
+		log.Error("error check session exists", slog.String("err", err.Error()))
+		return false, &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "jti",
+			Message: "internal error check session exists",
+			Error:   err,
+		}
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/redis/go-redis/v9"
+)
+
+// ротация атомарна: старый jti удаляется только одним запросом, второй получит 0
+// и будет считаться повторным использованием. Старый jti помечается как выведенный
+var rotateSessionScript = redis.NewScript(`
+if redis.call('DEL', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('SET', KEYS[2], ARGV[1], 'PX', ARGV[2])
+redis.call('SREM', KEYS[3], ARGV[3])
+redis.call('SADD', KEYS[3], ARGV[4])
+redis.call('SREM', KEYS[4], ARGV[3])
+redis.call('SADD', KEYS[4], ARGV[4])
+redis.call('PEXPIRE', KEYS[4], ARGV[2])
+redis.call('SET', KEYS[5], ARGV[5], 'PX', ARGV[2])
+return 1
+`)
+
+// RotateSession заменяет сессию oldJti на newJti в том же семействе, CreatedAt сохраняется - это время входа.
+// not_found - старой сессии уже нет (удалена или ротирована параллельным запросом)
+func (c *SessionStorage) RotateSession(ctx context.Context, oldJti string, newJti string, data SessionData, ttlHours int) *errorsApp.DbError {
+	op := "cache.SessionStorage.RotateSession"
+	log := c.log.With(slog.String("op", op))
+
+	data.LastUsedAt = time.Now()
+	if data.CreatedAt.IsZero() {
+		data.CreatedAt = data.LastUsedAt
+	}
+	data.Jti = newJti
+	data.ParentJti = oldJti
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		log.Error("error marshal session data", slog.String("err", err.Error()))
+		return &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "data",
+			Message: "internal error marshal session data",
+			Error:   err,
+		}
+	}
+
+	ttl := time.Duration(ttlHours) * time.Hour
+	keys := []string{
+		"jti:" + oldJti,
+		"jti:" + newJti,
+		"user_id:" + strconv.FormatInt(data.UserID, 10),
+		"family:" + data.FamilyID,
+		"retired_jti:" + oldJti,
+	}
+	rotated, err := rotateSessionScript.Run(ctx, c.RDB, keys,
+		jsonData, ttl.Milliseconds(), oldJti, newJti, data.FamilyID).Int()
+	if err != nil {
+		log.Error("error rotate session", slog.String("err", err.Error()))
+		return &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "data",
+			Message: "internal error rotate session",
+			Error:   err,
+		}
+	}
+	if rotated == 0 {
+		return &errorsApp.DbError{
+			Type:    "not_found",
+			Field:   "jti",
+			Message: "session not found",
+		}
+	}
+	return nil
+}
+
+// GetRetiredJtiFamily возвращает семейство, если jti уже был ротирован, пустая строка - не ротировался
+func (c *SessionStorage) GetRetiredJtiFamily(ctx context.Context, jti string) (string, *errorsApp.DbError) {
+	op := "cache.SessionStorage.GetRetiredJtiFamily"
+	log := c.log.With(slog.String("op", op))
+
+	familyId, err := c.RDB.Get(ctx, "retired_jti:"+jti).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		log.Error("error get retired jti", slog.String("err", err.Error()))
+		return "", &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "jti",
+			Message: "internal error get retired jti",
+			Error:   err,
+		}
+	}
+	return familyId, nil
+}
+
+// DeleteSessionFamily удаляет все живые сессии семейства
+func (c *SessionStorage) DeleteSessionFamily(ctx context.Context, familyId string, userId int64) *errorsApp.DbError {
+	op := "cache.SessionStorage.DeleteSessionFamily"
+	log := c.log.With(slog.String("op", op))
+
+	familyKey := "family:" + familyId
+	userIndexKey := "user_id:" + strconv.FormatInt(userId, 10)
+
+	jtis, err := c.RDB.SMembers(ctx, familyKey).Result()
+	if err != nil {
+		log.Error("error get session family", slog.String("err", err.Error()))
+		return &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "family",
+			Message: "internal error get session family",
+			Error:   err,
+		}
+	}
+
+	pipe := c.RDB.TxPipeline()
+	for _, jti := range jtis {
+		pipe.Del(ctx, "jti:"+jti)
+		pipe.SRem(ctx, userIndexKey, jti)
+	}
+	pipe.Del(ctx, familyKey)
+
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		log.Error("error delete session family", slog.String("err", err.Error()))
+		return &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "family",
+			Message: "internal error delete session family",
+			Error:   err,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/redis/go-redis/v9"
+)
+
+// GetSessionIndexUserIds возвращает id пользователей, для которых есть индекс user_id:<id>
+func (c *SessionStorage) GetSessionIndexUserIds(ctx context.Context) ([]int64, *errorsApp.DbError) {
+	op := "cache.SessionStorage.GetSessionIndexUserIds"
+	log := c.log.With(slog.String("op", op))
+
+	ids := make([]int64, 0)
+	iter := c.RDB.Scan(ctx, 0, "user_id:*", 1000).Iterator()
+	for iter.Next(ctx) {
+		id, err := strconv.ParseInt(strings.TrimPrefix(iter.Val(), "user_id:"), 10, 64)
+		if err != nil {
+			log.Warn("invalid session index key", slog.String("key", iter.Val()))
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if err := iter.Err(); err != nil {
+		log.Error("error scan session indexes", slog.String("err", err.Error()))
+		return nil, &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "index",
+			Message: "internal error scan session indexes",
+			Error:   err,
+		}
+	}
+	return ids, nil
+}
+
+// KEYS[1] - индекс user_id:<id>, KEYS[i+1] - jti:<ARGV[i+1]>; ARGV[1] = "1" - удалять, иначе только посчитать (dry run)
+var pruneSessionIndexScript = redis.NewScript(`
+local stale = 0
+for i = 2, #KEYS do
+	if redis.call('EXISTS', KEYS[i]) == 0 then
+		stale = stale + 1
+		if ARGV[1] == '1' then
+			redis.call('SREM', KEYS[1], ARGV[i])
+		end
+	end
+end
+return stale
+`)
+
+// PruneSessionIndex убирает из индекса user_id:<id> ссылки на истекшие сессии, возвращает их число.
+// Опустевший индекс Redis удаляет сам
+func (c *SessionStorage) PruneSessionIndex(ctx context.Context, userId int64, dryRun bool) (int64, *errorsApp.DbError) {
+	op := "cache.SessionStorage.PruneSessionIndex"
+	log := c.log.With(slog.String("op", op))
+
+	indexKey := "user_id:" + fmt.Sprintf("%d", userId)
+	apply := "1"
+	if dryRun {
+		apply = "0"
+	}
+
+	jtis, err := c.RDB.SMembers(ctx, indexKey).Result()
+	if err != nil {
+		log.Error("error get session index", slog.String("err", err.Error()))
+		return 0, &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "index",
+			Message: "internal error prune session index",
+			Error:   err,
+		}
+	}
+	if len(jtis) == 0 {
+		return 0, nil
+	}
+	// сессия, созданная после чтения индекса, в проверку не попадет и останется в индексе
+	keys := []string{indexKey}
+	args := []any{apply}
+	for _, jti := range jtis {
+		keys = append(keys, "jti:"+jti)
+		args = append(args, jti)
+	}
+
+	stale, err := pruneSessionIndexScript.Run(ctx, c.RDB, keys, args...).Int64()
+	if err != nil {
+		log.Error("error prune session index", slog.String("err", err.Error()))
+		return 0, &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "index",
+			Message: "internal error prune session index",
+			Error:   err,
+		}
+	}
+	return stale, nil
+}
@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/redis/go-redis/v9"
+)
+
+// useTotpStepScript запоминает последний принятый интервал TOTP пользователя,
+// код из того же или более раннего интервала повторно не принимается
+var useTotpStepScript = redis.NewScript(`
+local last = tonumber(redis.call("GET", KEYS[1]) or "-1")
+local step = tonumber(ARGV[1])
+if step <= last then
+	return 0
+end
+redis.call("SET", KEYS[1], step, "PX", ARGV[2])
+return 1
+`)
+
+// UseTotpStep отмечает интервал TOTP использованным, false - код из этого интервала уже применялся
+func (c *OtpStorage) UseTotpStep(ctx context.Context, userId int64, step int64, ttl time.Duration) (bool, *errorsApp.DbError) {
+	op := "cache.OtpStorage.UseTotpStep"
+	log := c.log.With(slog.String("op", op))
+
+	key := "totp_step:" + strconv.FormatInt(userId, 10)
+	ok, err := useTotpStepScript.Run(ctx, c.RDB, []string{key}, step, ttl.Milliseconds()).Int()
+	if err != nil {
+		log.Error("error use totp step", slog.String("err", err.Error()))
+		return false, &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "user_id",
+			Message: "internal error use totp step",
+			Error:   err,
+		}
+	}
+	return ok == 1, nil
+}
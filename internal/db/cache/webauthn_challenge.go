@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+)
+
+// WebauthnChallengeData - состояние церемонии WebAuthn между options и finish
+type WebauthnChallengeData struct {
+	Token     string          `json:"token"`
+	Ceremony  string          `json:"ceremony"` // register или login
+	UserID    int64           `json:"user_id"`  // 0 - вход по passkey без указания пользователя
+	Session   json.RawMessage `json:"session"`  // webauthn.SessionData
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+func (c *SessionStorage) SaveWebauthnChallenge(ctx context.Context, data WebauthnChallengeData, ttl time.Duration) *errorsApp.DbError {
+	op := "cache.SessionStorage.SaveWebauthnChallenge"
+	log := c.log.With(slog.String("op", op))
+
+	data.CreatedAt = time.Now()
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		log.Error("error marshal webauthn challenge", slog.String("err", err.Error()))
+		return &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "data",
+			Message: "internal error marshal webauthn challenge",
+			Error:   err,
+		}
+	}
+
+	err = c.RDB.Set(ctx, "webauthn_challenge:"+data.Token, jsonData, ttl).Err()
+	if err != nil {
+		log.Error("error save webauthn challenge", slog.String("err", err.Error()))
+		return &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "data",
+			Message: "internal error save webauthn challenge",
+			Error:   err,
+		}
+	}
+	return nil
+}
+
+// PopWebauthnChallenge возвращает и сразу удаляет challenge, каждая церемония проверяется один раз
+func (c *SessionStorage) PopWebauthnChallenge(ctx context.Context, token string) (WebauthnChallengeData, *errorsApp.DbError) {
+	op := "cache.SessionStorage.PopWebauthnChallenge"
+	log := c.log.With(slog.String("op", op))
+
+	data := WebauthnChallengeData{}
+
+	val, err := c.RDB.GetDel(ctx, "webauthn_challenge:"+token).Bytes()
+	if err != nil {
+		log.Warn("error get webauthn challenge", slog.String("err", err.Error()))
+		return data, &errorsApp.DbError{
+			Type:    "not_found",
+			Field:   "token",
+			Message: "webauthn challenge not found",
+			Error:   err,
+		}
+	}
+
+	err = json.Unmarshal(val, &data)
+	if err != nil {
+		log.Error("error unmarshal webauthn challenge", slog.String("err", err.Error()))
+		return data, &errorsApp.DbError{
+			Type:    "internal_error",
+			Field:   "data",
+			Message: "internal error unmarshal webauthn challenge",
+			Error:   err,
+		}
+	}
+	return data, nil
+}
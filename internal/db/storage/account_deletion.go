@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+// RequestUserDeletion отмечает запрос пользователя на удаление аккаунта
+func (s *Storage) RequestUserDeletion(ctx context.Context, id int64) *errorsApp.DbError {
+	op := "storage.RequestUserDeletion"
+	log := s.log.With("op", op)
+
+	query := `UPDATE "users" SET deletion_requested_at = $1, changed_date = $1 WHERE id = $2 AND deleted_at IS NULL`
+
+	tag, err := s.Db.Exec(ctx, query, time.Now(), id)
+	return userExecResult(log, tag, err, id)
+}
+
+// CancelUserDeletion снимает запрос на удаление (вход в льготный период)
+func (s *Storage) CancelUserDeletion(ctx context.Context, id int64) *errorsApp.DbError {
+	op := "storage.CancelUserDeletion"
+	log := s.log.With("op", op)
+
+	query := `UPDATE "users" SET deletion_requested_at = NULL, changed_date = $1 WHERE id = $2 AND deleted_at IS NULL`
+
+	tag, err := s.Db.Exec(ctx, query, time.Now(), id)
+	return userExecResult(log, tag, err, id)
+}
+
+// GetUserIdsForPurge возвращает пользователей, запросивших удаление раньше before и еще не обезличенных
+func (s *Storage) GetUserIdsForPurge(ctx context.Context, before time.Time) ([]int64, *errorsApp.DbError) {
+	op := "storage.GetUserIdsForPurge"
+	log := s.log.With("op", op)
+
+	ids := []int64{}
+	query := `SELECT id FROM "users" WHERE deletion_requested_at < $1 AND deleted_at IS NULL ORDER BY id`
+
+	err := pgxscan.Select(ctx, s.Db, &ids, query, before)
+	if err != nil {
+		log.Error(err.Error())
+		return ids, mapPgError(err)
+	}
+	return ids, nil
+}
+
+// AnonymizeDeletedUser обезличивает пользователя, запросившего удаление раньше before.
+// Если запрос снят (вход в льготный период) после выборки GetUserIdsForPurge, возвращает not_found
+func (s *Storage) AnonymizeDeletedUser(ctx context.Context, id int64, before time.Time) *errorsApp.DbError {
+	return s.anonymizeUser(ctx, "storage.AnonymizeDeletedUser", id, `u.deletion_requested_at < $2`, before)
+}
+
+// AnonymizeUnverifiedUser обезличивает пользователя по тем же условиям, что и GetUnverifiedUserIds.
+// Если пользователь успел подтвердить адрес или добавить способ входа после выборки, возвращает not_found
+func (s *Storage) AnonymizeUnverifiedUser(ctx context.Context, id int64, before time.Time) *errorsApp.DbError {
+	return s.anonymizeUser(ctx, "storage.AnonymizeUnverifiedUser", id, unverifiedUserCondition("$2"), before)
+}
+
+// unverifiedUserCondition - пользователь u без подтвержденных адресов и способов входа без адреса,
+// созданный раньше параметра before
+func unverifiedUserCondition(before string) string {
+	return `u.email_verified_at IS NULL AND u.phone_verified_at IS NULL
+		AND u.create_date < ` + before + `
+		AND NOT EXISTS (SELECT 1 FROM "oauth_accounts" o WHERE o.user_id = u.id)
+		AND NOT EXISTS (SELECT 1 FROM "webauthn_credentials" w WHERE w.user_id = u.id)`
+}
+
+// anonymizeUser обезличивает пользователя: строка остается (на нее могут ссылаться другие таблицы),
+// но без контактов и способов входа; oauth-аккаунты, коды восстановления 2FA и ключи WebAuthn удаляются,
+// события журнала входов отвязываются от пользователя и теряют адрес, IP и user agent.
+// Условие condition проверяется под блокировкой строки до удаления связанных данных:
+// вход или подтверждение адреса после выборки отменяют обезличивание
+func (s *Storage) anonymizeUser(ctx context.Context, op string, id int64, condition string, before time.Time) *errorsApp.DbError {
+	log := s.log.With("op", op)
+
+	tx, err := s.Db.Begin(ctx)
+	if err != nil {
+		log.Error(err.Error())
+		return mapPgError(err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	// FOR UPDATE ждет параллельные изменения пользователя и не дает добавить ему oauth-аккаунт или ключ до конца транзакции
+	var lockedId int64
+	query := `SELECT u.id FROM "users" u WHERE u.id = $1 AND u.deleted_at IS NULL AND ` + condition + ` FOR UPDATE`
+	err = tx.QueryRow(ctx, query, id, before).Scan(&lockedId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &errorsApp.DbError{
+			Type:    "not_found",
+			Field:   "id",
+			Data:    id,
+			Message: "user not found",
+			Error:   errors.New("user with id " + strconv.FormatInt(id, 10) + " not found or no longer matches"),
+		}
+	}
+	if err != nil {
+		log.Error(err.Error())
+		return mapPgError(err)
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM "oauth_accounts" WHERE user_id = $1`, id)
+	if err != nil {
+		log.Error(err.Error())
+		return mapPgError(err)
+	}
+	_, err = tx.Exec(ctx, `DELETE FROM "user_recovery_codes" WHERE user_id = $1`, id)
+	if err != nil {
+		log.Error(err.Error())
+		return mapPgError(err)
+	}
+	_, err = tx.Exec(ctx, `DELETE FROM "webauthn_credentials" WHERE user_id = $1`, id)
+	if err != nil {
+		log.Error(err.Error())
+		return mapPgError(err)
+	}
+	// события остаются в журнале для статистики и расследований, но без связи с пользователем и его данных
+	_, err = tx.Exec(ctx, `UPDATE "auth_events" SET user_id = NULL, identity = NULL, ip = NULL, user_agent = NULL WHERE user_id = $1`, id)
+	if err != nil {
+		log.Error(err.Error())
+		return mapPgError(err)
+	}
+	query = `UPDATE "users" SET name = 'deleted user', email = NULL, phone_number = NULL, password_hash = NULL,
+		email_verified_at = NULL, phone_verified_at = NULL, totp_secret = NULL, totp_enabled_at = NULL,
+		deleted_at = $1, changed_date = $1
+		WHERE id = $2 AND deleted_at IS NULL`
+	tag, err := tx.Exec(ctx, query, time.Now(), id)
+	if dbError := userExecResult(log, tag, err, id); dbError != nil {
+		return dbError
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error(err.Error())
+		return mapPgError(err)
+	}
+	return nil
+}
+
+// GetUnverifiedUserIds возвращает пользователей без подтвержденного email и телефона, созданных раньше before.
+// Пользователи с oauth-аккаунтом или ключом WebAuthn входят без подтверждения адреса и не попадают в выборку
+func (s *Storage) GetUnverifiedUserIds(ctx context.Context, before time.Time) ([]int64, *errorsApp.DbError) {
+	op := "storage.GetUnverifiedUserIds"
+	log := s.log.With("op", op)
+
+	ids := []int64{}
+	query := `SELECT u.id FROM "users" u WHERE u.deleted_at IS NULL AND ` + unverifiedUserCondition("$1") + ` ORDER BY u.id`
+
+	err := pgxscan.Select(ctx, s.Db, &ids, query, before)
+	if err != nil {
+		log.Error(err.Error())
+		return ids, mapPgError(err)
+	}
+	return ids, nil
+}
+
+// GetActiveUserIds возвращает те из ids, которые есть в users и не обезличены
+func (s *Storage) GetActiveUserIds(ctx context.Context, ids []int64) ([]int64, *errorsApp.DbError) {
+	op := "storage.GetActiveUserIds"
+	log := s.log.With("op", op)
+
+	active := []int64{}
+	query := `SELECT id FROM "users" WHERE id = ANY($1) AND deleted_at IS NULL`
+
+	err := pgxscan.Select(ctx, s.Db, &active, query, ids)
+	if err != nil {
+		log.Error(err.Error())
+		return active, mapPgError(err)
+	}
+	return active, nil
+}
@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func (s *Storage) ListUsers(ctx context.Context, filter models.UsersFilter) ([]models.UserEntity, int64, *errorsApp.DbError) {
+	op := "storage.ListUsers"
+	log := s.log.With("op", op)
+
+	users := []models.UserEntity{}
+	where := ` WHERE 1=1`
+	args := []any{}
+
+	if filter.Verified.Valid {
+		if filter.Verified.Bool {
+			where += ` AND (email_verified_at IS NOT NULL OR phone_verified_at IS NOT NULL)`
+		} else {
+			where += ` AND email_verified_at IS NULL AND phone_verified_at IS NULL`
+		}
+	}
+	if filter.RoleName != "" {
+		args = append(args, filter.RoleName)
+		where += ` AND role_id = (SELECT id FROM "roles" WHERE name = $` + strconv.Itoa(len(args)) + `)`
+	}
+	if filter.CreatedFrom.Valid {
+		args = append(args, filter.CreatedFrom.Time)
+		where += ` AND create_date >= $` + strconv.Itoa(len(args))
+	}
+	if filter.CreatedTo.Valid {
+		args = append(args, filter.CreatedTo.Time)
+		where += ` AND create_date < $` + strconv.Itoa(len(args))
+	}
+
+	var total int64
+	err := s.Db.QueryRow(ctx, `SELECT count(*) FROM "users"`+where, args...).Scan(&total)
+	if err != nil {
+		log.Error(err.Error())
+		return users, 0, mapPgError(err)
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := `SELECT * FROM "users"` + where + ` ORDER BY id LIMIT $` + strconv.Itoa(len(args)-1) + ` OFFSET $` + strconv.Itoa(len(args))
+
+	err = pgxscan.Select(ctx, s.Db, &users, query, args...)
+	if err != nil {
+		log.Error(err.Error())
+		return users, 0, mapPgError(err)
+	}
+	return users, total, nil
+}
+
+func (s *Storage) GetRoles(ctx context.Context) ([]models.RoleEntity, *errorsApp.DbError) {
+	op := "storage.GetRoles"
+	log := s.log.With("op", op)
+
+	roles := []models.RoleEntity{}
+	err := pgxscan.Select(ctx, s.Db, &roles, `SELECT * FROM "roles" ORDER BY id`)
+	if err != nil {
+		log.Error(err.Error())
+		return roles, mapPgError(err)
+	}
+	return roles, nil
+}
+
+func (s *Storage) UpdateUserRole(ctx context.Context, id int64, roleId int64) *errorsApp.DbError {
+	op := "storage.UpdateUserRole"
+	log := s.log.With("op", op)
+
+	query := `UPDATE "users" SET role_id = $1, changed_date = $2 WHERE id = $3`
+
+	tag, err := s.Db.Exec(ctx, query, roleId, time.Now(), id)
+	return userExecResult(log, tag, err, id)
+}
+
+// SetUserBlocked блокирует (blocked = true) или разблокирует пользователя
+func (s *Storage) SetUserBlocked(ctx context.Context, id int64, blocked bool) *errorsApp.DbError {
+	op := "storage.SetUserBlocked"
+	log := s.log.With("op", op)
+
+	query := `UPDATE "users" SET blocked_at = NULL, changed_date = $1 WHERE id = $2`
+	if blocked {
+		query = `UPDATE "users" SET blocked_at = $1, changed_date = $1 WHERE id = $2`
+	}
+
+	tag, err := s.Db.Exec(ctx, query, time.Now(), id)
+	return userExecResult(log, tag, err, id)
+}
+
+// DeleteUser удаляет пользователя вместе с привязанными oauth-аккаунтами, кодами восстановления 2FA и ключами WebAuthn
+func (s *Storage) DeleteUser(ctx context.Context, id int64) *errorsApp.DbError {
+	op := "storage.DeleteUser"
+	log := s.log.With("op", op)
+
+	tx, err := s.Db.Begin(ctx)
+	if err != nil {
+		log.Error(err.Error())
+		return mapPgError(err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	_, err = tx.Exec(ctx, `DELETE FROM "oauth_accounts" WHERE user_id = $1`, id)
+	if err != nil {
+		log.Error(err.Error())
+		return mapPgError(err)
+	}
+	_, err = tx.Exec(ctx, `DELETE FROM "user_recovery_codes" WHERE user_id = $1`, id)
+	if err != nil {
+		log.Error(err.Error())
+		return mapPgError(err)
+	}
+	_, err = tx.Exec(ctx, `DELETE FROM "webauthn_credentials" WHERE user_id = $1`, id)
+	if err != nil {
+		log.Error(err.Error())
+		return mapPgError(err)
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM "users" WHERE id = $1`, id)
+	if dbError := userExecResult(log, tag, err, id); dbError != nil {
+		return dbError
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error(err.Error())
+		return mapPgError(err)
+	}
+	return nil
+}
+
+// userExecResult приводит результат UPDATE/DELETE по id пользователя к DbError
+func userExecResult(log *slog.Logger, tag pgconn.CommandTag, err error, id int64) *errorsApp.DbError {
+	if err != nil {
+		log.Error(err.Error())
+		return mapPgError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return &errorsApp.DbError{
+			Type:    "not_found",
+			Field:   "id",
+			Data:    id,
+			Message: "user not found",
+			Error:   errors.New("user with id " + strconv.FormatInt(id, 10) + " not found"),
+		}
+	}
+	return nil
+}
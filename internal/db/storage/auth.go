@@ -199,3 +199,25 @@ func (s *Storage) UpdatePassword(ctx context.Context, id int64, password string)
 	}
 	return nil
 }
+
+// UpdateUserEmail меняет email на уже подтвержденный кодом адрес
+func (s *Storage) UpdateUserEmail(ctx context.Context, id int64, email string) *errorsApp.DbError {
+	op := "storage.UpdateUserEmail"
+	log := s.log.With("op", op)
+
+	query := `UPDATE "users" SET email = $1, email_verified_at = $2, changed_date = $2 WHERE id = $3`
+
+	tag, err := s.Db.Exec(ctx, query, email, time.Now(), id)
+	return userExecResult(log, tag, err, id)
+}
+
+// UpdateUserPhoneNumber меняет телефон на уже подтвержденный кодом номер
+func (s *Storage) UpdateUserPhoneNumber(ctx context.Context, id int64, phoneNumber string) *errorsApp.DbError {
+	op := "storage.UpdateUserPhoneNumber"
+	log := s.log.With("op", op)
+
+	query := `UPDATE "users" SET phone_number = $1, phone_verified_at = $2, changed_date = $2 WHERE id = $3`
+
+	tag, err := s.Db.Exec(ctx, query, phoneNumber, time.Now(), id)
+	return userExecResult(log, tag, err, id)
+}
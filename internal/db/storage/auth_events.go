@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
+	"github.com/georgysavva/scany/v2/pgxscan"
+)
+
+func (s *Storage) NewAuthEvent(ctx context.Context, event models.AuthEventEntity) *errorsApp.DbError {
+	op := "storage.NewAuthEvent"
+	log := s.log.With("op", op)
+
+	query := `INSERT INTO "auth_events" (user_id, event_type, outcome, reason, identity, ip, user_agent) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := s.Db.Exec(ctx, query, event.User_id, string(event.Event_type), event.Outcome, event.Reason, event.Identity, event.Ip, event.User_agent)
+	if err != nil {
+		log.Error(err.Error())
+		return mapPgError(err)
+	}
+	return nil
+}
+
+// ListAuthEvents возвращает события журнала по фильтру, новые первыми, и общее число подходящих
+func (s *Storage) ListAuthEvents(ctx context.Context, filter models.AuthEventsFilter) ([]models.AuthEventEntity, int64, *errorsApp.DbError) {
+	op := "storage.ListAuthEvents"
+	log := s.log.With("op", op)
+
+	events := []models.AuthEventEntity{}
+	where := ` WHERE 1=1`
+	args := []any{}
+
+	if filter.UserId.Valid {
+		args = append(args, filter.UserId.Int64)
+		where += ` AND user_id = $` + strconv.Itoa(len(args))
+	}
+	if filter.EventType != "" {
+		args = append(args, filter.EventType)
+		where += ` AND event_type = $` + strconv.Itoa(len(args))
+	}
+	if filter.Outcome != "" {
+		args = append(args, filter.Outcome)
+		where += ` AND outcome = $` + strconv.Itoa(len(args))
+	}
+	if filter.Ip != "" {
+		args = append(args, filter.Ip)
+		where += ` AND ip = $` + strconv.Itoa(len(args))
+	}
+	if filter.Identity != "" {
+		args = append(args, filter.Identity)
+		where += ` AND identity = $` + strconv.Itoa(len(args))
+	}
+	if filter.UserAgent != "" {
+		args = append(args, filter.UserAgent)
+		where += ` AND user_agent = $` + strconv.Itoa(len(args))
+	}
+	if filter.From.Valid {
+		args = append(args, filter.From.Time)
+		where += ` AND create_date >= $` + strconv.Itoa(len(args))
+	}
+	if filter.To.Valid {
+		args = append(args, filter.To.Time)
+		where += ` AND create_date < $` + strconv.Itoa(len(args))
+	}
+
+	var total int64
+	err := s.Db.QueryRow(ctx, `SELECT count(*) FROM "auth_events"`+where, args...).Scan(&total)
+	if err != nil {
+		log.Error(err.Error())
+		return events, 0, mapPgError(err)
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := `SELECT * FROM "auth_events"` + where + ` ORDER BY create_date DESC, id DESC LIMIT $` + strconv.Itoa(len(args)-1) + ` OFFSET $` + strconv.Itoa(len(args))
+
+	err = pgxscan.Select(ctx, s.Db, &events, query, args...)
+	if err != nil {
+		log.Error(err.Error())
+		return events, 0, mapPgError(err)
+	}
+	return events, total, nil
+}
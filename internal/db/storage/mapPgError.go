@@ -39,9 +39,10 @@ func mapPgError(err error) *errorsApp.DbError {
 
 		}
 	}
+	// pgErr == nil, если ошибка не от Postgres (сеть, контекст, сканирование)
 	return &errorsApp.DbError{
 		Type:    "unknown_database_error",
 		Message: "внутренняя ошибка базы данных",
-		Error:   fmt.Errorf("внутренняя ошибка базы данных: %s", pgErr.Message),
+		Error:   fmt.Errorf("внутренняя ошибка базы данных: %w", err),
 	}
 }
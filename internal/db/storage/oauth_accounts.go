@@ -15,7 +15,7 @@ func (s *Storage) NewOauthAccount(ctx context.Context, user models.OauthAccountE
 	op := "storage.NewOauthAccount"
 	log := s.log.With("op", op)
 
-	query := `INSERT INTO "oauth_accounts" (user_id, provider, provider_user_id) VALUES ($1, $2, $3 RETURNING *`
+	query := `INSERT INTO "oauth_accounts" (user_id, provider, provider_user_id) VALUES ($1, $2, $3) RETURNING *`
 
 	rows, err := s.Db.Query(ctx, query, user.User_id, user.Provider, user.Provider_user_id)
 	if err != nil {
@@ -56,26 +56,105 @@ func (s *Storage) GetOauthAccountById(ctx context.Context, id int64) (models.Oau
 	return account, nil
 }
 
-func (s *Storage) GetOauthAccountByUserId(ctx context.Context, id int64) (models.OauthAccountEntity, *errorsApp.DbError) {
-	op := "storage.GetOauthAccountByUserId"
+func (s *Storage) GetOauthAccountsByUserId(ctx context.Context, id int64) ([]models.OauthAccountEntity, *errorsApp.DbError) {
+	op := "storage.GetOauthAccountsByUserId"
 	log := s.log.With("op", op)
 
-	query := `SELECT * FROM "oauth_accounts" WHERE user_id = $1`
-	account := models.OauthAccountEntity{}
+	query := `SELECT * FROM "oauth_accounts" WHERE user_id = $1 ORDER BY id`
+	accounts := []models.OauthAccountEntity{}
 
-	err := pgxscan.Get(ctx, s.Db, &account, query, id)
+	err := pgxscan.Select(ctx, s.Db, &accounts, query, id)
 	if err != nil {
 		log.Error(err.Error())
+		return accounts, mapPgError(err)
+	}
+	return accounts, nil
+}
+
+func (s *Storage) DeleteOauthAccount(ctx context.Context, id int64, userId int64) *errorsApp.DbError {
+	op := "storage.DeleteOauthAccount"
+	log := s.log.With("op", op)
+
+	query := `DELETE FROM "oauth_accounts" WHERE id = $1 AND user_id = $2`
+
+	tag, err := s.Db.Exec(ctx, query, id, userId)
+	if err != nil {
+		log.Error(err.Error())
+		return mapPgError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return &errorsApp.DbError{
+			Type:    "not_found",
+			Field:   "id",
+			Data:    id,
+			Message: "oauth account not found",
+			Error:   errors.New("oauth account with id " + strconv.FormatInt(id, 10) + " not found"),
+		}
+	}
+	return nil
+}
+
+func (s *Storage) GetOauthAccountByProvider(ctx context.Context, provider string, providerUserId string) (models.OauthAccountEntity, *errorsApp.DbError) {
+	op := "storage.GetOauthAccountByProvider"
+	log := s.log.With("op", op)
+
+	query := `SELECT * FROM "oauth_accounts" WHERE provider = $1 AND provider_user_id = $2`
+	account := models.OauthAccountEntity{}
+
+	err := pgxscan.Get(ctx, s.Db, &account, query, provider, providerUserId)
+	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return account, &errorsApp.DbError{
 				Type:    "not_found",
-				Field:   "user_id",
-				Data:    id,
+				Field:   "provider_user_id",
+				Data:    providerUserId,
 				Message: "oauth account not found",
-				Error:   errors.New("oauth account with user_id " + strconv.FormatInt(id, 10) + " not found"),
+				Error:   errors.New("oauth account " + provider + ":" + providerUserId + " not found"),
 			}
 		}
+		log.Error(err.Error())
 		return account, mapPgError(err)
 	}
 	return account, nil
 }
+
+// NewUserWithOauthAccount создает пользователя и привязанный к нему oauth-аккаунт в одной транзакции
+func (s *Storage) NewUserWithOauthAccount(ctx context.Context, user models.UserEntity, account models.OauthAccountEntity) (models.UserEntity, *errorsApp.DbError) {
+	op := "storage.NewUserWithOauthAccount"
+	log := s.log.With("op", op)
+
+	tx, err := s.Db.Begin(ctx)
+	if err != nil {
+		log.Error(err.Error())
+		return user, mapPgError(err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	query := `INSERT INTO "users" (name, phone_number, email, password_hash, role_id, email_verified_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING *`
+	rows, err := tx.Query(ctx, query, user.Name, user.Phone_number, user.Email, user.Password_hash, user.Role_id, user.Email_verified_at)
+	if err != nil {
+		log.Error(err.Error())
+		return user, mapPgError(err)
+	}
+	savedUser, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[models.UserEntity])
+	if err != nil {
+		log.Error(err.Error())
+		return user, mapPgError(err)
+	}
+
+	queryOauth := `INSERT INTO "oauth_accounts" (user_id, provider, provider_user_id) VALUES ($1, $2, $3)`
+	_, err = tx.Exec(ctx, queryOauth, savedUser.Id, account.Provider, account.Provider_user_id)
+	if err != nil {
+		log.Error(err.Error())
+		return user, mapPgError(err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error(err.Error())
+		return user, mapPgError(err)
+	}
+
+	return savedUser, nil
+}
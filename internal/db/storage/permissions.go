@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
+	"github.com/georgysavva/scany/v2/pgxscan"
+)
+
+func (s *Storage) GetPermissionsByRoleId(ctx context.Context, roleId int64) ([]models.PermissionEntity, *errorsApp.DbError) {
+	op := "storage.GetPermissionsByRoleId"
+	log := s.log.With("op", op)
+
+	query := `SELECT p.* FROM "permissions" p
+		JOIN "role_permissions" rp ON rp.permission_id = p.id
+		WHERE rp.role_id = $1`
+	permissions := []models.PermissionEntity{}
+
+	err := pgxscan.Select(ctx, s.Db, &permissions, query, roleId)
+	if err != nil {
+		log.Error(err.Error())
+		return permissions, mapPgError(err)
+	}
+	return permissions, nil
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+)
+
+// SetUserTotpSecret сохраняет новый (еще не подтвержденный) секрет TOTP, 2FA при этом выключена
+func (s *Storage) SetUserTotpSecret(ctx context.Context, id int64, secret string) *errorsApp.DbError {
+	op := "storage.SetUserTotpSecret"
+	log := s.log.With("op", op)
+
+	query := `UPDATE "users" SET totp_secret = $1, totp_enabled_at = NULL, changed_date = $2 WHERE id = $3`
+
+	tag, err := s.Db.Exec(ctx, query, secret, time.Now(), id)
+	return userExecResult(log, tag, err, id)
+}
+
+// EnableUserTotp включает 2FA и заменяет коды восстановления новыми (хэши)
+func (s *Storage) EnableUserTotp(ctx context.Context, id int64, codeHashes []string) *errorsApp.DbError {
+	op := "storage.EnableUserTotp"
+	log := s.log.With("op", op)
+
+	tx, err := s.Db.Begin(ctx)
+	if err != nil {
+		log.Error(err.Error())
+		return mapPgError(err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	tag, err := tx.Exec(ctx, `UPDATE "users" SET totp_enabled_at = $1, changed_date = $1 WHERE id = $2`, time.Now(), id)
+	if dbError := userExecResult(log, tag, err, id); dbError != nil {
+		return dbError
+	}
+	_, err = tx.Exec(ctx, `DELETE FROM "user_recovery_codes" WHERE user_id = $1`, id)
+	if err != nil {
+		log.Error(err.Error())
+		return mapPgError(err)
+	}
+	for _, hash := range codeHashes {
+		_, err = tx.Exec(ctx, `INSERT INTO "user_recovery_codes" (user_id, code_hash) VALUES ($1, $2)`, id, hash)
+		if err != nil {
+			log.Error(err.Error())
+			return mapPgError(err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error(err.Error())
+		return mapPgError(err)
+	}
+	return nil
+}
+
+// DisableUserTotp выключает 2FA, удаляет секрет и коды восстановления
+func (s *Storage) DisableUserTotp(ctx context.Context, id int64) *errorsApp.DbError {
+	op := "storage.DisableUserTotp"
+	log := s.log.With("op", op)
+
+	tx, err := s.Db.Begin(ctx)
+	if err != nil {
+		log.Error(err.Error())
+		return mapPgError(err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	_, err = tx.Exec(ctx, `DELETE FROM "user_recovery_codes" WHERE user_id = $1`, id)
+	if err != nil {
+		log.Error(err.Error())
+		return mapPgError(err)
+	}
+	tag, err := tx.Exec(ctx, `UPDATE "users" SET totp_secret = NULL, totp_enabled_at = NULL, changed_date = $1 WHERE id = $2`, time.Now(), id)
+	if dbError := userExecResult(log, tag, err, id); dbError != nil {
+		return dbError
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error(err.Error())
+		return mapPgError(err)
+	}
+	return nil
+}
+
+// UseRecoveryCode отмечает код восстановления использованным, false - кода нет или он уже использован
+func (s *Storage) UseRecoveryCode(ctx context.Context, id int64, codeHash string) (bool, *errorsApp.DbError) {
+	op := "storage.UseRecoveryCode"
+	log := s.log.With("op", op)
+
+	query := `UPDATE "user_recovery_codes" SET used_at = $1 WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL`
+
+	tag, err := s.Db.Exec(ctx, query, time.Now(), id, codeHash)
+	if err != nil {
+		log.Error(err.Error())
+		return false, mapPgError(err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// CountRecoveryCodes возвращает количество неиспользованных кодов восстановления
+func (s *Storage) CountRecoveryCodes(ctx context.Context, id int64) (int64, *errorsApp.DbError) {
+	op := "storage.CountRecoveryCodes"
+	log := s.log.With("op", op)
+
+	var count int64
+	err := s.Db.QueryRow(ctx, `SELECT count(*) FROM "user_recovery_codes" WHERE user_id = $1 AND used_at IS NULL`, id).Scan(&count)
+	if err != nil {
+		log.Error(err.Error())
+		return 0, mapPgError(err)
+	}
+	return count, nil
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Storage) NewWebauthnCredential(ctx context.Context, credential models.WebauthnCredentialEntity) (models.WebauthnCredentialEntity, *errorsApp.DbError) {
+	op := "storage.NewWebauthnCredential"
+	log := s.log.With("op", op)
+
+	query := `INSERT INTO "webauthn_credentials" (user_id, credential_id, public_key, attestation_type, aaguid, sign_count, transports,
+		user_present, user_verified, backup_eligible, backup_state, name)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING *`
+
+	rows, err := s.Db.Query(ctx, query, credential.User_id, credential.Credential_id, credential.Public_key, credential.Attestation_type,
+		credential.Aaguid, credential.Sign_count, credential.Transports, credential.User_present, credential.User_verified,
+		credential.Backup_eligible, credential.Backup_state, credential.Name)
+	if err != nil {
+		log.Error(err.Error())
+		return credential, mapPgError(err)
+	}
+
+	saved, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[models.WebauthnCredentialEntity])
+	if err != nil {
+		log.Error(err.Error())
+		return credential, mapPgError(err)
+	}
+	return saved, nil
+}
+
+func (s *Storage) GetWebauthnCredentialsByUserId(ctx context.Context, id int64) ([]models.WebauthnCredentialEntity, *errorsApp.DbError) {
+	op := "storage.GetWebauthnCredentialsByUserId"
+	log := s.log.With("op", op)
+
+	query := `SELECT * FROM "webauthn_credentials" WHERE user_id = $1 ORDER BY id`
+	credentials := []models.WebauthnCredentialEntity{}
+
+	err := pgxscan.Select(ctx, s.Db, &credentials, query, id)
+	if err != nil {
+		log.Error(err.Error())
+		return credentials, mapPgError(err)
+	}
+	return credentials, nil
+}
+
+func (s *Storage) GetWebauthnCredentialByCredentialId(ctx context.Context, credentialId []byte) (models.WebauthnCredentialEntity, *errorsApp.DbError) {
+	op := "storage.GetWebauthnCredentialByCredentialId"
+	log := s.log.With("op", op)
+
+	credential := models.WebauthnCredentialEntity{}
+	query := `SELECT * FROM "webauthn_credentials" WHERE credential_id = $1`
+
+	err := pgxscan.Get(ctx, s.Db, &credential, query, credentialId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return credential, &errorsApp.DbError{
+				Type:    "not_found",
+				Field:   "credential_id",
+				Message: "webauthn credential not found",
+				Error:   err,
+			}
+		}
+		log.Error(err.Error())
+		return credential, mapPgError(err)
+	}
+	return credential, nil
+}
+
+// UpdateWebauthnCredentialUsage сохраняет счетчик подписей и флаг резервной копии после успешного входа
+func (s *Storage) UpdateWebauthnCredentialUsage(ctx context.Context, id int64, signCount int64, backupState bool) *errorsApp.DbError {
+	op := "storage.UpdateWebauthnCredentialUsage"
+	log := s.log.With("op", op)
+
+	query := `UPDATE "webauthn_credentials" SET sign_count = $1, backup_state = $2, last_used_at = $3, changed_date = $3 WHERE id = $4`
+
+	_, err := s.Db.Exec(ctx, query, signCount, backupState, time.Now(), id)
+	if err != nil {
+		log.Error(err.Error())
+		return mapPgError(err)
+	}
+	return nil
+}
+
+func (s *Storage) DeleteWebauthnCredential(ctx context.Context, id int64, userId int64) *errorsApp.DbError {
+	op := "storage.DeleteWebauthnCredential"
+	log := s.log.With("op", op)
+
+	query := `DELETE FROM "webauthn_credentials" WHERE id = $1 AND user_id = $2`
+
+	tag, err := s.Db.Exec(ctx, query, id, userId)
+	if err != nil {
+		log.Error(err.Error())
+		return mapPgError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return &errorsApp.DbError{
+			Type:    "not_found",
+			Field:   "id",
+			Data:    id,
+			Message: "webauthn credential not found",
+			Error:   errors.New("webauthn credential with id " + strconv.FormatInt(id, 10) + " not found"),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/guregu/null/v6"
+)
+
+type AdminUsersQueryParams struct {
+	Page        int    `query:"page" validate:"omitempty,gte=1" example:"1"`
+	Limit       int    `query:"limit" validate:"omitempty,gte=1,lte=100" example:"20"`
+	Verified    string `query:"verified" validate:"omitempty,oneof=true false" example:"true"`
+	Role        string `query:"role" validate:"omitempty" example:"user"`
+	CreatedFrom string `query:"created_from" validate:"omitempty,datetime=2006-01-02" example:"2025-01-01"`
+	CreatedTo   string `query:"created_to" validate:"omitempty,datetime=2006-01-02" example:"2025-12-31"`
+}
+
+type AdminUser struct {
+	Id                    int64       `json:"id"`
+	Name                  string      `json:"name"`
+	Email                 null.String `json:"email" swaggertype:"string"`
+	Phone_number          null.String `json:"phone_number" swaggertype:"string"`
+	Role_id               int64       `json:"role_id"`
+	Role_name             string      `json:"role_name"`
+	Email_verified_at     null.Time   `json:"email_verified_at" swaggertype:"string"`
+	Phone_verified_at     null.Time   `json:"phone_verified_at" swaggertype:"string"`
+	Blocked_at            null.Time   `json:"blocked_at" swaggertype:"string"`
+	Deletion_requested_at null.Time   `json:"deletion_requested_at" swaggertype:"string"`
+	Deleted_at            null.Time   `json:"deleted_at" swaggertype:"string"`
+	Create_date           time.Time   `json:"create_date"`
+}
+
+type AdminUsersResponse struct {
+	Users []AdminUser `json:"users"`
+	Total int64       `json:"total"`
+	Page  int         `json:"page"`
+	Limit int         `json:"limit"`
+}
+
+type AdminUpdateRoleRequest struct {
+	Role_id int64 `json:"role_id" validate:"required,gte=1" example:"2"`
+}
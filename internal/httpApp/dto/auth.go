@@ -1,6 +1,7 @@
 package dto
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/guregu/null/v6"
@@ -19,6 +20,8 @@ type AuthRegisterResponse struct {
 	Name         string    `json:"name"`
 	Role_name    string    `json:"role_name"`
 	OtpExpiresAt time.Time `json:"otp_expires_at"`
+	// раньше этого времени повторная отправка кода вернет 429
+	ResendAvailableAt time.Time `json:"resend_available_at"`
 }
 
 type AuthLoginRequest struct {
@@ -33,6 +36,11 @@ type AuthLoginResponse struct {
 	Role_name    string `json:"role_name"`
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
+	// включена 2FA: токенов нет, mfa_token и код отправляются в /auth/2fa/verify
+	MfaRequired bool   `json:"mfa_required,omitempty"`
+	MfaToken    string `json:"mfa_token,omitempty"`
+	// вход отменил запрос на удаление аккаунта
+	AccountRestored bool `json:"account_restored,omitempty"`
 }
 
 type AuthHelloResponse struct {
@@ -52,13 +60,27 @@ type AuthSession struct {
 	Role_id           int64     `json:"role_id"`
 	User_agent        string    `json:"user_agent"`
 	IP                string    `json:"ip"`
+	Browser           string    `json:"browser" example:"Chrome 120.0.0.0"`
+	OS                string    `json:"os" example:"Windows 10"`
+	Device            string    `json:"device" example:"desktop"`
+	Country           string    `json:"country" example:"Казахстан"`
+	City              string    `json:"city" example:"Алматы"`
 	Created_at        time.Time `json:"created_at"`
+	Last_used_at      time.Time `json:"last_used_at"`
 }
 
 type AuthSessionResponse struct {
 	Sessions []AuthSession `json:"sessions"`
 }
 
+type AuthLogoutAllQueryParams struct {
+	KeepCurrent bool `query:"keep_current" example:"true"`
+}
+
+type AuthLogoutAllResponse struct {
+	Revoked int64 `json:"revoked"`
+}
+
 type AuthSendVerifyRequest struct {
 	Type    string `json:"type" validate:"required" swaggertype:"string" example:"phone"`
 	Address string `json:"address" validate:"required" swaggertype:"string" example:"+77012345678"`
@@ -73,6 +95,8 @@ type AuthConfirmVerifyRequest struct {
 
 type AuthSendVerifyResponse struct {
 	OtpExpiresAt time.Time `json:"otp_expires_at"`
+	// раньше этого времени повторная отправка кода вернет 429
+	ResendAvailableAt time.Time `json:"resend_available_at"`
 }
 
 type AuthUpdatePasswordRequest struct {
@@ -80,3 +104,149 @@ type AuthUpdatePasswordRequest struct {
 	OldPassword string `json:"old_password" validate:"required"`
 	NewPassword string `json:"new_password" validate:"required,min=8"`
 }
+
+type AuthResetPasswordRequest struct {
+	Type    string `json:"type" validate:"required,oneof=phone email" swaggertype:"string" example:"phone"`
+	Address string `json:"address" validate:"required" swaggertype:"string" example:"77012345678"`
+}
+
+type AuthConfirmResetPasswordRequest struct {
+	Type        string `json:"type" validate:"required,oneof=phone email" swaggertype:"string" example:"phone"`
+	Address     string `json:"address" validate:"required" swaggertype:"string" example:"77012345678"`
+	Code        string `json:"code" validate:"required,min=6,max=6"`
+	NewPassword string `json:"new_password" validate:"required,min=8"`
+}
+
+// AuthChangeContactRequest - подтверждение паролем или кодом с текущего адреса из /auth/change-contact/code
+type AuthChangeContactRequest struct {
+	Type        string `json:"type" validate:"required,oneof=phone email" swaggertype:"string" example:"email"`
+	Address     string `json:"address" validate:"required" swaggertype:"string" example:"new@mail.com"`
+	Password    string `json:"password,omitempty"`
+	CurrentType string `json:"current_type,omitempty" validate:"omitempty,oneof=phone email" swaggertype:"string" example:"phone"`
+	CurrentCode string `json:"current_code,omitempty" validate:"omitempty,min=6,max=6"`
+}
+
+type AuthChangeContactCodeRequest struct {
+	Type string `json:"type" validate:"required,oneof=phone email" swaggertype:"string" example:"phone"`
+}
+
+type AuthConfirmChangeContactRequest struct {
+	Type    string `json:"type" validate:"required,oneof=phone email" swaggertype:"string" example:"email"`
+	Address string `json:"address" validate:"required,contactAddress" swaggertype:"string" example:"new@mail.com"`
+	Code    string `json:"code" validate:"required,min=6,max=6"`
+}
+
+type AuthDeleteAccountCodeRequest struct {
+	Type string `json:"type" validate:"required,oneof=phone email" swaggertype:"string" example:"email"`
+}
+
+// AuthDeleteAccountRequest - подтверждение паролем или кодом из /me/deletion-code
+type AuthDeleteAccountRequest struct {
+	Password string `json:"password,omitempty"`
+	Type     string `json:"type,omitempty" validate:"omitempty,oneof=phone email" swaggertype:"string" example:"email"`
+	Code     string `json:"code,omitempty" validate:"omitempty,min=6,max=6"`
+}
+
+type AuthDeleteAccountResponse struct {
+	DeletionRequestedAt time.Time `json:"deletion_requested_at"`
+	PurgeAt             time.Time `json:"purge_at"` // до этого момента вход восстанавливает аккаунт
+}
+
+type AuthOtpStartRequest struct {
+	Type    string `json:"type" validate:"required,oneof=phone email" swaggertype:"string" example:"phone"`
+	Address string `json:"address" validate:"required,contactAddress" swaggertype:"string" example:"77012345678"`
+}
+
+type AuthOtpCompleteRequest struct {
+	Type    string `json:"type" validate:"required,oneof=phone email" swaggertype:"string" example:"phone"`
+	Address string `json:"address" validate:"required" swaggertype:"string" example:"77012345678"`
+	Code    string `json:"code" validate:"required,min=6,max=6"`
+}
+
+type AuthOauthProvidersResponse struct {
+	Providers []string `json:"providers" example:"google,yandex"`
+}
+
+type AuthOauthAccount struct {
+	Id               int64     `json:"id"`
+	Provider         string    `json:"provider" example:"google"`
+	Provider_user_id string    `json:"provider_user_id"`
+	Create_date      time.Time `json:"create_date"`
+}
+
+type AuthOauthAccountsResponse struct {
+	Accounts []AuthOauthAccount `json:"accounts"`
+}
+
+type AuthOauthLinkResponse struct {
+	Url string `json:"url"`
+}
+
+type AuthTotpStatusResponse struct {
+	Enabled           bool      `json:"enabled"`
+	Enabled_at        null.Time `json:"enabled_at" swaggertype:"string"`
+	RecoveryCodesLeft int64     `json:"recovery_codes_left"`
+}
+
+type AuthTotpEnrollResponse struct {
+	Secret     string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	OtpauthURI string `json:"otpauth_uri" example:"otpauth://totp/service:test@mail.com?issuer=service&secret=JBSWY3DPEHPK3PXP"`
+	QrPng      string `json:"qr_png"` // base64 PNG
+}
+
+type AuthTotpCodeRequest struct {
+	// код из приложения, для отключения также подходит код восстановления
+	Code string `json:"code" validate:"required,min=6,max=16" example:"123456"`
+}
+
+type AuthTotpRecoveryCodesResponse struct {
+	// показываются один раз, в БД хранятся только хэши
+	RecoveryCodes []string `json:"recovery_codes" example:"a1b2c-3d4e5"`
+}
+
+type AuthMfaVerifyRequest struct {
+	MfaToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required,min=6,max=16" example:"123456"`
+}
+
+type AuthWebauthnRegisterCodeRequest struct {
+	Type string `json:"type" validate:"required,oneof=phone email" swaggertype:"string" example:"email"`
+}
+
+// AuthWebauthnRegisterOptionsRequest - подтверждение паролем или кодом из /auth/webauthn/register/code
+type AuthWebauthnRegisterOptionsRequest struct {
+	Password string `json:"password,omitempty"`
+	Type     string `json:"type,omitempty" validate:"omitempty,oneof=phone email" swaggertype:"string" example:"email"`
+	Code     string `json:"code,omitempty" validate:"omitempty,min=6,max=6"`
+}
+
+type AuthWebauthnLoginOptionsRequest struct {
+	// необязательно: без адреса - вход по passkey, пользователь определяется ключом
+	Phone_number null.String `json:"phone_number" validate:"omitempty,phoneKZ" swaggertype:"string" example:"77012345678"`
+	Email        null.String `json:"email" swaggertype:"string" example:"test@mail.com"`
+}
+
+type AuthWebauthnOptionsResponse struct {
+	ChallengeToken string `json:"challenge_token"`
+	// передается в navigator.credentials.create() / get()
+	Options any `json:"options" swaggertype:"object"`
+}
+
+type AuthWebauthnFinishRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	// ответ navigator.credentials.create() / get() в JSON
+	Credential json.RawMessage `json:"credential" validate:"required" swaggertype:"object"`
+	Name       string          `json:"name" validate:"max=64" example:"YubiKey"` // только для регистрации
+}
+
+type AuthWebauthnCredential struct {
+	Id           int64     `json:"id"`
+	Name         string    `json:"name" example:"YubiKey"`
+	Transports   []string  `json:"transports" example:"usb,nfc"`
+	Last_used_at null.Time `json:"last_used_at" swaggertype:"string"`
+	Create_date  time.Time `json:"create_date"`
+}
+
+type AuthWebauthnCredentialsResponse struct {
+	Credentials []AuthWebauthnCredential `json:"credentials"`
+}
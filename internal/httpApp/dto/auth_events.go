@@ -0,0 +1,43 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/guregu/null/v6"
+)
+
+type AuthEventsQueryParams struct {
+	Page  int `query:"page" validate:"omitempty,gte=1" example:"1"`
+	Limit int `query:"limit" validate:"omitempty,gte=1,lte=100" example:"20"`
+}
+
+type AdminAuthEventsQueryParams struct {
+	Page     int    `query:"page" validate:"omitempty,gte=1" example:"1"`
+	Limit    int    `query:"limit" validate:"omitempty,gte=1,lte=100" example:"20"`
+	UserId   int64  `query:"user_id" validate:"omitempty,gte=1" example:"1"`
+	Type     string `query:"type" validate:"omitempty,oneof=login refresh logout logout_all session_revoke password_change password_reset verify" example:"login"`
+	Outcome  string `query:"outcome" validate:"omitempty,oneof=success failure" example:"failure"`
+	Ip       string `query:"ip" validate:"omitempty,ip" example:"127.0.0.1"`
+	Identity string `query:"identity" validate:"omitempty" example:"user@example.com"`
+	From     string `query:"from" validate:"omitempty,datetime=2006-01-02" example:"2025-01-01"`
+	To       string `query:"to" validate:"omitempty,datetime=2006-01-02" example:"2025-12-31"`
+}
+
+type AuthEvent struct {
+	Id          int64       `json:"id"`
+	User_id     null.Int    `json:"user_id" swaggertype:"integer"`
+	Event_type  string      `json:"event_type"`
+	Outcome     string      `json:"outcome"`
+	Reason      null.String `json:"reason" swaggertype:"string"`
+	Identity    null.String `json:"identity" swaggertype:"string"`
+	Ip          null.String `json:"ip" swaggertype:"string"`
+	User_agent  null.String `json:"user_agent" swaggertype:"string"`
+	Create_date time.Time   `json:"create_date"`
+}
+
+type AuthEventsResponse struct {
+	Events []AuthEvent `json:"events"`
+	Total  int64       `json:"total"`
+	Page   int         `json:"page"`
+	Limit  int         `json:"limit"`
+}
@@ -0,0 +1,13 @@
+package dto
+
+// CleanupReport - итог запуска cmd/clear_db, в dry run - что было бы сделано
+type CleanupReport struct {
+	DryRun           bool    `json:"dry_run"`
+	UnverifiedMode   string  `json:"unverified_mode"` // delete, archive или off
+	UnverifiedUsers  []int64 `json:"unverified_users"`
+	PurgedUsers      []int64 `json:"purged_users"` // истек льготный период удаления
+	SessionIndexes   int     `json:"session_indexes"`
+	StaleSessionRefs int64   `json:"stale_session_refs"` // ссылки индекса user_id:* на истекшие сессии
+	OrphanedIndexes  []int64 `json:"orphaned_indexes"`   // сессии пользователей, которых нет в users или они обезличены
+	Errors           int     `json:"errors"`
+}
@@ -0,0 +1,40 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/guregu/null/v6"
+)
+
+type DataExportStatusResponse struct {
+	Id          string    `json:"id"`
+	Status      string    `json:"status" example:"ready"` // pending, ready, failed
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	DownloadUrl string    `json:"download_url,omitempty"` // одноразовая подписанная ссылка, только для ready
+}
+
+// DataExport - содержимое export.json в архиве выгрузки
+type DataExport struct {
+	ExportedAt          time.Time                `json:"exported_at"`
+	User                DataExportUser           `json:"user"`
+	OauthAccounts       []AuthOauthAccount       `json:"oauth_accounts"`
+	WebauthnCredentials []AuthWebauthnCredential `json:"webauthn_credentials"`
+	Sessions            []AuthSession            `json:"sessions"`
+	AuthEvents          []AuthEvent              `json:"auth_events"`
+}
+
+type DataExportUser struct {
+	Id                    int64       `json:"id"`
+	Name                  string      `json:"name"`
+	Email                 null.String `json:"email" swaggertype:"string"`
+	Phone_number          null.String `json:"phone_number" swaggertype:"string"`
+	Role_name             string      `json:"role_name"`
+	Email_verified_at     null.Time   `json:"email_verified_at" swaggertype:"string"`
+	Phone_verified_at     null.Time   `json:"phone_verified_at" swaggertype:"string"`
+	Blocked_at            null.Time   `json:"blocked_at" swaggertype:"string"`
+	Totp_enabled_at       null.Time   `json:"totp_enabled_at" swaggertype:"string"`
+	Deletion_requested_at null.Time   `json:"deletion_requested_at" swaggertype:"string"`
+	Changed_date          time.Time   `json:"changed_date"`
+	Create_date           time.Time   `json:"create_date"`
+}
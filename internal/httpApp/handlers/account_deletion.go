@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"log/slog"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/gofiber/fiber/v3"
+)
+
+// @Summary      Send account deletion code to verified phone or email of current user (for accounts without password)
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body      dto.AuthDeleteAccountCodeRequest  true  "Request body"
+// @Success      200      {object}  dto.AuthSendVerifyResponse
+// @Failure      400      {string}  string  "bad request"
+// @Failure      401      {string}  string  "authentication failed"
+// @Failure      429      {string}  string  "otp already sent, wait before resend"
+// @Header       429  {integer}  Retry-After  "seconds until resend is allowed"
+// @Router       /me/deletion-code [post]
+func (h *AuthHandler) DeleteAccountCode(c fiber.Ctx) error {
+	op := "HttpHandlers.DeleteAccountCode"
+	log := h.log.With(slog.String("op", op))
+
+	err := lib.ValidateBody(c, &dto.AuthDeleteAccountCodeRequest{})
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(err.Error())
+	}
+
+	body := dto.AuthDeleteAccountCodeRequest{}
+	if err := c.Bind().Body(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "not correct data: " + err.Error(),
+		})
+	}
+
+	res, err := h.service.DeleteAccountCode(c, c.Locals("user_id").(int64), body, c.IP())
+	if err != nil {
+		log.Warn(err.Error())
+		return deleteAccountError(c, err)
+	}
+
+	return c.Status(200).JSON(res)
+}
+
+// @Summary      Delete own account, confirmed with password or code from /me/deletion-code; all sessions are revoked, login during grace period restores the account
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body      dto.AuthDeleteAccountRequest  true  "Request body"
+// @Success      200      {object}  dto.AuthDeleteAccountResponse
+// @Failure      400      {string}  string  "bad request"
+// @Failure      401      {string}  string  "authentication failed"
+// @Failure      410      {string}  string  "too many wrong codes, request a new code"
+// @Failure      429      {string}  string  "too many attempts"
+// @Header       429  {integer}  Retry-After  "seconds until password attempts are allowed"
+// @Router       /me [delete]
+func (h *AuthHandler) DeleteAccount(c fiber.Ctx) error {
+	op := "HttpHandlers.DeleteAccount"
+	log := h.log.With(slog.String("op", op))
+
+	err := lib.ValidateBody(c, &dto.AuthDeleteAccountRequest{})
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(err.Error())
+	}
+
+	body := dto.AuthDeleteAccountRequest{}
+	if err := c.Bind().Body(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "not correct data: " + err.Error(),
+		})
+	}
+
+	res, err := h.service.DeleteAccount(c, c.Locals("user_id").(int64), body)
+	if err != nil {
+		log.Warn(err.Error())
+		return deleteAccountError(c, err)
+	}
+
+	clearRefreshCookie(c)
+	return c.Status(200).JSON(res)
+}
+
+func deleteAccountError(c fiber.Ctx, err error) error {
+	if ok, errSend := sendRetryError(c, err); ok {
+		return errSend
+	}
+	switch err {
+	case errorsApp.ErrBadRequest.Error:
+		return c.Status(errorsApp.ErrBadRequest.Code).SendString(errorsApp.ErrBadRequest.Message)
+	case errorsApp.ErrAuthentication.Error:
+		return c.Status(errorsApp.ErrAuthentication.Code).SendString(errorsApp.ErrAuthentication.Message)
+	case errorsApp.ErrVerifyNotFound.Error:
+		return c.Status(errorsApp.ErrVerifyNotFound.Code).SendString(errorsApp.ErrVerifyNotFound.Message)
+	case errorsApp.ErrOtpAttemptsExceeded.Error:
+		return c.Status(errorsApp.ErrOtpAttemptsExceeded.Code).SendString(errorsApp.ErrOtpAttemptsExceeded.Message)
+	case errorsApp.ErrUserNotFound.Error:
+		return c.Status(errorsApp.ErrUserNotFound.Code).SendString(errorsApp.ErrUserNotFound.Message)
+	}
+	return c.Status(500).SendString(errorsApp.ErrInternalError.Message)
+}
@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/gofiber/fiber/v3"
+)
+
+type adminUserService interface {
+	ListUsers(ctx context.Context, params dto.AdminUsersQueryParams) (dto.AdminUsersResponse, error)
+	UpdateRole(ctx context.Context, adminId int64, userId int64, roleId int64) error
+	Sessions(ctx context.Context, userId int64) (dto.AuthSessionResponse, error)
+	Logout(ctx context.Context, adminId int64, userId int64) error
+	SetBlocked(ctx context.Context, adminId int64, userId int64, blocked bool) error
+	DeleteUser(ctx context.Context, adminId int64, userId int64) error
+}
+
+type AdminUserHandler struct {
+	log     *slog.Logger
+	service adminUserService
+}
+
+func NewAdminUserHandler(log *slog.Logger, service adminUserService) *AdminUserHandler {
+	return &AdminUserHandler{
+		log:     log,
+		service: service,
+	}
+}
+
+// @Summary      List users with filters and pagination
+// @Tags         Admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        page          query     int     false  "Page, default 1"
+// @Param        limit         query     int     false  "Limit, default 20, max 100"
+// @Param        verified      query     bool    false  "Email or phone verified"
+// @Param        role          query     string  false  "Role name"
+// @Param        created_from  query     string  false  "Created from date (2006-01-02)"
+// @Param        created_to    query     string  false  "Created to date inclusive (2006-01-02)"
+// @Success      200      {object}  dto.AdminUsersResponse
+// @Failure      400      {string}  string  "bad request"
+// @Failure      401      {string}  string  "authentication failed"
+// @Failure      403      {string}  string  "forbidden"
+// @Router       /admin/users [get]
+func (h *AdminUserHandler) ListUsers(c fiber.Ctx) error {
+	op := "HttpHandlers.AdminListUsers"
+	log := h.log.With(slog.String("op", op))
+
+	err := lib.ValidateQueryParams(c, &dto.AdminUsersQueryParams{})
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(err.Error())
+	}
+
+	params := dto.AdminUsersQueryParams{}
+	if err := c.Bind().Query(&params); err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(errorsApp.ErrBadRequest.Message)
+	}
+
+	res, err := h.service.ListUsers(c, params)
+	if err != nil {
+		log.Warn(err.Error())
+		return adminUserError(c, err)
+	}
+	return c.Status(200).JSON(res)
+}
+
+// @Summary      Get sessions of user
+// @Tags         Admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path      int  true  "User id"
+// @Success      200      {object}  dto.AuthSessionResponse
+// @Failure      401      {string}  string  "authentication failed"
+// @Failure      403      {string}  string  "forbidden"
+// @Failure      404      {string}  string  "user not found"
+// @Router       /admin/users/{id}/sessions [get]
+func (h *AdminUserHandler) Sessions(c fiber.Ctx) error {
+	op := "HttpHandlers.AdminSessions"
+	log := h.log.With(slog.String("op", op))
+
+	userId, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(errorsApp.ErrBadRequest.Message)
+	}
+
+	res, err := h.service.Sessions(c, userId)
+	if err != nil {
+		log.Warn(err.Error())
+		return adminUserError(c, err)
+	}
+	return c.Status(200).JSON(res)
+}
+
+// @Summary      Change user role, all user sessions are revoked
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path      int                         true  "User id"
+// @Param        request  body      dto.AdminUpdateRoleRequest  true  "Request body"
+// @Success      200      string  "ok"
+// @Failure      400      {string}  string  "bad request"
+// @Failure      401      {string}  string  "authentication failed"
+// @Failure      403      {string}  string  "forbidden"
+// @Failure      404      {string}  string  "user not found"
+// @Router       /admin/users/{id}/role [patch]
+func (h *AdminUserHandler) UpdateRole(c fiber.Ctx) error {
+	op := "HttpHandlers.AdminUpdateRole"
+	log := h.log.With(slog.String("op", op))
+
+	userId, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(errorsApp.ErrBadRequest.Message)
+	}
+
+	err = lib.ValidateBody(c, &dto.AdminUpdateRoleRequest{})
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(err.Error())
+	}
+
+	body := dto.AdminUpdateRoleRequest{}
+	if err := c.Bind().Body(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "not correct data: " + err.Error(),
+		})
+	}
+
+	err = h.service.UpdateRole(c, c.Locals("user_id").(int64), userId, body.Role_id)
+	if err != nil {
+		log.Warn(err.Error())
+		return adminUserError(c, err)
+	}
+	return c.Status(200).SendString("ok")
+}
+
+// @Summary      Force logout, revokes all user sessions
+// @Tags         Admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path      int  true  "User id"
+// @Success      200      string  "ok"
+// @Failure      401      {string}  string  "authentication failed"
+// @Failure      403      {string}  string  "forbidden"
+// @Failure      404      {string}  string  "user not found"
+// @Router       /admin/users/{id}/logout [post]
+func (h *AdminUserHandler) Logout(c fiber.Ctx) error {
+	op := "HttpHandlers.AdminLogout"
+	log := h.log.With(slog.String("op", op))
+
+	userId, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(errorsApp.ErrBadRequest.Message)
+	}
+
+	err = h.service.Logout(c, c.Locals("user_id").(int64), userId)
+	if err != nil {
+		log.Warn(err.Error())
+		return adminUserError(c, err)
+	}
+	return c.Status(200).SendString("ok")
+}
+
+// @Summary      Block user, all user sessions are revoked
+// @Tags         Admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path      int  true  "User id"
+// @Success      200      string  "ok"
+// @Failure      401      {string}  string  "authentication failed"
+// @Failure      403      {string}  string  "forbidden"
+// @Failure      404      {string}  string  "user not found"
+// @Router       /admin/users/{id}/block [post]
+func (h *AdminUserHandler) Block(c fiber.Ctx) error {
+	return h.setBlocked(c, true)
+}
+
+// @Summary      Unblock user
+// @Tags         Admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path      int  true  "User id"
+// @Success      200      string  "ok"
+// @Failure      401      {string}  string  "authentication failed"
+// @Failure      403      {string}  string  "forbidden"
+// @Failure      404      {string}  string  "user not found"
+// @Router       /admin/users/{id}/unblock [post]
+func (h *AdminUserHandler) Unblock(c fiber.Ctx) error {
+	return h.setBlocked(c, false)
+}
+
+func (h *AdminUserHandler) setBlocked(c fiber.Ctx, blocked bool) error {
+	op := "HttpHandlers.AdminSetBlocked"
+	log := h.log.With(slog.String("op", op))
+
+	userId, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(errorsApp.ErrBadRequest.Message)
+	}
+
+	err = h.service.SetBlocked(c, c.Locals("user_id").(int64), userId, blocked)
+	if err != nil {
+		log.Warn(err.Error())
+		return adminUserError(c, err)
+	}
+	return c.Status(200).SendString("ok")
+}
+
+// @Summary      Delete user with linked oauth accounts, all user sessions are revoked
+// @Tags         Admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path      int  true  "User id"
+// @Success      200      string  "ok"
+// @Failure      401      {string}  string  "authentication failed"
+// @Failure      403      {string}  string  "forbidden"
+// @Failure      404      {string}  string  "user not found"
+// @Router       /admin/users/{id} [delete]
+func (h *AdminUserHandler) DeleteUser(c fiber.Ctx) error {
+	op := "HttpHandlers.AdminDeleteUser"
+	log := h.log.With(slog.String("op", op))
+
+	userId, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(errorsApp.ErrBadRequest.Message)
+	}
+
+	err = h.service.DeleteUser(c, c.Locals("user_id").(int64), userId)
+	if err != nil {
+		log.Warn(err.Error())
+		return adminUserError(c, err)
+	}
+	return c.Status(200).SendString("ok")
+}
+
+func adminUserError(c fiber.Ctx, err error) error {
+	switch err {
+	case errorsApp.ErrBadRequest.Error:
+		return c.Status(errorsApp.ErrBadRequest.Code).SendString(errorsApp.ErrBadRequest.Message)
+	case errorsApp.ErrForbidden.Error:
+		return c.Status(errorsApp.ErrForbidden.Code).SendString(errorsApp.ErrForbidden.Message)
+	case errorsApp.ErrUserNotFound.Error:
+		return c.Status(errorsApp.ErrUserNotFound.Code).SendString(errorsApp.ErrUserNotFound.Message)
+	}
+	return c.Status(500).SendString(errorsApp.ErrInternalError.Message)
+}
@@ -14,15 +14,49 @@ import (
 )
 
 type authService interface {
-	Register(context.Context, dto.AuthRegisterRequest) (dto.AuthRegisterResponse, error)
+	Register(context.Context, dto.AuthRegisterRequest, string) (dto.AuthRegisterResponse, error)
 	Login(context.Context, dto.AuthLoginRequest, string, string) (dto.AuthLoginResponse, error)
 	Hello(context.Context, string) (dto.AuthHelloResponse, error)
-	Refresh(context.Context, string) (dto.AuthLoginResponse, error)
+	Refresh(context.Context, string, string, string) (dto.AuthLoginResponse, error)
 	Sessions(context.Context, int64) (dto.AuthSessionResponse, error)
 	RevokeSession(fiber.Ctx, string) error
-	SendVerify(context.Context, dto.AuthSendVerifyRequest) (dto.AuthSendVerifyResponse, error)
-	ConfirmVerify(context.Context, dto.AuthConfirmVerifyRequest) error
-	UpdatePassword(context.Context, int64, string, string) error
+	Logout(context.Context, int64, string, string, string) error
+	LogoutAll(context.Context, int64, string, bool, string, string) (dto.AuthLogoutAllResponse, error)
+	SendVerify(context.Context, dto.AuthSendVerifyRequest, string) (dto.AuthSendVerifyResponse, error)
+	ConfirmVerify(context.Context, dto.AuthConfirmVerifyRequest, string, string) error
+	UpdatePassword(context.Context, int64, string, string, string, string) error
+	ResetPassword(context.Context, dto.AuthResetPasswordRequest, string) (dto.AuthSendVerifyResponse, error)
+	ConfirmResetPassword(context.Context, dto.AuthConfirmResetPasswordRequest, string, string) error
+	ChangeContactCode(context.Context, int64, dto.AuthChangeContactCodeRequest, string) (dto.AuthSendVerifyResponse, error)
+	ChangeContact(context.Context, int64, dto.AuthChangeContactRequest, string) (dto.AuthSendVerifyResponse, error)
+	ConfirmChangeContact(context.Context, int64, dto.AuthConfirmChangeContactRequest) error
+	DeleteAccountCode(context.Context, int64, dto.AuthDeleteAccountCodeRequest, string) (dto.AuthSendVerifyResponse, error)
+	DeleteAccount(context.Context, int64, dto.AuthDeleteAccountRequest) (dto.AuthDeleteAccountResponse, error)
+	StartDataExport(context.Context, int64) (dto.DataExportStatusResponse, error)
+	DataExportStatus(context.Context, int64, string) (dto.DataExportStatusResponse, error)
+	DownloadDataExport(context.Context, string, string, string) ([]byte, error)
+	CheckRevokeLink(context.Context, string, string, string) error
+	RevokeAllByLink(context.Context, string, string, string, string, string) (dto.AuthLogoutAllResponse, error)
+	OtpStart(context.Context, dto.AuthOtpStartRequest, string) (dto.AuthSendVerifyResponse, error)
+	OtpComplete(context.Context, dto.AuthOtpCompleteRequest, string, string) (dto.AuthLoginResponse, error)
+	OauthProviders() dto.AuthOauthProvidersResponse
+	OauthAuthURL(context.Context, string) (string, string, error)
+	OauthCallback(context.Context, string, string, string, string, string, string) (dto.AuthLoginResponse, *dto.AuthOauthAccount, error)
+	OauthAccounts(context.Context, int64) (dto.AuthOauthAccountsResponse, error)
+	OauthLinkURL(context.Context, string, int64) (dto.AuthOauthLinkResponse, string, error)
+	UnlinkOauthAccount(context.Context, int64, int64) error
+	TotpStatus(context.Context, int64) (dto.AuthTotpStatusResponse, error)
+	TotpEnroll(context.Context, int64) (dto.AuthTotpEnrollResponse, error)
+	TotpConfirm(context.Context, int64, string) (dto.AuthTotpRecoveryCodesResponse, error)
+	TotpDisable(context.Context, int64, string) error
+	MfaVerify(context.Context, dto.AuthMfaVerifyRequest, string, string) (dto.AuthLoginResponse, error)
+	WebauthnRegisterCode(context.Context, int64, dto.AuthWebauthnRegisterCodeRequest, string) (dto.AuthSendVerifyResponse, error)
+	WebauthnRegisterOptions(context.Context, int64, dto.AuthWebauthnRegisterOptionsRequest) (dto.AuthWebauthnOptionsResponse, error)
+	WebauthnRegisterFinish(context.Context, int64, dto.AuthWebauthnFinishRequest, string, string) (dto.AuthWebauthnCredential, error)
+	WebauthnLoginOptions(context.Context, dto.AuthWebauthnLoginOptionsRequest) (dto.AuthWebauthnOptionsResponse, error)
+	WebauthnLoginFinish(context.Context, dto.AuthWebauthnFinishRequest, string, string) (dto.AuthLoginResponse, error)
+	WebauthnCredentials(context.Context, int64) (dto.AuthWebauthnCredentialsResponse, error)
+	DeleteWebauthnCredential(context.Context, int64, int64) error
 }
 
 type AuthHandler struct {
@@ -47,6 +81,7 @@ func NewAuthHandler(cfg *config.Config, log *slog.Logger, service authService) *
 // @Success      201      {object}  dto.AuthRegisterResponse
 // @Failure      409      {string}  string  "значение для поля уже существует (ограничение уникальности: users_XXXX_key)"
 // @Failure      400      {string}  string  "Key: 'AuthRegisterRequest.Password' Error:Field validation for 'Password' failed on the 'min' tag"
+// @Failure      429      {string}  string  "otp send limit exceeded"
 // @Router       /auth/register [post]
 func (h *AuthHandler) AuthRegister(c fiber.Ctx) error {
 	op := "HttpHandlers.AuthRegister"
@@ -66,9 +101,12 @@ func (h *AuthHandler) AuthRegister(c fiber.Ctx) error {
 		})
 	}
 
-	res, err := h.service.Register(c, body)
+	res, err := h.service.Register(c, body, c.IP())
 	if err != nil {
 		log.Warn(err.Error())
+		if ok, errSend := sendRetryError(c, err); ok {
+			return errSend
+		}
 		if err == errorsApp.ErrAlreadyOtp.Error {
 			return c.Status(400).SendString(errorsApp.ErrAlreadyOtp.Message)
 		}
@@ -78,7 +116,7 @@ func (h *AuthHandler) AuthRegister(c fiber.Ctx) error {
 	return c.Status(201).JSON(res)
 }
 
-// @Summary      Login as user, returns access and refresh tokens
+// @Summary      Login as user, returns access and refresh tokens (or mfa_token if 2FA is enabled)
 // @Tags         Auth
 // @Accept       json
 // @Produce      json
@@ -86,6 +124,9 @@ func (h *AuthHandler) AuthRegister(c fiber.Ctx) error {
 // @Header       200  {string}  Set-Cookie  "refresh_token cookie is set (HttpOnly)"
 // @Success      200      {object}  dto.AuthLoginResponse
 // @Failure      401      {string}  string  "authentication failed"
+// @Failure      403      {string}  string  "user is blocked"
+// @Failure      429      {string}  string  "too many login attempts, try later"
+// @Header       429  {integer}  Retry-After  "seconds until next attempt"
 // @Router       /auth/login [post]
 func (h *AuthHandler) AuthLogin(c fiber.Ctx) error {
 	op := "HttpHandlers.AuthLogin"
@@ -112,23 +153,25 @@ func (h *AuthHandler) AuthLogin(c fiber.Ctx) error {
 	res, err := h.service.Login(c, body, c.IP(), string(c.Get(fiber.HeaderUserAgent)))
 	if err != nil {
 		log.Warn(err.Error())
+		if ok, errSend := sendRetryError(c, err); ok {
+			return errSend
+		}
 		if err == errorsApp.ErrAuthentication.Error {
 			return c.Status(401).SendString(errorsApp.ErrAuthentication.Message)
 		}
 		if err == errorsApp.ErrVerifyNotFound.Error {
 			return c.Status(401).SendString(errorsApp.ErrVerifyNotFound.Message)
 		}
+		if err == errorsApp.ErrUserBlocked.Error {
+			return c.Status(errorsApp.ErrUserBlocked.Code).SendString(errorsApp.ErrUserBlocked.Message)
+		}
 		return c.Status(500).SendString(errorsApp.ErrInternalError.Message)
 	}
-	cookie := new(fiber.Cookie)
-	cookie.Name = "refresh_token"
-	cookie.Value = res.RefreshToken
-	cookie.HTTPOnly = true
-	cookie.Secure = true // true в prod (HTTPS)
-	cookie.SameSite = fiber.CookieSameSiteStrictMode
-	cookie.Path = "/auth/login"
-	cookie.MaxAge = h.cfg.AUTH_REFRESH_TOKEN_EXP_HOURS * 60 * 60
-	c.Cookie(cookie)
+	// включена 2FA - токенов еще нет, клиент продолжает через /auth/2fa/verify
+	if res.MfaRequired {
+		return c.Status(200).JSON(res)
+	}
+	setRefreshCookie(c, res.RefreshToken, h.cfg.AUTH_REFRESH_TOKEN_EXP_HOURS*60*60)
 
 	return c.Status(200).JSON(res)
 }
@@ -166,7 +209,8 @@ func (h *AuthHandler) AuthHello(c fiber.Ctx) error {
 // @Security BearerAuth
 // @Success      200      {object}  dto.AuthLoginResponse
 // @Header       200  {string}  Set-Cookie  "refresh_token cookie is set (HttpOnly)"
-// @Failure      401      {string}  string  "authentication failed"
+// @Failure      401      {string}  string  "authentication failed / refresh token reuse detected, sessions revoked"
+// @Failure      403      {string}  string  "user is blocked"
 // @Router       /auth/refresh [post]
 func (h *AuthHandler) AuthRefresh(c fiber.Ctx) error {
 	op := "HttpHandlers.AuthRefresh"
@@ -178,7 +222,7 @@ func (h *AuthHandler) AuthRefresh(c fiber.Ctx) error {
 		return c.Status(err.Code).SendString(err.Message)
 	}
 
-	res, err2 := h.service.Refresh(c, token)
+	res, err2 := h.service.Refresh(c, token, c.IP(), string(c.Get(fiber.HeaderUserAgent)))
 	if err2 != nil {
 		log.Warn(err2.Error())
 		if strings.Contains(err2.Error(), "internal error") {
@@ -187,17 +231,16 @@ func (h *AuthHandler) AuthRefresh(c fiber.Ctx) error {
 		if err2 == errorsApp.ErrSessionNotFound.Error {
 			return c.Status(401).SendString(errorsApp.ErrSessionNotFound.Message)
 		}
+		if err2 == errorsApp.ErrRefreshTokenReused.Error {
+			return c.Status(errorsApp.ErrRefreshTokenReused.Code).SendString(errorsApp.ErrRefreshTokenReused.Message)
+		}
+		if err2 == errorsApp.ErrUserBlocked.Error {
+			return c.Status(errorsApp.ErrUserBlocked.Code).SendString(errorsApp.ErrUserBlocked.Message)
+		}
 
 		return c.Status(401).SendString(errorsApp.ErrAuthentication.Message)
 	}
-	cookie := new(fiber.Cookie)
-	cookie.Name = "refresh_token"
-	cookie.Value = res.RefreshToken
-	cookie.HTTPOnly = true
-	cookie.SameSite = fiber.CookieSameSiteStrictMode
-	cookie.Path = "/auth/refresh"
-	cookie.MaxAge = h.cfg.AUTH_REFRESH_TOKEN_EXP_HOURS * 60 * 60
-	c.Cookie(cookie)
+	setRefreshCookie(c, res.RefreshToken, h.cfg.AUTH_REFRESH_TOKEN_EXP_HOURS*60*60)
 
 	return c.Status(200).JSON(res)
 }
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/gofiber/fiber/v3"
+)
+
+type authEventService interface {
+	UserEvents(ctx context.Context, userId int64, params dto.AuthEventsQueryParams) (dto.AuthEventsResponse, error)
+	ListEvents(ctx context.Context, params dto.AdminAuthEventsQueryParams) (dto.AuthEventsResponse, error)
+}
+
+type AuthEventHandler struct {
+	log     *slog.Logger
+	service authEventService
+}
+
+func NewAuthEventHandler(log *slog.Logger, service authEventService) *AuthEventHandler {
+	return &AuthEventHandler{
+		log:     log,
+		service: service,
+	}
+}
+
+// @Summary      Security events of current user (logins, refresh, logout, password changes)
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Param        page   query     int  false  "Page, default 1"
+// @Param        limit  query     int  false  "Limit, default 20, max 100"
+// @Success      200      {object}  dto.AuthEventsResponse
+// @Failure      400      {string}  string  "bad request"
+// @Failure      401      {string}  string  "authentication failed"
+// @Router       /me/security-events [get]
+func (h *AuthEventHandler) MyEvents(c fiber.Ctx) error {
+	op := "HttpHandlers.MyEvents"
+	log := h.log.With(slog.String("op", op))
+
+	err := lib.ValidateQueryParams(c, &dto.AuthEventsQueryParams{})
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(err.Error())
+	}
+
+	params := dto.AuthEventsQueryParams{}
+	if err := c.Bind().Query(&params); err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(errorsApp.ErrBadRequest.Message)
+	}
+
+	res, err := h.service.UserEvents(c, c.Locals("user_id").(int64), params)
+	if err != nil {
+		log.Warn(err.Error())
+		return authEventError(c, err)
+	}
+	return c.Status(200).JSON(res)
+}
+
+// @Summary      List auth events with filters and pagination
+// @Tags         Admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        page      query     int     false  "Page, default 1"
+// @Param        limit     query     int     false  "Limit, default 20, max 100"
+// @Param        user_id   query     int     false  "User id"
+// @Param        type      query     string  false  "Event type"  Enums(login, refresh, logout, logout_all, session_revoke, password_change, password_reset, verify)
+// @Param        outcome   query     string  false  "Outcome"  Enums(success, failure)
+// @Param        ip        query     string  false  "IP address"
+// @Param        identity  query     string  false  "Email or phone from request"
+// @Param        from      query     string  false  "From date (2006-01-02)"
+// @Param        to        query     string  false  "To date inclusive (2006-01-02)"
+// @Success      200      {object}  dto.AuthEventsResponse
+// @Failure      400      {string}  string  "bad request"
+// @Failure      401      {string}  string  "authentication failed"
+// @Failure      403      {string}  string  "forbidden"
+// @Router       /admin/auth-events [get]
+func (h *AuthEventHandler) AdminEvents(c fiber.Ctx) error {
+	op := "HttpHandlers.AdminEvents"
+	log := h.log.With(slog.String("op", op))
+
+	err := lib.ValidateQueryParams(c, &dto.AdminAuthEventsQueryParams{})
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(err.Error())
+	}
+
+	params := dto.AdminAuthEventsQueryParams{}
+	if err := c.Bind().Query(&params); err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(errorsApp.ErrBadRequest.Message)
+	}
+
+	res, err := h.service.ListEvents(c, params)
+	if err != nil {
+		log.Warn(err.Error())
+		return authEventError(c, err)
+	}
+	return c.Status(200).JSON(res)
+}
+
+func authEventError(c fiber.Ctx, err error) error {
+	switch err {
+	case errorsApp.ErrBadRequest.Error:
+		return c.Status(errorsApp.ErrBadRequest.Code).SendString(errorsApp.ErrBadRequest.Message)
+	}
+	return c.Status(500).SendString(errorsApp.ErrInternalError.Message)
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"log/slog"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/gofiber/fiber/v3"
+)
+
+// @Summary      Send code to verified phone or email of current user to confirm address change (for accounts without password)
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body      dto.AuthChangeContactCodeRequest  true  "Request body"
+// @Success      200      {object}  dto.AuthSendVerifyResponse
+// @Failure      400      {string}  string  "bad request"
+// @Failure      401      {string}  string  "authentication failed"
+// @Failure      429      {string}  string  "otp already sent, wait before resend"
+// @Header       429  {integer}  Retry-After  "seconds until resend is allowed"
+// @Router       /auth/change-contact/code [post]
+func (h *AuthHandler) ChangeContactCode(c fiber.Ctx) error {
+	op := "HttpHandlers.ChangeContactCode"
+	log := h.log.With(slog.String("op", op))
+
+	err := lib.ValidateBody(c, &dto.AuthChangeContactCodeRequest{})
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(err.Error())
+	}
+
+	body := dto.AuthChangeContactCodeRequest{}
+	if err := c.Bind().Body(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "not correct data: " + err.Error(),
+		})
+	}
+
+	res, err := h.service.ChangeContactCode(c, c.Locals("user_id").(int64), body, c.IP())
+	if err != nil {
+		log.Warn(err.Error())
+		return changeContactError(c, err)
+	}
+
+	return c.Status(200).JSON(res)
+}
+
+// @Summary      Start email or phone change confirmed with password or code from /auth/change-contact/code, sends 6-digit code to the new address (current address is kept until confirmed)
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body      dto.AuthChangeContactRequest  true  "Request body"
+// @Success      200      {object}  dto.AuthSendVerifyResponse
+// @Failure      400      {string}  string  "bad request"
+// @Failure      401      {string}  string  "authentication failed"
+// @Failure      409      {string}  string  "email or phone number already in use"
+// @Failure      410      {string}  string  "too many wrong codes, request a new code"
+// @Failure      429      {string}  string  "otp already sent, wait before resend"
+// @Header       429  {integer}  Retry-After  "seconds until resend is allowed"
+// @Router       /auth/change-contact [post]
+func (h *AuthHandler) ChangeContact(c fiber.Ctx) error {
+	op := "HttpHandlers.ChangeContact"
+	log := h.log.With(slog.String("op", op))
+
+	err := lib.ValidateBody(c, &dto.AuthChangeContactRequest{})
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(err.Error())
+	}
+
+	body := dto.AuthChangeContactRequest{}
+	if err := c.Bind().Body(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "not correct data: " + err.Error(),
+		})
+	}
+
+	res, err := h.service.ChangeContact(c, c.Locals("user_id").(int64), body, c.IP())
+	if err != nil {
+		log.Warn(err.Error())
+		return changeContactError(c, err)
+	}
+
+	return c.Status(200).JSON(res)
+}
+
+// @Summary      Confirm email or phone change with code from the new address, previous address is notified
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body      dto.AuthConfirmChangeContactRequest  true  "Request body"
+// @Success      200      string  "ok"
+// @Failure      400      {string}  string  "bad request"
+// @Failure      401      {string}  string  "authentication failed"
+// @Failure      409      {string}  string  "email or phone number already in use"
+// @Failure      410      {string}  string  "too many wrong codes, request a new code"
+// @Router       /auth/confirm-change-contact [post]
+func (h *AuthHandler) ConfirmChangeContact(c fiber.Ctx) error {
+	op := "HttpHandlers.ConfirmChangeContact"
+	log := h.log.With(slog.String("op", op))
+
+	err := lib.ValidateBody(c, &dto.AuthConfirmChangeContactRequest{})
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(err.Error())
+	}
+
+	body := dto.AuthConfirmChangeContactRequest{}
+	if err := c.Bind().Body(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "not correct data: " + err.Error(),
+		})
+	}
+
+	err = h.service.ConfirmChangeContact(c, c.Locals("user_id").(int64), body)
+	if err != nil {
+		log.Warn(err.Error())
+		return changeContactError(c, err)
+	}
+
+	return c.Status(200).SendString("ok")
+}
+
+func changeContactError(c fiber.Ctx, err error) error {
+	if ok, errSend := sendRetryError(c, err); ok {
+		return errSend
+	}
+	switch err {
+	case errorsApp.ErrBadRequest.Error:
+		return c.Status(errorsApp.ErrBadRequest.Code).SendString(errorsApp.ErrBadRequest.Message)
+	case errorsApp.ErrAuthentication.Error:
+		return c.Status(errorsApp.ErrAuthentication.Code).SendString(errorsApp.ErrAuthentication.Message)
+	case errorsApp.ErrVerifyNotFound.Error:
+		return c.Status(errorsApp.ErrVerifyNotFound.Code).SendString(errorsApp.ErrVerifyNotFound.Message)
+	case errorsApp.ErrOtpAttemptsExceeded.Error:
+		return c.Status(errorsApp.ErrOtpAttemptsExceeded.Code).SendString(errorsApp.ErrOtpAttemptsExceeded.Message)
+	case errorsApp.ErrUserNotFound.Error:
+		return c.Status(errorsApp.ErrUserNotFound.Code).SendString(errorsApp.ErrUserNotFound.Message)
+	case errorsApp.ErrContactTaken.Error:
+		return c.Status(errorsApp.ErrContactTaken.Code).SendString(errorsApp.ErrContactTaken.Message)
+	}
+	return c.Status(500).SendString(errorsApp.ErrInternalError.Message)
+}
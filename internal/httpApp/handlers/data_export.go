@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"log/slog"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/gofiber/fiber/v3"
+)
+
+// @Summary      Start export of all personal data of current user, archive is built in background; one export per DATA_EXPORT_TTL, pending or ready export is returned again
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      202      {object}  dto.DataExportStatusResponse
+// @Failure      401      {string}  string  "authentication failed"
+// @Failure      429      {string}  string  "data export was already requested, try later"
+// @Header       429  {integer}  Retry-After  "seconds until new export is allowed"
+// @Router       /me/export [post]
+func (h *AuthHandler) StartDataExport(c fiber.Ctx) error {
+	op := "HttpHandlers.StartDataExport"
+	log := h.log.With(slog.String("op", op))
+
+	res, err := h.service.StartDataExport(c, c.Locals("user_id").(int64))
+	if err != nil {
+		log.Warn(err.Error())
+		return dataExportError(c, err)
+	}
+	return c.Status(202).JSON(res)
+}
+
+// @Summary      Status of personal data export, when ready contains one-time signed download_url
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Export id"
+// @Success      200      {object}  dto.DataExportStatusResponse
+// @Failure      401      {string}  string  "authentication failed"
+// @Failure      404      {string}  string  "data export not found or link expired"
+// @Router       /me/export/{id} [get]
+func (h *AuthHandler) DataExportStatus(c fiber.Ctx) error {
+	op := "HttpHandlers.DataExportStatus"
+	log := h.log.With(slog.String("op", op))
+
+	res, err := h.service.DataExportStatus(c, c.Locals("user_id").(int64), c.Params("id"))
+	if err != nil {
+		log.Warn(err.Error())
+		return dataExportError(c, err)
+	}
+	return c.Status(200).JSON(res)
+}
+
+// @Summary      Download personal data export (zip with export.json) by one-time signed link
+// @Tags         Auth
+// @Produce      application/zip
+// @Param        id         path      string  true  "Export id"
+// @Param        expires    query     string  true  "Link expiration (unix time)"
+// @Param        signature  query     string  true  "Link signature"
+// @Success      200      {file}    file
+// @Failure      404      {string}  string  "data export not found or link expired"
+// @Router       /data-export/{id} [get]
+func (h *AuthHandler) DownloadDataExport(c fiber.Ctx) error {
+	op := "HttpHandlers.DownloadDataExport"
+	log := h.log.With(slog.String("op", op))
+
+	id := c.Params("id")
+	file, err := h.service.DownloadDataExport(c, id, c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		log.Warn(err.Error())
+		return dataExportError(c, err)
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="data-export-`+id+`.zip"`)
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(200).Send(file)
+}
+
+func dataExportError(c fiber.Ctx, err error) error {
+	if ok, errSend := sendRetryError(c, err); ok {
+		return errSend
+	}
+	switch err {
+	case errorsApp.ErrDataExportNotFound.Error:
+		return c.Status(errorsApp.ErrDataExportNotFound.Code).SendString(errorsApp.ErrDataExportNotFound.Message)
+	}
+	return c.Status(500).SendString(errorsApp.ErrInternalError.Message)
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"log/slog"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
+	"github.com/gofiber/fiber/v3"
+)
+
+type JwksHandler struct {
+	log  *slog.Logger
+	keys *lib.JWTKeySet
+}
+
+func NewJwksHandler(log *slog.Logger, keys *lib.JWTKeySet) *JwksHandler {
+	return &JwksHandler{
+		log:  log,
+		keys: keys,
+	}
+}
+
+// @Summary      Public keys for verifying access and refresh tokens (RFC 7517)
+// @Tags         Auth
+// @Produce      json
+// @Success      200      {object}  lib.JWKS
+// @Router       /.well-known/jwks.json [get]
+func (h *JwksHandler) GetJwks(c fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.Status(200).JSON(h.keys.JWKS())
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"log/slog"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/gofiber/fiber/v3"
+)
+
+// @Summary      Logout, revokes current session
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200      string  "ok"
+// @Failure      401      {string}  string  "session not found or expired"
+// @Router       /auth/logout [post]
+func (h *AuthHandler) Logout(c fiber.Ctx) error {
+	op := "HttpHandlers.Logout"
+	log := h.log.With(slog.String("op", op))
+
+	err := h.service.Logout(c, c.Locals("user_id").(int64), c.Locals("jti").(string), c.IP(), string(c.Get(fiber.HeaderUserAgent)))
+	if err != nil {
+		log.Warn(err.Error())
+		if err == errorsApp.ErrSessionNotFound.Error {
+			return c.Status(errorsApp.ErrSessionNotFound.Code).SendString(errorsApp.ErrSessionNotFound.Message)
+		}
+		if err == errorsApp.ErrForbidden.Error {
+			return c.Status(errorsApp.ErrForbidden.Code).SendString(errorsApp.ErrForbidden.Message)
+		}
+		return c.Status(500).SendString(errorsApp.ErrInternalError.Message)
+	}
+
+	clearRefreshCookie(c)
+	return c.Status(200).SendString("ok")
+}
+
+// @Summary      Logout everywhere, revokes all user sessions
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Param        keep_current  query     bool  false  "Keep current session"
+// @Success      200      {object}  dto.AuthLogoutAllResponse
+// @Failure      401      {string}  string  "authentication failed"
+// @Router       /auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(c fiber.Ctx) error {
+	op := "HttpHandlers.LogoutAll"
+	log := h.log.With(slog.String("op", op))
+
+	err := lib.ValidateQueryParams(c, &dto.AuthLogoutAllQueryParams{})
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(err.Error())
+	}
+
+	params := dto.AuthLogoutAllQueryParams{}
+	if err := c.Bind().Query(&params); err != nil {
+		return c.Status(400).SendString(errorsApp.ErrBadRequest.Message)
+	}
+
+	res, err := h.service.LogoutAll(c, c.Locals("user_id").(int64), c.Locals("jti").(string), params.KeepCurrent, c.IP(), string(c.Get(fiber.HeaderUserAgent)))
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(500).SendString(errorsApp.ErrInternalError.Message)
+	}
+
+	if !params.KeepCurrent {
+		clearRefreshCookie(c)
+	}
+	return c.Status(200).JSON(res)
+}
+
+// все маршруты смонтированы под /api, иначе браузер не пришлет cookie на refresh
+const refreshCookiePath = "/api/auth/refresh"
+
+func setRefreshCookie(c fiber.Ctx, token string, maxAge int) {
+	c.Cookie(&fiber.Cookie{
+		Name:     "refresh_token",
+		Value:    token,
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: fiber.CookieSameSiteStrictMode,
+		Path:     refreshCookiePath,
+		MaxAge:   maxAge,
+	})
+}
+
+func clearRefreshCookie(c fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
+		Name:     "refresh_token",
+		Value:    "",
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: fiber.CookieSameSiteStrictMode,
+		Path:     refreshCookiePath,
+		MaxAge:   -1,
+	})
+}
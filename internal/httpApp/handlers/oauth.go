@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"log/slog"
+	"strconv"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/gofiber/fiber/v3"
+)
+
+// oauthStateCookie привязывает state к браузеру, который начал вход или привязку.
+// SameSite=Lax - cookie должна прийти на callback при переходе с сайта провайдера
+const oauthStateCookie = "oauth_state"
+
+func (h *AuthHandler) setOauthStateCookie(c fiber.Ctx, value string) {
+	c.Cookie(&fiber.Cookie{
+		Name:     oauthStateCookie,
+		Value:    value,
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+		Path:     "/api/auth/oauth",
+		MaxAge:   h.cfg.OAUTH_STATE_TTL_MINUTES * 60,
+	})
+}
+
+func clearOauthStateCookie(c fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
+		Name:     oauthStateCookie,
+		Value:    "",
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+		Path:     "/api/auth/oauth",
+		MaxAge:   -1,
+	})
+}
+
+// @Summary      List configured oauth providers
+// @Tags         Auth
+// @Produce      json
+// @Success      200      {object}  dto.AuthOauthProvidersResponse
+// @Router       /auth/oauth/providers [get]
+func (h *AuthHandler) OauthProviders(c fiber.Ctx) error {
+	return c.Status(200).JSON(h.service.OauthProviders())
+}
+
+// @Summary      Redirect to provider consent page (authorization code + PKCE)
+// @Tags         Auth
+// @Param        provider  path      string  true  "Provider name"  example(google)
+// @Header       302  {string}  Set-Cookie  "oauth_state cookie binds the flow to this browser (HttpOnly)"
+// @Success      302
+// @Failure      404      {string}  string  "oauth provider not configured"
+// @Router       /auth/oauth/{provider}/start [get]
+func (h *AuthHandler) OauthStart(c fiber.Ctx) error {
+	op := "HttpHandlers.OauthStart"
+	log := h.log.With(slog.String("op", op))
+
+	url, browser, err := h.service.OauthAuthURL(c, c.Params("provider"))
+	if err != nil {
+		log.Warn(err.Error())
+		if err == errorsApp.ErrOauthNotConfigured.Error {
+			return c.Status(errorsApp.ErrOauthNotConfigured.Code).SendString(errorsApp.ErrOauthNotConfigured.Message)
+		}
+		return c.Status(500).SendString(errorsApp.ErrInternalError.Message)
+	}
+
+	h.setOauthStateCookie(c, browser)
+	return c.Redirect().To(url)
+}
+
+// @Summary      Provider callback, returns access and refresh tokens. If started via link - returns linked account
+// @Tags         Auth
+// @Produce      json
+// @Param        provider  path      string  true  "Provider name"  example(google)
+// @Param        code      query     string  true  "Authorization code"
+// @Param        state     query     string  true  "State"
+// @Header       200  {string}  Set-Cookie  "refresh_token cookie is set (HttpOnly)"
+// @Success      200      {object}  dto.AuthLoginResponse
+// @Failure      401      {string}  string  "oauth authentication failed"
+// @Failure      403      {string}  string  "user is blocked"
+// @Failure      404      {string}  string  "oauth provider not configured"
+// @Failure      409      {string}  string  "oauth account already linked to another user, or account with this email exists but email is not verified"
+// @Router       /auth/oauth/{provider}/callback [get]
+func (h *AuthHandler) OauthCallback(c fiber.Ctx) error {
+	op := "HttpHandlers.OauthCallback"
+	log := h.log.With(slog.String("op", op))
+
+	if errParam := c.Query("error"); errParam != "" {
+		log.Warn("provider returned error", slog.String("err", errParam))
+		return c.Status(errorsApp.ErrOauthFailed.Code).SendString(errorsApp.ErrOauthFailed.Message)
+	}
+
+	code := c.Query("code")
+	state := c.Query("state")
+	if code == "" || state == "" {
+		log.Warn("code or state is empty")
+		return c.Status(400).SendString(errorsApp.ErrBadRequest.Message)
+	}
+
+	browser := c.Cookies(oauthStateCookie)
+	clearOauthStateCookie(c)
+	res, linked, err := h.service.OauthCallback(c, c.Params("provider"), code, state, browser, c.IP(), string(c.Get(fiber.HeaderUserAgent)))
+	if err != nil {
+		log.Warn(err.Error())
+		if err == errorsApp.ErrOauthAlreadyLinked.Error {
+			return c.Status(errorsApp.ErrOauthAlreadyLinked.Code).SendString(errorsApp.ErrOauthAlreadyLinked.Message)
+		}
+		if err == errorsApp.ErrOauthEmailNotVerified.Error {
+			return c.Status(errorsApp.ErrOauthEmailNotVerified.Code).SendString(errorsApp.ErrOauthEmailNotVerified.Message)
+		}
+		if err == errorsApp.ErrOauthFailed.Error {
+			return c.Status(errorsApp.ErrOauthFailed.Code).SendString(errorsApp.ErrOauthFailed.Message)
+		}
+		if err == errorsApp.ErrOauthNotConfigured.Error {
+			return c.Status(errorsApp.ErrOauthNotConfigured.Code).SendString(errorsApp.ErrOauthNotConfigured.Message)
+		}
+		if err == errorsApp.ErrUserBlocked.Error {
+			return c.Status(errorsApp.ErrUserBlocked.Code).SendString(errorsApp.ErrUserBlocked.Message)
+		}
+		return c.Status(500).SendString(errorsApp.ErrInternalError.Message)
+	}
+	if linked != nil {
+		return c.Status(200).JSON(linked)
+	}
+	if res.MfaRequired {
+		return c.Status(200).JSON(res)
+	}
+
+	setRefreshCookie(c, res.RefreshToken, h.cfg.AUTH_REFRESH_TOKEN_EXP_HOURS*60*60)
+
+	return c.Status(200).JSON(res)
+}
+
+// @Summary      List oauth accounts linked to current user
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200      {object}  dto.AuthOauthAccountsResponse
+// @Failure      401      {string}  string  "authentication failed"
+// @Router       /auth/oauth/accounts [get]
+func (h *AuthHandler) OauthAccounts(c fiber.Ctx) error {
+	op := "HttpHandlers.OauthAccounts"
+	log := h.log.With(slog.String("op", op))
+
+	userId := c.Locals("user_id").(int64)
+
+	res, err := h.service.OauthAccounts(c, userId)
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(500).SendString(errorsApp.ErrInternalError.Message)
+	}
+
+	return c.Status(200).JSON(res)
+}
+
+// @Summary      Start linking provider to current user, returns provider url for redirect
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Param        provider  path      string  true  "Provider name"  example(google)
+// @Header       200  {string}  Set-Cookie  "oauth_state cookie binds the flow to this browser (HttpOnly)"
+// @Success      200      {object}  dto.AuthOauthLinkResponse
+// @Failure      401      {string}  string  "authentication failed"
+// @Failure      404      {string}  string  "oauth provider not configured"
+// @Router       /auth/oauth/{provider}/link [post]
+func (h *AuthHandler) OauthLink(c fiber.Ctx) error {
+	op := "HttpHandlers.OauthLink"
+	log := h.log.With(slog.String("op", op))
+
+	userId := c.Locals("user_id").(int64)
+
+	res, browser, err := h.service.OauthLinkURL(c, c.Params("provider"), userId)
+	if err != nil {
+		log.Warn(err.Error())
+		if err == errorsApp.ErrOauthNotConfigured.Error {
+			return c.Status(errorsApp.ErrOauthNotConfigured.Code).SendString(errorsApp.ErrOauthNotConfigured.Message)
+		}
+		return c.Status(500).SendString(errorsApp.ErrInternalError.Message)
+	}
+
+	h.setOauthStateCookie(c, browser)
+	return c.Status(200).JSON(res)
+}
+
+// @Summary      Unlink oauth account from current user, refused for the last login method
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path      int  true  "Oauth account id"
+// @Success      200      string  "ok"
+// @Failure      401      {string}  string  "authentication failed"
+// @Failure      404      {string}  string  "oauth account not found"
+// @Failure      409      {string}  string  "cannot remove the last login method"
+// @Router       /auth/oauth/accounts/{id} [delete]
+func (h *AuthHandler) OauthUnlink(c fiber.Ctx) error {
+	op := "HttpHandlers.OauthUnlink"
+	log := h.log.With(slog.String("op", op))
+
+	accountId, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(errorsApp.ErrBadRequest.Message)
+	}
+	userId := c.Locals("user_id").(int64)
+
+	err = h.service.UnlinkOauthAccount(c, userId, accountId)
+	if err != nil {
+		log.Warn(err.Error())
+		if err == errorsApp.ErrOauthAccountNotFound.Error {
+			return c.Status(errorsApp.ErrOauthAccountNotFound.Code).SendString(errorsApp.ErrOauthAccountNotFound.Message)
+		}
+		if err == errorsApp.ErrLastLoginMethod.Error {
+			return c.Status(errorsApp.ErrLastLoginMethod.Code).SendString(errorsApp.ErrLastLoginMethod.Message)
+		}
+		if err == errorsApp.ErrUserNotFound.Error {
+			return c.Status(errorsApp.ErrUserNotFound.Code).SendString(errorsApp.ErrUserNotFound.Message)
+		}
+		return c.Status(500).SendString(errorsApp.ErrInternalError.Message)
+	}
+
+	return c.Status(200).SendString("ok")
+}
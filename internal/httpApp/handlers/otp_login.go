@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"log/slog"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/gofiber/fiber/v3"
+)
+
+// @Summary      Start passwordless login, sends 6-digit code to verified phone or email
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.AuthOtpStartRequest  true  "Request body"
+// @Success      200      {object}  dto.AuthSendVerifyResponse
+// @Failure      400      {string}  string  "bad request"
+// @Failure      429      {string}  string  "otp already sent, wait before resend"
+// @Header       429  {integer}  Retry-After  "seconds until resend is allowed"
+// @Router       /auth/otp/start [post]
+func (h *AuthHandler) OtpStart(c fiber.Ctx) error {
+	op := "HttpHandlers.OtpStart"
+	log := h.log.With(slog.String("op", op))
+
+	err := lib.ValidateBody(c, &dto.AuthOtpStartRequest{})
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(err.Error())
+	}
+
+	body := dto.AuthOtpStartRequest{}
+	if err := c.Bind().Body(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "not correct data: " + err.Error(),
+		})
+	}
+
+	res, err := h.service.OtpStart(c, body, c.IP())
+	if err != nil {
+		log.Warn(err.Error())
+		return otpLoginError(c, err)
+	}
+
+	return c.Status(200).JSON(res)
+}
+
+// @Summary      Complete passwordless login with code, returns access and refresh tokens (or mfa_token if 2FA is enabled)
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.AuthOtpCompleteRequest  true  "Request body"
+// @Header       200  {string}  Set-Cookie  "refresh_token cookie is set (HttpOnly)"
+// @Success      200      {object}  dto.AuthLoginResponse
+// @Failure      400      {string}  string  "bad request"
+// @Failure      401      {string}  string  "authentication failed"
+// @Failure      403      {string}  string  "user is blocked"
+// @Failure      410      {string}  string  "too many wrong codes, request a new code"
+// @Router       /auth/otp/complete [post]
+func (h *AuthHandler) OtpComplete(c fiber.Ctx) error {
+	op := "HttpHandlers.OtpComplete"
+	log := h.log.With(slog.String("op", op))
+
+	err := lib.ValidateBody(c, &dto.AuthOtpCompleteRequest{})
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(err.Error())
+	}
+
+	body := dto.AuthOtpCompleteRequest{}
+	if err := c.Bind().Body(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "not correct data: " + err.Error(),
+		})
+	}
+
+	res, err := h.service.OtpComplete(c, body, c.IP(), string(c.Get(fiber.HeaderUserAgent)))
+	if err != nil {
+		log.Warn(err.Error())
+		return otpLoginError(c, err)
+	}
+	if res.MfaRequired {
+		return c.Status(200).JSON(res)
+	}
+
+	setRefreshCookie(c, res.RefreshToken, h.cfg.AUTH_REFRESH_TOKEN_EXP_HOURS*60*60)
+	return c.Status(200).JSON(res)
+}
+
+func otpLoginError(c fiber.Ctx, err error) error {
+	if ok, errSend := sendRetryError(c, err); ok {
+		return errSend
+	}
+	switch err {
+	case errorsApp.ErrBadRequest.Error:
+		return c.Status(errorsApp.ErrBadRequest.Code).SendString(errorsApp.ErrBadRequest.Message)
+	case errorsApp.ErrAuthentication.Error:
+		return c.Status(errorsApp.ErrAuthentication.Code).SendString(errorsApp.ErrAuthentication.Message)
+	case errorsApp.ErrVerifyNotFound.Error:
+		return c.Status(errorsApp.ErrVerifyNotFound.Code).SendString(errorsApp.ErrVerifyNotFound.Message)
+	case errorsApp.ErrOtpAttemptsExceeded.Error:
+		return c.Status(errorsApp.ErrOtpAttemptsExceeded.Code).SendString(errorsApp.ErrOtpAttemptsExceeded.Message)
+	case errorsApp.ErrUserNotFound.Error:
+		return c.Status(errorsApp.ErrUserNotFound.Code).SendString(errorsApp.ErrUserNotFound.Message)
+	case errorsApp.ErrUserBlocked.Error:
+		return c.Status(errorsApp.ErrUserBlocked.Code).SendString(errorsApp.ErrUserBlocked.Message)
+	}
+	return c.Status(500).SendString(errorsApp.ErrInternalError.Message)
+}
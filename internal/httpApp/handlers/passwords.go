@@ -36,7 +36,7 @@ func (h *AuthHandler) UpdatePassword(c fiber.Ctx) error {
 		})
 	}
 
-	err2 := h.service.UpdatePassword(c, body.UserId, body.OldPassword, body.NewPassword)
+	err2 := h.service.UpdatePassword(c, body.UserId, body.OldPassword, body.NewPassword, c.IP(), string(c.Get(fiber.HeaderUserAgent)))
 	if err2 != nil {
 		if err2 == errorsApp.ErrAuthentication.Error {
 			return c.Status(401).SendString(err2.Error())
@@ -50,3 +50,99 @@ func (h *AuthHandler) UpdatePassword(c fiber.Ctx) error {
 
 	return c.Status(200).SendString("ok")
 }
+
+// @Summary      Send reset password code to user address
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.AuthResetPasswordRequest  true  "Request body"
+// @Success      200      {object}  dto.AuthSendVerifyResponse
+// @Failure      400      {string}  string  "bad request"
+// @Failure      429      {string}  string  "otp already sent, wait before resend"
+// @Header       429  {integer}  Retry-After  "seconds until resend is allowed"
+// @Router       /auth/reset-password [post]
+func (h *AuthHandler) ResetPassword(c fiber.Ctx) error {
+	op := "HttpHandlers.ResetPassword"
+	log := h.log.With(slog.String("op", op))
+
+	err := lib.ValidateBody(c, &dto.AuthResetPasswordRequest{})
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(err.Error())
+	}
+
+	body := dto.AuthResetPasswordRequest{}
+	if err := c.Bind().Body(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "not correct data: " + err.Error(),
+		})
+	}
+
+	res, err2 := h.service.ResetPassword(c, body, c.IP())
+	if err2 != nil {
+		log.Warn(err2.Error())
+		if ok, errSend := sendRetryError(c, err2); ok {
+			return errSend
+		}
+		if err2 == errorsApp.ErrUserNotFound.Error {
+			return c.Status(errorsApp.ErrUserNotFound.Code).SendString(errorsApp.ErrUserNotFound.Message)
+		}
+		if err2 == errorsApp.ErrInternalError.Error {
+			return c.Status(500).SendString(errorsApp.ErrInternalError.Message)
+		}
+		return c.Status(400).SendString(err2.Error())
+	}
+
+	return c.Status(200).JSON(res)
+}
+
+// @Summary      Confirm reset password code and set new password, all user sessions are revoked
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.AuthConfirmResetPasswordRequest  true  "Request body"
+// @Success      200      string  "ok"
+// @Failure      400      {string}  string  "bad request"
+// @Failure      401      {string}  string  "authentication failed"
+// @Failure      410      {string}  string  "too many wrong codes, request a new code"
+// @Router       /auth/confirm-reset-password [post]
+func (h *AuthHandler) ConfirmResetPassword(c fiber.Ctx) error {
+	op := "HttpHandlers.ConfirmResetPassword"
+	log := h.log.With(slog.String("op", op))
+
+	err := lib.ValidateBody(c, &dto.AuthConfirmResetPasswordRequest{})
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(err.Error())
+	}
+
+	body := dto.AuthConfirmResetPasswordRequest{}
+	if err := c.Bind().Body(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "not correct data: " + err.Error(),
+		})
+	}
+
+	err2 := h.service.ConfirmResetPassword(c, body, c.IP(), string(c.Get(fiber.HeaderUserAgent)))
+	if err2 != nil {
+		log.Warn(err2.Error())
+		if err2 == errorsApp.ErrAuthentication.Error {
+			return c.Status(401).SendString(errorsApp.ErrAuthentication.Message)
+		}
+		if err2 == errorsApp.ErrVerifyNotFound.Error {
+			return c.Status(errorsApp.ErrVerifyNotFound.Code).SendString(errorsApp.ErrVerifyNotFound.Message)
+		}
+		if err2 == errorsApp.ErrOtpAttemptsExceeded.Error {
+			return c.Status(errorsApp.ErrOtpAttemptsExceeded.Code).SendString(errorsApp.ErrOtpAttemptsExceeded.Message)
+		}
+		if err2 == errorsApp.ErrUserNotFound.Error {
+			return c.Status(errorsApp.ErrUserNotFound.Code).SendString(errorsApp.ErrUserNotFound.Message)
+		}
+		if err2 == errorsApp.ErrInternalError.Error {
+			return c.Status(500).SendString(errorsApp.ErrInternalError.Message)
+		}
+		return c.Status(400).SendString(err2.Error())
+	}
+
+	return c.Status(200).SendString("ok")
+}
@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/gofiber/fiber/v3"
+)
+
+// sendRetryError отвечает кодом RetryError с заголовком Retry-After,
+// ok = false если err не RetryError и ответ не отправлен
+func sendRetryError(c fiber.Ctx, err error) (bool, error) {
+	var retryErr *errorsApp.RetryError
+	if !errors.As(err, &retryErr) {
+		return false, nil
+	}
+	c.Set(fiber.HeaderRetryAfter, strconv.FormatInt(retryErr.Seconds(), 10))
+	return true, c.Status(retryErr.Err.Code).SendString(retryErr.Err.Message)
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"bytes"
+	"html/template"
+	"log/slog"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/gofiber/fiber/v3"
+)
+
+// страница подтверждения: сессии завершает только отправка формы (POST),
+// GET по ссылке из письма открывают и почтовые сканеры
+var revokeAllConfirmTemplate = template.Must(template.New("revoke_all_confirm").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>Sign out everywhere</title></head>
+<body>
+<p>Sign out of your account on all devices? Then change your password.</p>
+<form method="post" action="revoke-all">
+<input type="hidden" name="user_id" value="{{.UserId}}">
+<input type="hidden" name="expires" value="{{.Expires}}">
+<input type="hidden" name="signature" value="{{.Signature}}">
+<button type="submit">Sign out everywhere</button>
+</form>
+</body>
+</html>
+`))
+
+var revokeAllDoneTemplate = template.Must(template.New("revoke_all_done").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>Signed out everywhere</title></head>
+<body>
+<p>All sessions were signed out ({{.Revoked}}). Change your password now.</p>
+</body>
+</html>
+`))
+
+// @Summary      Confirmation page for signed "this wasn't me" link from security notification, sessions are revoked only by POST
+// @Tags         Auth
+// @Produce      html
+// @Param        user_id    query     string  true  "User id"
+// @Param        expires    query     string  true  "Link expiration (unix time)"
+// @Param        signature  query     string  true  "Link signature"
+// @Success      200      {string}  string  "confirmation page"
+// @Failure      400      {string}  string  "link is invalid or expired"
+// @Router       /security/revoke-all [get]
+func (h *AuthHandler) RevokeAllConfirm(c fiber.Ctx) error {
+	op := "HttpHandlers.RevokeAllConfirm"
+	log := h.log.With(slog.String("op", op))
+
+	link := fiber.Map{
+		"UserId":    c.Query("user_id"),
+		"Expires":   c.Query("expires"),
+		"Signature": c.Query("signature"),
+	}
+	err := h.service.CheckRevokeLink(c, c.Query("user_id"), c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		log.Warn(err.Error())
+		return revokeLinkError(c, err)
+	}
+
+	return sendHtml(c, log, revokeAllConfirmTemplate, link)
+}
+
+// @Summary      Revoke all sessions by signed "this wasn't me" link, submitted from the confirmation page
+// @Tags         Auth
+// @Accept       x-www-form-urlencoded
+// @Produce      html
+// @Param        user_id    formData  string  true  "User id"
+// @Param        expires    formData  string  true  "Link expiration (unix time)"
+// @Param        signature  formData  string  true  "Link signature"
+// @Success      200      {string}  string  "sessions revoked"
+// @Failure      400      {string}  string  "link is invalid or expired"
+// @Router       /security/revoke-all [post]
+func (h *AuthHandler) RevokeAllByLink(c fiber.Ctx) error {
+	op := "HttpHandlers.RevokeAllByLink"
+	log := h.log.With(slog.String("op", op))
+
+	res, err := h.service.RevokeAllByLink(c, c.FormValue("user_id"), c.FormValue("expires"), c.FormValue("signature"), c.IP(), string(c.Get(fiber.HeaderUserAgent)))
+	if err != nil {
+		log.Warn(err.Error())
+		return revokeLinkError(c, err)
+	}
+
+	return sendHtml(c, log, revokeAllDoneTemplate, res)
+}
+
+func sendHtml(c fiber.Ctx, log *slog.Logger, tmpl *template.Template, data any) error {
+	buf := bytes.Buffer{}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		log.Error("error execute template", slog.String("err", err.Error()))
+		return c.Status(500).SendString(errorsApp.ErrInternalError.Message)
+	}
+
+	// в ссылке подпись - не отдаем ее в кэш и в Referer
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set(fiber.HeaderReferrerPolicy, "no-referrer")
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Status(200).Send(buf.Bytes())
+}
+
+func revokeLinkError(c fiber.Ctx, err error) error {
+	switch err {
+	case errorsApp.ErrRevokeLinkInvalid.Error:
+		return c.Status(errorsApp.ErrRevokeLinkInvalid.Code).SendString(errorsApp.ErrRevokeLinkInvalid.Message)
+	}
+	return c.Status(500).SendString(errorsApp.ErrInternalError.Message)
+}
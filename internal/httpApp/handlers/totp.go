@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"log/slog"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/gofiber/fiber/v3"
+)
+
+// @Summary      Two-factor authentication status of current user
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200      {object}  dto.AuthTotpStatusResponse
+// @Failure      401      {string}  string  "authentication failed"
+// @Router       /auth/2fa [get]
+func (h *AuthHandler) TotpStatus(c fiber.Ctx) error {
+	op := "HttpHandlers.TotpStatus"
+	log := h.log.With(slog.String("op", op))
+
+	res, err := h.service.TotpStatus(c, c.Locals("user_id").(int64))
+	if err != nil {
+		log.Warn(err.Error())
+		return totpError(c, err)
+	}
+	return c.Status(200).JSON(res)
+}
+
+// @Summary      Start TOTP enrollment, returns secret, otpauth uri and QR code (base64 PNG)
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200      {object}  dto.AuthTotpEnrollResponse
+// @Failure      401      {string}  string  "authentication failed"
+// @Failure      409      {string}  string  "two-factor authentication already enabled"
+// @Router       /auth/2fa/enroll [post]
+func (h *AuthHandler) TotpEnroll(c fiber.Ctx) error {
+	op := "HttpHandlers.TotpEnroll"
+	log := h.log.With(slog.String("op", op))
+
+	res, err := h.service.TotpEnroll(c, c.Locals("user_id").(int64))
+	if err != nil {
+		log.Warn(err.Error())
+		return totpError(c, err)
+	}
+	return c.Status(200).JSON(res)
+}
+
+// @Summary      Confirm TOTP enrollment with code from app, enables 2FA and returns recovery codes
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body      dto.AuthTotpCodeRequest  true  "Request body"
+// @Success      200      {object}  dto.AuthTotpRecoveryCodesResponse
+// @Failure      401      {string}  string  "invalid two-factor code"
+// @Failure      409      {string}  string  "two-factor authentication already enabled"
+// @Failure      429      {string}  string  "too many login attempts, try later"
+// @Router       /auth/2fa/confirm [post]
+func (h *AuthHandler) TotpConfirm(c fiber.Ctx) error {
+	op := "HttpHandlers.TotpConfirm"
+	log := h.log.With(slog.String("op", op))
+
+	err := lib.ValidateBody(c, &dto.AuthTotpCodeRequest{})
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(err.Error())
+	}
+
+	body := dto.AuthTotpCodeRequest{}
+	if err := c.Bind().Body(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "not correct data: " + err.Error(),
+		})
+	}
+
+	res, err := h.service.TotpConfirm(c, c.Locals("user_id").(int64), body.Code)
+	if err != nil {
+		log.Warn(err.Error())
+		return totpError(c, err)
+	}
+	return c.Status(200).JSON(res)
+}
+
+// @Summary      Disable 2FA with code from app or recovery code
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body      dto.AuthTotpCodeRequest  true  "Request body"
+// @Success      200      string  "ok"
+// @Failure      401      {string}  string  "invalid two-factor code"
+// @Failure      409      {string}  string  "two-factor authentication is not enabled"
+// @Failure      429      {string}  string  "too many login attempts, try later"
+// @Router       /auth/2fa/disable [post]
+func (h *AuthHandler) TotpDisable(c fiber.Ctx) error {
+	op := "HttpHandlers.TotpDisable"
+	log := h.log.With(slog.String("op", op))
+
+	err := lib.ValidateBody(c, &dto.AuthTotpCodeRequest{})
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(err.Error())
+	}
+
+	body := dto.AuthTotpCodeRequest{}
+	if err := c.Bind().Body(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "not correct data: " + err.Error(),
+		})
+	}
+
+	err = h.service.TotpDisable(c, c.Locals("user_id").(int64), body.Code)
+	if err != nil {
+		log.Warn(err.Error())
+		return totpError(c, err)
+	}
+	return c.Status(200).SendString("ok")
+}
+
+// @Summary      Second login step: exchange mfa_token and TOTP or recovery code for access and refresh tokens
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.AuthMfaVerifyRequest  true  "Request body"
+// @Header       200  {string}  Set-Cookie  "refresh_token cookie is set (HttpOnly)"
+// @Success      200      {object}  dto.AuthLoginResponse
+// @Failure      401      {string}  string  "invalid two-factor code / mfa challenge expired or not found"
+// @Failure      403      {string}  string  "user is blocked"
+// @Failure      429      {string}  string  "too many login attempts, try later"
+// @Header       429  {integer}  Retry-After  "seconds until next attempt"
+// @Router       /auth/2fa/verify [post]
+func (h *AuthHandler) MfaVerify(c fiber.Ctx) error {
+	op := "HttpHandlers.MfaVerify"
+	log := h.log.With(slog.String("op", op))
+
+	err := lib.ValidateBody(c, &dto.AuthMfaVerifyRequest{})
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(err.Error())
+	}
+
+	body := dto.AuthMfaVerifyRequest{}
+	if err := c.Bind().Body(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "not correct data: " + err.Error(),
+		})
+	}
+
+	res, err := h.service.MfaVerify(c, body, c.IP(), string(c.Get(fiber.HeaderUserAgent)))
+	if err != nil {
+		log.Warn(err.Error())
+		return totpError(c, err)
+	}
+
+	setRefreshCookie(c, res.RefreshToken, h.cfg.AUTH_REFRESH_TOKEN_EXP_HOURS*60*60)
+	return c.Status(200).JSON(res)
+}
+
+func totpError(c fiber.Ctx, err error) error {
+	if ok, errSend := sendRetryError(c, err); ok {
+		return errSend
+	}
+	switch err {
+	case errorsApp.ErrTotpAlreadyEnabled.Error:
+		return c.Status(errorsApp.ErrTotpAlreadyEnabled.Code).SendString(errorsApp.ErrTotpAlreadyEnabled.Message)
+	case errorsApp.ErrTotpNotEnabled.Error:
+		return c.Status(errorsApp.ErrTotpNotEnabled.Code).SendString(errorsApp.ErrTotpNotEnabled.Message)
+	case errorsApp.ErrTotpInvalidCode.Error:
+		return c.Status(errorsApp.ErrTotpInvalidCode.Code).SendString(errorsApp.ErrTotpInvalidCode.Message)
+	case errorsApp.ErrMfaChallengeNotFound.Error:
+		return c.Status(errorsApp.ErrMfaChallengeNotFound.Code).SendString(errorsApp.ErrMfaChallengeNotFound.Message)
+	case errorsApp.ErrUserBlocked.Error:
+		return c.Status(errorsApp.ErrUserBlocked.Code).SendString(errorsApp.ErrUserBlocked.Message)
+	}
+	return c.Status(500).SendString(errorsApp.ErrInternalError.Message)
+}
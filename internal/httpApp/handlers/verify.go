@@ -5,6 +5,7 @@ import (
 
 	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
 	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
 	"github.com/gofiber/fiber/v3"
 )
 
@@ -15,6 +16,8 @@ import (
 // @Param        request  body      dto.AuthSendVerifyRequest  true  "Request body"
 // @Success      200      {object}  dto.AuthSendVerifyResponse
 // @Failure      401      {string}  string  "authentication failed"
+// @Failure      429      {string}  string  "otp already sent, wait before resend"
+// @Header       429  {integer}  Retry-After  "seconds until resend is allowed"
 // @Router       /auth/send-verify [post]
 func (h *AuthHandler) SendVerify(c fiber.Ctx) error {
 	op := "HttpHandlers.SendVerify"
@@ -33,9 +36,12 @@ func (h *AuthHandler) SendVerify(c fiber.Ctx) error {
 		})
 	}
 
-	responseOtp, err2 := h.service.SendVerify(c, body)
+	responseOtp, err2 := h.service.SendVerify(c, body, c.IP())
 	if err2 != nil {
 		log.Warn(err2.Error())
+		if ok, errSend := sendRetryError(c, err2); ok {
+			return errSend
+		}
 		return c.Status(400).SendString(err2.Error())
 	}
 
@@ -49,6 +55,7 @@ func (h *AuthHandler) SendVerify(c fiber.Ctx) error {
 // @Param        request  body      dto.AuthConfirmVerifyRequest  true  "Request body"
 // @Success      200      string  "ok"
 // @Failure      400      {string}  string  "bad request"
+// @Failure      410      {string}  string  "too many wrong codes, request a new code"
 // @Router       /auth/confirm-verify [post]
 func (h *AuthHandler) ConfirmVerify(c fiber.Ctx) error {
 	op := "HttpHandlers.ConfirmVerify"
@@ -67,9 +74,12 @@ func (h *AuthHandler) ConfirmVerify(c fiber.Ctx) error {
 		})
 	}
 
-	err2 := h.service.ConfirmVerify(c, body)
+	err2 := h.service.ConfirmVerify(c, body, c.IP(), string(c.Get(fiber.HeaderUserAgent)))
 	if err2 != nil {
 		log.Warn(err2.Error())
+		if err2 == errorsApp.ErrOtpAttemptsExceeded.Error {
+			return c.Status(errorsApp.ErrOtpAttemptsExceeded.Code).SendString(errorsApp.ErrOtpAttemptsExceeded.Message)
+		}
 		return c.Status(400).SendString(err2.Error())
 	}
 
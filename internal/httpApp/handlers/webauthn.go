@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"log/slog"
+	"strconv"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/gofiber/fiber/v3"
+)
+
+// @Summary      Send code confirming a new WebAuthn key to verified phone or email of current user (for accounts without password)
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body      dto.AuthWebauthnRegisterCodeRequest  true  "Request body"
+// @Success      200      {object}  dto.AuthSendVerifyResponse
+// @Failure      400      {string}  string  "bad request"
+// @Failure      401      {string}  string  "authentication failed"
+// @Failure      404      {string}  string  "webauthn not configured"
+// @Failure      429      {string}  string  "otp already sent, wait before resend"
+// @Header       429  {integer}  Retry-After  "seconds until resend is allowed"
+// @Router       /auth/webauthn/register/code [post]
+func (h *AuthHandler) WebauthnRegisterCode(c fiber.Ctx) error {
+	op := "HttpHandlers.WebauthnRegisterCode"
+	log := h.log.With(slog.String("op", op))
+
+	err := lib.ValidateBody(c, &dto.AuthWebauthnRegisterCodeRequest{})
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(err.Error())
+	}
+
+	body := dto.AuthWebauthnRegisterCodeRequest{}
+	if err := c.Bind().Body(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "not correct data: " + err.Error(),
+		})
+	}
+
+	res, err := h.service.WebauthnRegisterCode(c, c.Locals("user_id").(int64), body, c.IP())
+	if err != nil {
+		log.Warn(err.Error())
+		return webauthnError(c, err)
+	}
+	return c.Status(200).JSON(res)
+}
+
+// @Summary      Start WebAuthn registration of a new key for current user, confirmed with password or code from /auth/webauthn/register/code
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body      dto.AuthWebauthnRegisterOptionsRequest  true  "Request body"
+// @Success      200      {object}  dto.AuthWebauthnOptionsResponse
+// @Failure      400      {string}  string  "bad request"
+// @Failure      401      {string}  string  "authentication failed"
+// @Failure      404      {string}  string  "webauthn not configured"
+// @Failure      410      {string}  string  "too many wrong codes, request a new code"
+// @Failure      429      {string}  string  "too many attempts"
+// @Router       /auth/webauthn/register/options [post]
+func (h *AuthHandler) WebauthnRegisterOptions(c fiber.Ctx) error {
+	op := "HttpHandlers.WebauthnRegisterOptions"
+	log := h.log.With(slog.String("op", op))
+
+	err := lib.ValidateBody(c, &dto.AuthWebauthnRegisterOptionsRequest{})
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(err.Error())
+	}
+
+	body := dto.AuthWebauthnRegisterOptionsRequest{}
+	if err := c.Bind().Body(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "not correct data: " + err.Error(),
+		})
+	}
+
+	res, err := h.service.WebauthnRegisterOptions(c, c.Locals("user_id").(int64), body)
+	if err != nil {
+		log.Warn(err.Error())
+		return webauthnError(c, err)
+	}
+	return c.Status(200).JSON(res)
+}
+
+// @Summary      Finish WebAuthn registration, stores the key
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body      dto.AuthWebauthnFinishRequest  true  "Request body"
+// @Success      201      {object}  dto.AuthWebauthnCredential
+// @Failure      401      {string}  string  "webauthn verification failed"
+// @Failure      404      {string}  string  "webauthn not configured"
+// @Router       /auth/webauthn/register/finish [post]
+func (h *AuthHandler) WebauthnRegisterFinish(c fiber.Ctx) error {
+	op := "HttpHandlers.WebauthnRegisterFinish"
+	log := h.log.With(slog.String("op", op))
+
+	body, err := bindWebauthnFinish(c)
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(err.Error())
+	}
+
+	res, err := h.service.WebauthnRegisterFinish(c, c.Locals("user_id").(int64), body, c.IP(), string(c.Get(fiber.HeaderUserAgent)))
+	if err != nil {
+		log.Warn(err.Error())
+		return webauthnError(c, err)
+	}
+	return c.Status(201).JSON(res)
+}
+
+// @Summary      Start WebAuthn login. Without email/phone - passkey login, user is resolved by the key
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.AuthWebauthnLoginOptionsRequest  false  "Request body"
+// @Success      200      {object}  dto.AuthWebauthnOptionsResponse
+// @Failure      404      {string}  string  "webauthn not configured"
+// @Router       /auth/webauthn/login/options [post]
+func (h *AuthHandler) WebauthnLoginOptions(c fiber.Ctx) error {
+	op := "HttpHandlers.WebauthnLoginOptions"
+	log := h.log.With(slog.String("op", op))
+
+	body := dto.AuthWebauthnLoginOptionsRequest{}
+	if len(c.Body()) > 0 {
+		err := lib.ValidateBody(c, &dto.AuthWebauthnLoginOptionsRequest{})
+		if err != nil {
+			log.Warn(err.Error())
+			return c.Status(400).SendString(err.Error())
+		}
+		if err := c.Bind().Body(&body); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "not correct data: " + err.Error(),
+			})
+		}
+	}
+
+	res, err := h.service.WebauthnLoginOptions(c, body)
+	if err != nil {
+		log.Warn(err.Error())
+		return webauthnError(c, err)
+	}
+	return c.Status(200).JSON(res)
+}
+
+// @Summary      Finish WebAuthn login, returns access and refresh tokens (or mfa_token if 2FA is enabled)
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.AuthWebauthnFinishRequest  true  "Request body"
+// @Header       200  {string}  Set-Cookie  "refresh_token cookie is set (HttpOnly)"
+// @Success      200      {object}  dto.AuthLoginResponse
+// @Failure      401      {string}  string  "webauthn verification failed"
+// @Failure      403      {string}  string  "user is blocked"
+// @Router       /auth/webauthn/login/finish [post]
+func (h *AuthHandler) WebauthnLoginFinish(c fiber.Ctx) error {
+	op := "HttpHandlers.WebauthnLoginFinish"
+	log := h.log.With(slog.String("op", op))
+
+	body, err := bindWebauthnFinish(c)
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(err.Error())
+	}
+
+	res, err := h.service.WebauthnLoginFinish(c, body, c.IP(), string(c.Get(fiber.HeaderUserAgent)))
+	if err != nil {
+		log.Warn(err.Error())
+		return webauthnError(c, err)
+	}
+	if res.MfaRequired {
+		return c.Status(200).JSON(res)
+	}
+
+	setRefreshCookie(c, res.RefreshToken, h.cfg.AUTH_REFRESH_TOKEN_EXP_HOURS*60*60)
+	return c.Status(200).JSON(res)
+}
+
+// @Summary      List WebAuthn keys of current user
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200      {object}  dto.AuthWebauthnCredentialsResponse
+// @Failure      401      {string}  string  "authentication failed"
+// @Router       /auth/webauthn/credentials [get]
+func (h *AuthHandler) WebauthnCredentials(c fiber.Ctx) error {
+	op := "HttpHandlers.WebauthnCredentials"
+	log := h.log.With(slog.String("op", op))
+
+	res, err := h.service.WebauthnCredentials(c, c.Locals("user_id").(int64))
+	if err != nil {
+		log.Warn(err.Error())
+		return webauthnError(c, err)
+	}
+	return c.Status(200).JSON(res)
+}
+
+// @Summary      Delete WebAuthn key of current user
+// @Tags         Auth
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      int  true  "Credential id"
+// @Success      200      string  "ok"
+// @Failure      404      {string}  string  "webauthn credential not found"
+// @Router       /auth/webauthn/credentials/{id} [delete]
+func (h *AuthHandler) DeleteWebauthnCredential(c fiber.Ctx) error {
+	op := "HttpHandlers.DeleteWebauthnCredential"
+	log := h.log.With(slog.String("op", op))
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		log.Warn(err.Error())
+		return c.Status(400).SendString(errorsApp.ErrBadRequest.Message)
+	}
+
+	err = h.service.DeleteWebauthnCredential(c, c.Locals("user_id").(int64), id)
+	if err != nil {
+		log.Warn(err.Error())
+		return webauthnError(c, err)
+	}
+	return c.Status(200).SendString("ok")
+}
+
+func bindWebauthnFinish(c fiber.Ctx) (dto.AuthWebauthnFinishRequest, error) {
+	body := dto.AuthWebauthnFinishRequest{}
+	if err := lib.ValidateBody(c, &dto.AuthWebauthnFinishRequest{}); err != nil {
+		return body, err
+	}
+	if err := c.Bind().Body(&body); err != nil {
+		return body, err
+	}
+	return body, nil
+}
+
+func webauthnError(c fiber.Ctx, err error) error {
+	if ok, errSend := sendRetryError(c, err); ok {
+		return errSend
+	}
+	switch err {
+	case errorsApp.ErrWebauthnNotConfigured.Error:
+		return c.Status(errorsApp.ErrWebauthnNotConfigured.Code).SendString(errorsApp.ErrWebauthnNotConfigured.Message)
+	case errorsApp.ErrWebauthnFailed.Error:
+		return c.Status(errorsApp.ErrWebauthnFailed.Code).SendString(errorsApp.ErrWebauthnFailed.Message)
+	case errorsApp.ErrWebauthnCredentialNotFound.Error:
+		return c.Status(errorsApp.ErrWebauthnCredentialNotFound.Code).SendString(errorsApp.ErrWebauthnCredentialNotFound.Message)
+	case errorsApp.ErrUserNotFound.Error:
+		return c.Status(errorsApp.ErrUserNotFound.Code).SendString(errorsApp.ErrUserNotFound.Message)
+	case errorsApp.ErrUserBlocked.Error:
+		return c.Status(errorsApp.ErrUserBlocked.Code).SendString(errorsApp.ErrUserBlocked.Message)
+	case errorsApp.ErrBadRequest.Error:
+		return c.Status(errorsApp.ErrBadRequest.Code).SendString(errorsApp.ErrBadRequest.Message)
+	case errorsApp.ErrAuthentication.Error:
+		return c.Status(errorsApp.ErrAuthentication.Code).SendString(errorsApp.ErrAuthentication.Message)
+	case errorsApp.ErrVerifyNotFound.Error:
+		return c.Status(errorsApp.ErrVerifyNotFound.Code).SendString(errorsApp.ErrVerifyNotFound.Message)
+	case errorsApp.ErrOtpAttemptsExceeded.Error:
+		return c.Status(errorsApp.ErrOtpAttemptsExceeded.Code).SendString(errorsApp.ErrOtpAttemptsExceeded.Message)
+	}
+	return c.Status(500).SendString(errorsApp.ErrInternalError.Message)
+}
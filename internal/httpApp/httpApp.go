@@ -3,11 +3,13 @@ package httpApp
 import (
 	"context"
 	"log/slog"
+	"time"
 
 	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/config"
 	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/db/cache"
 	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/db/storage"
 	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/middleware"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/services"
 	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v3"
@@ -24,7 +26,10 @@ type HttpApp struct {
 	Storage        *storage.Storage
 	SessionStorage *cache.SessionStorage
 	OtpStorage     *cache.OtpStorage
+	LimitStorage   *cache.LimitStorage
+	GeoIP          *lib.GeoIP
 	Cfg            *config.Config
+	stopBackground context.CancelFunc // фоновые задачи: перечитывание ключей, очистка удаленных аккаунтов
 }
 
 func (v *structValidator) Validate(out any) error {
@@ -60,8 +65,41 @@ func NewHttpApp(
 		return nil, err
 	}
 
+	limitStorage, err := cache.InitLimit(ctxDB, cfg.REDIS_HOST, cfg.REDIS_PORT, cfg.REDIS_LIMIT_DB, log)
+	if err != nil {
+		log.Error("not init cache limit")
+		return nil, err
+	}
+
+	var jwtKeys *lib.JWTKeySet
+	if cfg.AUTH_JWT_KEYS_DIR != "" {
+		jwtKeys, err = lib.LoadJWTKeySetFromDir(cfg.AUTH_JWT_KEYS_DIR)
+	} else {
+		jwtKeys, err = lib.LoadJWTKeySet(cfg.AUTH_JWT_ALG, cfg.AUTH_SECRET_KEY, cfg.AUTH_JWT_PRIVATE_KEY_PATH, cfg.AUTH_JWT_KID)
+	}
+	if err != nil {
+		log.Error("not init jwt keys", slog.String("err", err.Error()))
+		return nil, err
+	}
+	log.Info("jwt signing key loaded", slog.String("alg", jwtKeys.Signing().Method.Alg()), slog.String("kid", jwtKeys.Signing().Kid))
+
+	geoIP, err := lib.OpenGeoIP(cfg.GEOIP_DB_PATH, cfg.GEOIP_LANG)
+	if err != nil {
+		// без геолокации сессии работают, просто без страны и города
+		log.Error("not open geoip database", slog.String("path", cfg.GEOIP_DB_PATH), slog.String("err", err.Error()))
+	}
+
+	ctxBackground, stopBackground := context.WithCancel(context.Background())
+	if cfg.AUTH_JWT_KEYS_DIR != "" {
+		go reloadJwtKeys(ctxBackground, jwtKeys, cfg, log)
+	}
+	if cfg.AUTH_ACCOUNT_PURGE_INTERVAL > 0 {
+		go purgeDeletedUsers(ctxBackground, services.NewCleanupService(log, storage, sessionStorage, cfg), cfg, log)
+	}
+
 	validator := validator.New()
 	validator.RegisterValidation("phoneKZ", lib.PhoneValidatorKZ)
+	validator.RegisterValidation("contactAddress", lib.ContactAddressValidator)
 
 	server := fiber.New(fiber.Config{
 		StructValidator: &structValidator{validate: validator},
@@ -78,7 +116,7 @@ func NewHttpApp(
 
 	server.Use(middleware.PrometheusMiddleware(prometheus.CounterVec, prometheus.HistogramVec))
 
-	RegisterMainRoutes(server, storage, sessionStorage, otpStorage, log, cfg)
+	RegisterMainRoutes(server, storage, sessionStorage, otpStorage, limitStorage, jwtKeys, geoIP, prometheus, log, cfg)
 
 	server.Get("/healthz", func(c fiber.Ctx) error {
 		return c.Status(200).SendString("OK")
@@ -90,10 +128,60 @@ func NewHttpApp(
 		Storage:        storage,
 		SessionStorage: sessionStorage,
 		OtpStorage:     otpStorage,
+		LimitStorage:   limitStorage,
+		GeoIP:          geoIP,
 		Cfg:            cfg,
+		stopBackground: stopBackground,
 	}, nil
 }
 
+// reloadJwtKeys периодически перечитывает каталог ключей, чтобы promote/retire
+// из cmd/jwtkeys применялись без перезапуска
+func reloadJwtKeys(ctx context.Context, jwtKeys *lib.JWTKeySet, cfg *config.Config, log *slog.Logger) {
+	ticker := time.NewTicker(cfg.AUTH_JWT_KEYS_RELOAD_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			activeKid := jwtKeys.Signing().Kid
+			if err := jwtKeys.ReloadFromDir(cfg.AUTH_JWT_KEYS_DIR); err != nil {
+				// оставляем прежний набор ключей
+				log.Error("error reload jwt keys", slog.String("err", err.Error()))
+				continue
+			}
+			if jwtKeys.Signing().Kid != activeKid {
+				log.Info("jwt signing key changed", slog.String("kid", jwtKeys.Signing().Kid))
+			}
+		}
+	}
+}
+
+// purgeDeletedUsers периодически обезличивает аккаунты с истекшим льготным периодом удаления,
+// остальную очистку (неподтвержденные пользователи, индексы сессий) выполняет cmd/clear_db
+func purgeDeletedUsers(ctx context.Context, cleanupService *services.CleanupService, cfg *config.Config, log *slog.Logger) {
+	ticker := time.NewTicker(cfg.AUTH_ACCOUNT_PURGE_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, _, err := cleanupService.PurgeDeletedUsers(ctx, false)
+			if err != nil {
+				log.Error("error purge deleted users", slog.String("err", err.Error()))
+				continue
+			}
+			if len(purged) > 0 {
+				log.Info("deleted users purged", slog.Int("count", len(purged)))
+			}
+		}
+	}
+}
+
 func (a *HttpApp) Run() {
 	err := a.Server.Listen(":"+a.Cfg.HTTP_PORT, fiber.ListenConfig{
 		EnablePrefork:   a.Cfg.HTTP_PREFORK,
@@ -106,8 +194,10 @@ func (a *HttpApp) Run() {
 }
 
 func (a *HttpApp) Stop() {
+	a.stopBackground()
 	err := a.Server.Shutdown()
 	a.Storage.Close()
+	a.GeoIP.Close()
 	if err != nil {
 		a.Log.Error("error on stop server: ", slog.String("err", err.Error()))
 		panic(err)
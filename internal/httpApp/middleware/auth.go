@@ -8,7 +8,7 @@ import (
 	"github.com/gofiber/fiber/v3"
 )
 
-func RequireAuth(log *slog.Logger, cfg *config.Config) fiber.Handler {
+func RequireAuth(log *slog.Logger, cfg *config.Config, jwtKeys *lib.JWTKeySet) fiber.Handler {
 	return func(c fiber.Ctx) error {
 
 		token := ""
@@ -16,13 +16,15 @@ func RequireAuth(log *slog.Logger, cfg *config.Config) fiber.Handler {
 		if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
 			token = authHeader[7:]
 		}
-		claims, err := lib.GetClaimsFromAccessToken(token, cfg.AUTH_SECRET_KEY, cfg.SERVICE_NAME)
+		claims, err := lib.GetClaimsFromAccessToken(token, jwtKeys, cfg.SERVICE_NAME)
 		if err != nil {
 			log.Error("GetClaimsFromAccessToken error: ", slog.Any("err", err))
 			return c.SendStatus(fiber.StatusUnauthorized)
 		}
 		//log.Debug("Claims: ", slog.Any("claims", claims))
 		c.Locals("user_id", claims.UserId)
+		c.Locals("role_id", claims.RoleId)
+		c.Locals("jti", claims.Jti)
 
 		return c.Next()
 	}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/db/cache"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/gofiber/fiber/v3"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	RateLimitByIP     = "ip"
+	RateLimitByUser   = "user"    // user_id из Locals, ставится после RequireAuth
+	RateLimitByApiKey = "api_key" // заголовок X-API-Key, только ключи из ApiKeys
+)
+
+type rateLimiter interface {
+	RateLimitHit(ctx context.Context, key string, limit int, window time.Duration) (cache.RateLimitResult, *errorsApp.DbError)
+}
+
+type RateLimitConfig struct {
+	Name   string // имя лимитера, часть ключа в Redis и метка в метриках
+	Limit  int
+	Window time.Duration
+	KeyBy  string // ip, user или api_key, при отсутствии user_id / известного ключа - по IP
+	// ApiKeys - выданные ключи для KeyBy=api_key, неизвестный ключ считается по IP,
+	// иначе случайными значениями заголовка лимит обходится
+	ApiKeys []string
+}
+
+// RateLimit ограничивает число запросов в скользящем окне. Ставит заголовки X-RateLimit-*,
+// при превышении отвечает 429 с Retry-After. При недоступности Redis запросы пропускаются
+func RateLimit(log *slog.Logger, limiter rateLimiter, rejected *prometheus.CounterVec, cfg RateLimitConfig) fiber.Handler {
+	log = log.With(slog.String("op", "middleware.RateLimit"), slog.String("limiter", cfg.Name))
+
+	// сам ключ в Redis не храним, сравниваем и считаем по хэшу
+	apiKeys := make(map[string]struct{}, len(cfg.ApiKeys))
+	for _, apiKey := range cfg.ApiKeys {
+		if apiKey != "" {
+			apiKeys[apiKeyHash(apiKey)] = struct{}{}
+		}
+	}
+
+	return func(c fiber.Ctx) error {
+		if cfg.Limit <= 0 {
+			return c.Next()
+		}
+
+		key := cfg.Name + ":" + rateLimitKey(c, cfg.KeyBy, apiKeys)
+		res, dbError := limiter.RateLimitHit(c, key, cfg.Limit, cfg.Window)
+		if dbError != nil {
+			log.Error("rate limit error, skip", slog.String("err", dbError.Message))
+			return c.Next()
+		}
+
+		reset := strconv.FormatInt(int64(math.Ceil(res.Reset.Seconds())), 10)
+		c.Set("X-RateLimit-Limit", strconv.Itoa(cfg.Limit))
+		c.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Set("X-RateLimit-Reset", reset)
+
+		if !res.Allowed {
+			log.Warn("rate limit exceeded", slog.String("key", key), slog.String("path", c.Path()))
+			if rejected != nil {
+				rejected.WithLabelValues(cfg.Name, cfg.KeyBy).Inc()
+			}
+			c.Set(fiber.HeaderRetryAfter, reset)
+			return c.Status(errorsApp.ErrRateLimited.Code).SendString(errorsApp.ErrRateLimited.Message)
+		}
+		return c.Next()
+	}
+}
+
+func rateLimitKey(c fiber.Ctx, keyBy string, apiKeys map[string]struct{}) string {
+	switch keyBy {
+	case RateLimitByUser:
+		if userId, ok := c.Locals("user_id").(int64); ok {
+			return "user:" + strconv.FormatInt(userId, 10)
+		}
+	case RateLimitByApiKey:
+		if apiKey := c.Get("X-API-Key"); apiKey != "" {
+			hash := apiKeyHash(apiKey)
+			if _, ok := apiKeys[hash]; ok {
+				return "api_key:" + hash
+			}
+		}
+	}
+	return "ip:" + c.IP()
+}
+
+func apiKeyHash(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/gofiber/fiber/v3"
+)
+
+type rbacService interface {
+	HasRole(ctx context.Context, roleId int64, roles ...string) (bool, error)
+	HasPermissions(ctx context.Context, roleId int64, permissions ...string) (bool, error)
+}
+
+// RequireRole пропускает пользователя с одной из ролей, ставится после RequireAuth
+func RequireRole(log *slog.Logger, rbac rbacService, roles ...string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		roleId, ok := c.Locals("role_id").(int64)
+		if !ok {
+			log.Error("role_id not found in locals, RequireAuth missing?")
+			return c.Status(errorsApp.ErrAuthentication.Code).SendString(errorsApp.ErrAuthentication.Message)
+		}
+
+		allowed, err := rbac.HasRole(c, roleId, roles...)
+		return checkAccess(c, log, allowed, err)
+	}
+}
+
+// RequirePermission пропускает пользователя, у роли которого есть все перечисленные права,
+// ставится после RequireAuth
+func RequirePermission(log *slog.Logger, rbac rbacService, permissions ...string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		roleId, ok := c.Locals("role_id").(int64)
+		if !ok {
+			log.Error("role_id not found in locals, RequireAuth missing?")
+			return c.Status(errorsApp.ErrAuthentication.Code).SendString(errorsApp.ErrAuthentication.Message)
+		}
+
+		allowed, err := rbac.HasPermissions(c, roleId, permissions...)
+		return checkAccess(c, log, allowed, err)
+	}
+}
+
+func checkAccess(c fiber.Ctx, log *slog.Logger, allowed bool, err error) error {
+	if err != nil {
+		if err == errorsApp.ErrInternalError.Error {
+			return c.Status(errorsApp.ErrInternalError.Code).SendString(errorsApp.ErrInternalError.Message)
+		}
+		return c.Status(errorsApp.ErrForbidden.Code).SendString(errorsApp.ErrForbidden.Message)
+	}
+	if !allowed {
+		log.Warn("access denied", slog.Any("user_id", c.Locals("user_id")), slog.String("path", c.Path()))
+		return c.Status(errorsApp.ErrForbidden.Code).SendString(errorsApp.ErrForbidden.Message)
+	}
+	return c.Next()
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/gofiber/fiber/v3"
+)
+
+type sessionChecker interface {
+	IsSessionAlive(ctx context.Context, jti string) (bool, error)
+}
+
+// RequireSession отклоняет access-токен, сессия которого отозвана (logout, revoke, refresh),
+// ставится после RequireAuth на маршруты, где важен немедленный отзыв
+func RequireSession(log *slog.Logger, sessions sessionChecker) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		jti, ok := c.Locals("jti").(string)
+		if !ok {
+			log.Error("jti not found in locals, RequireAuth missing?")
+			return c.Status(errorsApp.ErrAuthentication.Code).SendString(errorsApp.ErrAuthentication.Message)
+		}
+
+		alive, err := sessions.IsSessionAlive(c, jti)
+		if err != nil {
+			return c.Status(errorsApp.ErrInternalError.Code).SendString(errorsApp.ErrInternalError.Message)
+		}
+		if !alive {
+			log.Warn("session revoked", slog.Any("user_id", c.Locals("user_id")), slog.String("jti", jti))
+			return c.Status(errorsApp.ErrSessionNotFound.Code).SendString(errorsApp.ErrSessionNotFound.Message)
+		}
+		return c.Next()
+	}
+}
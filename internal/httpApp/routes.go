@@ -10,24 +10,54 @@ import (
 	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/handlers"
 	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/middleware"
 	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/services"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
 	"github.com/gofiber/fiber/v3"
 	"github.com/gofiber/swagger/v2"
 )
 
-func RegisterMainRoutes(app *fiber.App, storage *storage.Storage, sessionStorage *cache.SessionStorage, otpStorage *cache.OtpStorage, log *slog.Logger, cfg *config.Config) {
+func RegisterMainRoutes(app *fiber.App, storage *storage.Storage, sessionStorage *cache.SessionStorage, otpStorage *cache.OtpStorage, limitStorage *cache.LimitStorage, jwtKeys *lib.JWTKeySet, geoIP *lib.GeoIP, prometheus lib.PrometheusType, log *slog.Logger, cfg *config.Config) {
 	cp := "registerRoutes"
 	log = log.With(slog.String("cp", cp))
 	log.Info("Register routes:")
 
 	app.Get("/swagger/*", swagger.HandlerDefault) // default
 
+	jwksHandler := handlers.NewJwksHandler(log, jwtKeys)
+	log.Info("GET /.well-known/jwks.json")
+	app.Get("/.well-known/jwks.json", jwksHandler.GetJwks)
+
 	log.Info("/api")
-	api := app.Group("/api")
-	RegisterUserRoutes(api, storage, log, cfg)
-	RegisterAuthRoutes(api, storage, sessionStorage, otpStorage, log, cfg)
+	api := app.Group("/api", middleware.RateLimit(log, limitStorage, prometheus.RateLimitRejected, middleware.RateLimitConfig{
+		Name:    "api",
+		Limit:   cfg.RATE_LIMIT_API_LIMIT,
+		Window:  cfg.RATE_LIMIT_API_WINDOW,
+		KeyBy:   cfg.RATE_LIMIT_API_KEY_BY,
+		ApiKeys: cfg.RATE_LIMIT_API_KEYS,
+	}))
+	// вход, регистрация и коды - отдельный, более строгий лимит по IP
+	api.Use("/auth", middleware.RateLimit(log, limitStorage, prometheus.RateLimitRejected, middleware.RateLimitConfig{
+		Name:   "auth",
+		Limit:  cfg.RATE_LIMIT_AUTH_LIMIT,
+		Window: cfg.RATE_LIMIT_AUTH_WINDOW,
+		KeyBy:  middleware.RateLimitByIP,
+	}))
+	adminRateLimit := middleware.RateLimit(log, limitStorage, prometheus.RateLimitRejected, middleware.RateLimitConfig{
+		Name:   "admin",
+		Limit:  cfg.RATE_LIMIT_ADMIN_LIMIT,
+		Window: cfg.RATE_LIMIT_ADMIN_WINDOW,
+		KeyBy:  middleware.RateLimitByUser,
+	})
+	rbacService := services.NewRbacService(log, storage, cfg)
+	// проверка отзыва access-токена ставится только на маршруты, где она нужна ([session] в логе)
+	sessionCheckService := services.NewSessionCheckService(log, sessionStorage, cfg)
+
+	RegisterUserRoutes(api, storage, rbacService, sessionCheckService, jwtKeys, log, cfg)
+	RegisterAuthRoutes(api, storage, sessionStorage, otpStorage, limitStorage, sessionCheckService, jwtKeys, geoIP, log, cfg)
+	RegisterAdminRoutes(api, storage, sessionStorage, rbacService, sessionCheckService, adminRateLimit, jwtKeys, log, cfg)
+	RegisterAuthEventRoutes(api, storage, rbacService, sessionCheckService, adminRateLimit, jwtKeys, log, cfg)
 }
 
-func RegisterUserRoutes(api fiber.Router, storage *storage.Storage, log *slog.Logger, cfg *config.Config) {
+func RegisterUserRoutes(api fiber.Router, storage *storage.Storage, rbacService *services.RbacService, sessionCheckService *services.SessionCheckService, jwtKeys *lib.JWTKeySet, log *slog.Logger, cfg *config.Config) {
 
 	userService := services.NewUserService(log, storage, cfg)
 	userHandler := handlers.NewUserHandler(log, userService)
@@ -35,13 +65,13 @@ func RegisterUserRoutes(api fiber.Router, storage *storage.Storage, log *slog.Lo
 	log.Info("GET /api/user/:id?")
 	api.Get("/user/:id?", userHandler.GetUserById)
 
-	log.Info("GET /api/users")
-	api.Get("/users", middleware.RequireAuth(log, cfg), userHandler.GetUserSearch)
+	log.Info("GET /api/users [session] [users:read]")
+	api.Get("/users", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), middleware.RequirePermission(log, rbacService, "users:read"), userHandler.GetUserSearch)
 }
 
-func RegisterAuthRoutes(api fiber.Router, storage *storage.Storage, sessionStorage *cache.SessionStorage, otpStorage *cache.OtpStorage, log *slog.Logger, cfg *config.Config) {
+func RegisterAuthRoutes(api fiber.Router, storage *storage.Storage, sessionStorage *cache.SessionStorage, otpStorage *cache.OtpStorage, limitStorage *cache.LimitStorage, sessionCheckService *services.SessionCheckService, jwtKeys *lib.JWTKeySet, geoIP *lib.GeoIP, log *slog.Logger, cfg *config.Config) {
 
-	authService := services.NewAuthService(log, storage, sessionStorage, otpStorage, cfg)
+	authService := services.NewAuthService(log, storage, sessionStorage, otpStorage, limitStorage, jwtKeys, geoIP, cfg)
 	authHandler := handlers.NewAuthHandler(cfg, log, authService)
 
 	log.Info("POST /api/auth/register")
@@ -52,14 +82,116 @@ func RegisterAuthRoutes(api fiber.Router, storage *storage.Storage, sessionStora
 	api.Get("/auth/hello", authHandler.AuthHello)
 	log.Info("POST /api/auth/refresh")
 	api.Post("/auth/refresh", authHandler.AuthRefresh)
-	log.Info("GET /api/auth/sessions/:id")
-	api.Get("/auth/sessions/:id", middleware.RequireAuth(log, cfg), authHandler.AuthSessions)
-	log.Info("DELETE /api/auth/sessions/:jti")
-	api.Delete("/auth/sessions/:jti", middleware.RequireAuth(log, cfg), authHandler.RevokeSession)
+	log.Info("GET /api/auth/sessions/:id [session]")
+	api.Get("/auth/sessions/:id", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.AuthSessions)
+	log.Info("DELETE /api/auth/sessions/:jti [session]")
+	api.Delete("/auth/sessions/:jti", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.RevokeSession)
+	log.Info("POST /api/auth/logout [session]")
+	api.Post("/auth/logout", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.Logout)
+	log.Info("POST /api/auth/logout-all [session]")
+	api.Post("/auth/logout-all", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.LogoutAll)
 	log.Info("POST /api/auth/send-verify")
 	api.Post("/auth/send-verify", authHandler.SendVerify)
 	log.Info("POST /api/auth/confirm-verify")
 	api.Post("/auth/confirm-verify", authHandler.ConfirmVerify)
-	log.Info("POST /api/auth/update-password")
-	api.Post("/auth/update-password", middleware.RequireAuth(log, cfg), authHandler.UpdatePassword)
+	log.Info("POST /api/auth/update-password [session]")
+	api.Post("/auth/update-password", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.UpdatePassword)
+	log.Info("POST /api/auth/reset-password")
+	api.Post("/auth/reset-password", authHandler.ResetPassword)
+	log.Info("POST /api/auth/confirm-reset-password")
+	api.Post("/auth/confirm-reset-password", authHandler.ConfirmResetPassword)
+	log.Info("POST /api/auth/change-contact/code [session]")
+	api.Post("/auth/change-contact/code", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.ChangeContactCode)
+	log.Info("POST /api/auth/change-contact [session]")
+	api.Post("/auth/change-contact", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.ChangeContact)
+	log.Info("POST /api/auth/confirm-change-contact [session]")
+	api.Post("/auth/confirm-change-contact", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.ConfirmChangeContact)
+	log.Info("POST /api/me/deletion-code [session]")
+	api.Post("/me/deletion-code", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.DeleteAccountCode)
+	log.Info("DELETE /api/me [session]")
+	api.Delete("/me", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.DeleteAccount)
+	log.Info("POST /api/me/export [session]")
+	api.Post("/me/export", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.StartDataExport)
+	log.Info("GET /api/me/export/:id [session]")
+	api.Get("/me/export/:id", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.DataExportStatus)
+	log.Info("GET /api/data-export/:id [signed link]")
+	api.Get("/data-export/:id", authHandler.DownloadDataExport)
+	log.Info("GET /api/security/revoke-all [signed link]")
+	api.Get("/security/revoke-all", authHandler.RevokeAllConfirm)
+	log.Info("POST /api/security/revoke-all [signed link]")
+	api.Post("/security/revoke-all", authHandler.RevokeAllByLink)
+	log.Info("POST /api/auth/otp/start")
+	api.Post("/auth/otp/start", authHandler.OtpStart)
+	log.Info("POST /api/auth/otp/complete")
+	api.Post("/auth/otp/complete", authHandler.OtpComplete)
+	log.Info("GET /api/auth/oauth/providers")
+	api.Get("/auth/oauth/providers", authHandler.OauthProviders)
+	log.Info("GET /api/auth/oauth/:provider/start")
+	api.Get("/auth/oauth/:provider/start", authHandler.OauthStart)
+	log.Info("GET /api/auth/oauth/:provider/callback")
+	api.Get("/auth/oauth/:provider/callback", authHandler.OauthCallback)
+	log.Info("GET /api/auth/oauth/accounts [session]")
+	api.Get("/auth/oauth/accounts", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.OauthAccounts)
+	log.Info("POST /api/auth/oauth/:provider/link [session]")
+	api.Post("/auth/oauth/:provider/link", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.OauthLink)
+	log.Info("DELETE /api/auth/oauth/accounts/:id [session]")
+	api.Delete("/auth/oauth/accounts/:id", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.OauthUnlink)
+	log.Info("GET /api/auth/2fa [session]")
+	api.Get("/auth/2fa", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.TotpStatus)
+	log.Info("POST /api/auth/2fa/enroll [session]")
+	api.Post("/auth/2fa/enroll", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.TotpEnroll)
+	log.Info("POST /api/auth/2fa/confirm [session]")
+	api.Post("/auth/2fa/confirm", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.TotpConfirm)
+	log.Info("POST /api/auth/2fa/disable [session]")
+	api.Post("/auth/2fa/disable", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.TotpDisable)
+	log.Info("POST /api/auth/2fa/verify")
+	api.Post("/auth/2fa/verify", authHandler.MfaVerify)
+	log.Info("POST /api/auth/webauthn/register/code [session]")
+	api.Post("/auth/webauthn/register/code", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.WebauthnRegisterCode)
+	log.Info("POST /api/auth/webauthn/register/options [session]")
+	api.Post("/auth/webauthn/register/options", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.WebauthnRegisterOptions)
+	log.Info("POST /api/auth/webauthn/register/finish [session]")
+	api.Post("/auth/webauthn/register/finish", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.WebauthnRegisterFinish)
+	log.Info("POST /api/auth/webauthn/login/options")
+	api.Post("/auth/webauthn/login/options", authHandler.WebauthnLoginOptions)
+	log.Info("POST /api/auth/webauthn/login/finish")
+	api.Post("/auth/webauthn/login/finish", authHandler.WebauthnLoginFinish)
+	log.Info("GET /api/auth/webauthn/credentials [session]")
+	api.Get("/auth/webauthn/credentials", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.WebauthnCredentials)
+	log.Info("DELETE /api/auth/webauthn/credentials/:id [session]")
+	api.Delete("/auth/webauthn/credentials/:id", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authHandler.DeleteWebauthnCredential)
+}
+
+func RegisterAdminRoutes(api fiber.Router, storage *storage.Storage, sessionStorage *cache.SessionStorage, rbacService *services.RbacService, sessionCheckService *services.SessionCheckService, rateLimit fiber.Handler, jwtKeys *lib.JWTKeySet, log *slog.Logger, cfg *config.Config) {
+
+	adminUserService := services.NewAdminUserService(log, storage, sessionStorage, cfg)
+	adminUserHandler := handlers.NewAdminUserHandler(log, adminUserService)
+
+	admin := api.Group("/admin/users", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), middleware.RequireRole(log, rbacService, "admin"), rateLimit)
+
+	log.Info("GET /api/admin/users [admin]")
+	admin.Get("/", adminUserHandler.ListUsers)
+	log.Info("GET /api/admin/users/:id/sessions [admin]")
+	admin.Get("/:id/sessions", adminUserHandler.Sessions)
+	log.Info("PATCH /api/admin/users/:id/role [admin]")
+	admin.Patch("/:id/role", adminUserHandler.UpdateRole)
+	log.Info("POST /api/admin/users/:id/logout [admin]")
+	admin.Post("/:id/logout", adminUserHandler.Logout)
+	log.Info("POST /api/admin/users/:id/block [admin]")
+	admin.Post("/:id/block", adminUserHandler.Block)
+	log.Info("POST /api/admin/users/:id/unblock [admin]")
+	admin.Post("/:id/unblock", adminUserHandler.Unblock)
+	log.Info("DELETE /api/admin/users/:id [admin]")
+	admin.Delete("/:id", adminUserHandler.DeleteUser)
+}
+
+func RegisterAuthEventRoutes(api fiber.Router, storage *storage.Storage, rbacService *services.RbacService, sessionCheckService *services.SessionCheckService, rateLimit fiber.Handler, jwtKeys *lib.JWTKeySet, log *slog.Logger, cfg *config.Config) {
+
+	authEventService := services.NewAuthEventService(log, storage, cfg)
+	authEventHandler := handlers.NewAuthEventHandler(log, authEventService)
+
+	log.Info("GET /api/me/security-events [session]")
+	api.Get("/me/security-events", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), authEventHandler.MyEvents)
+	log.Info("GET /api/admin/auth-events [admin]")
+	api.Get("/admin/auth-events", middleware.RequireAuth(log, cfg, jwtKeys), middleware.RequireSession(log, sessionCheckService), middleware.RequireRole(log, rbacService, "admin"), rateLimit, authEventHandler.AdminEvents)
 }
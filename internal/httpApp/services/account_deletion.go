@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
+)
+
+// otpTypeDeleteAccount - код удаления привязан к пользователю, как и код смены адреса
+func otpTypeDeleteAccount(userId int64) string {
+	return "delete:" + strconv.FormatInt(userId, 10)
+}
+
+// DeleteAccountCode отправляет код подтверждения удаления на подтвержденный адрес пользователя,
+// нужен тем, у кого нет пароля (oauth, вход по коду, passkey)
+func (s *AuthService) DeleteAccountCode(ctx context.Context, userId int64, body dto.AuthDeleteAccountCodeRequest, ip string) (dto.AuthSendVerifyResponse, error) {
+	op := "services.DeleteAccountCode"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthSendVerifyResponse{}
+
+	user, dbError := s.authStorage.GetUserById(ctx, userId)
+	if dbError != nil {
+		log.Warn("error get user by id", slog.String("err", dbError.Message))
+		return response, errorsApp.ErrUserNotFound.Error
+	}
+	if !addressVerified(user, body.Type) {
+		log.Warn("address not verified", slog.Int64("user_id", userId), slog.String("type", body.Type))
+		return response, errorsApp.ErrBadRequest.Error
+	}
+
+	return s.issueOtp(ctx, otpTypeDeleteAccount(userId), body.Type, userAddress(user, body.Type), ip, "Account deletion code for "+s.cfg.SERVICE_NAME, "Your account deletion code is: ")
+}
+
+// DeleteAccount принимает запрос на удаление, подтвержденный паролем или кодом, и завершает все сессии.
+// До конца AUTH_ACCOUNT_DELETION_GRACE вход восстанавливает аккаунт, потом его обезличивает CleanupService
+func (s *AuthService) DeleteAccount(ctx context.Context, userId int64, body dto.AuthDeleteAccountRequest) (dto.AuthDeleteAccountResponse, error) {
+	op := "services.DeleteAccount"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthDeleteAccountResponse{}
+
+	user, dbError := s.authStorage.GetUserById(ctx, userId)
+	if dbError != nil {
+		log.Warn("error get user by id", slog.String("err", dbError.Message))
+		return response, errorsApp.ErrUserNotFound.Error
+	}
+
+	if err := s.reauthenticate(ctx, user, otpTypeDeleteAccount(userId), body.Password, body.Type, body.Code); err != nil {
+		return response, err
+	}
+
+	dbError = s.authStorage.RequestUserDeletion(ctx, userId)
+	if dbError != nil {
+		log.Error("error request user deletion", slog.String("err", dbError.Message))
+		return response, errorsApp.ErrInternalError.Error
+	}
+	dbError = s.sessionStorage.DeleteSessionsByUserId(ctx, userId)
+	if dbError != nil {
+		log.Error("error delete sessions by user id", slog.String("err", dbError.Message))
+		return response, errorsApp.ErrInternalError.Error
+	}
+
+	response.DeletionRequestedAt = time.Now()
+	response.PurgeAt = response.DeletionRequestedAt.Add(s.cfg.AUTH_ACCOUNT_DELETION_GRACE)
+	log.Info("account deletion requested", slog.Int64("user_id", userId), slog.Time("purge_at", response.PurgeAt))
+
+	text := "Your " + s.cfg.SERVICE_NAME + " account will be deleted on " + response.PurgeAt.Format(time.DateOnly) +
+		". Log in before this date to keep the account."
+	s.notifyUser(user, "Account deletion requested", text)
+	return response, nil
+}
+
+// reauthenticate подтверждает действие с аккаунтом паролем или одноразовым кодом типа otpType,
+// отправленным на подтвержденный адрес пользователя в канал channel
+func (s *AuthService) reauthenticate(ctx context.Context, user models.UserEntity, otpType string, password string, channel string, code string) error {
+	op := "services.reauthenticate"
+	log := s.log.With(slog.String("op", op))
+
+	switch {
+	case password != "":
+		// перебор пароля по украденному access-токену ограничивается так же, как вход
+		lockKey := "reauth:" + strconv.FormatInt(user.Id, 10)
+		lock, dbError := s.limitStorage.GetLoginLock(ctx, lockKey)
+		if dbError != nil {
+			log.Error("error get reauth lock", slog.String("err", dbError.Message))
+			return errorsApp.ErrInternalError.Error
+		}
+		if lock > 0 {
+			log.Warn("reauth locked", slog.Int64("user_id", user.Id), slog.Duration("retry_after", lock))
+			return &errorsApp.RetryError{Err: errorsApp.ErrTooManyAttempts, RetryAfter: lock}
+		}
+		if !user.Password_hash.Valid || lib.CheckPassword(user.Password_hash.String, password) != nil {
+			log.Warn("invalid password", slog.Int64("user_id", user.Id))
+			_, dbError := s.limitStorage.AddLoginFailure(ctx, lockKey, s.cfg.AUTH_LOGIN_ATTEMPTS_WINDOW,
+				s.cfg.AUTH_LOGIN_MAX_ATTEMPTS, s.cfg.AUTH_LOGIN_LOCK_BASE, s.cfg.AUTH_LOGIN_LOCK_MAX)
+			if dbError != nil {
+				log.Error("error add reauth failure", slog.String("err", dbError.Message))
+			}
+			return errorsApp.ErrAuthentication.Error
+		}
+		if dbError := s.limitStorage.ResetLoginFailures(ctx, lockKey); dbError != nil {
+			log.Warn("error reset reauth failures", slog.String("err", dbError.Message))
+		}
+	case code != "" && channel != "":
+		if !addressVerified(user, channel) {
+			log.Warn("address not verified", slog.Int64("user_id", user.Id), slog.String("type", channel))
+			return errorsApp.ErrBadRequest.Error
+		}
+		address := userAddress(user, channel)
+		if err := s.checkOtp(ctx, otpType, address, code); err != nil {
+			return err
+		}
+		s.invalidateOtp(ctx, address, otpType)
+	default:
+		log.Warn("no password or code", slog.Int64("user_id", user.Id))
+		return errorsApp.ErrBadRequest.Error
+	}
+	return nil
+}
+
+// restoreAccount снимает запрос на удаление при входе в льготный период
+func (s *AuthService) restoreAccount(ctx context.Context, user models.UserEntity) error {
+	op := "services.restoreAccount"
+	log := s.log.With(slog.String("op", op))
+
+	dbError := s.authStorage.CancelUserDeletion(ctx, user.Id)
+	if dbError != nil {
+		log.Error("error cancel user deletion", slog.String("err", dbError.Message))
+		return errorsApp.ErrInternalError.Error
+	}
+	log.Info("account restored", slog.Int64("user_id", user.Id))
+
+	s.notifyUser(user, "Account restored", "Deletion of your "+s.cfg.SERVICE_NAME+" account was cancelled because you logged in.")
+	return nil
+}
+
+// notifyUser уведомляет пользователя на все подтвержденные адреса
+func (s *AuthService) notifyUser(user models.UserEntity, subject string, text string) {
+	for _, channel := range []string{"email", "phone"} {
+		if addressVerified(user, channel) {
+			s.notify(channel, userAddress(user, channel), subject, text)
+		}
+	}
+}
+
+func userAddress(user models.UserEntity, channel string) string {
+	if channel == "phone" {
+		return user.Phone_number.String
+	}
+	return user.Email.String
+}
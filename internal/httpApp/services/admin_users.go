@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/config"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/db/cache"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
+	"github.com/guregu/null/v6"
+	"github.com/jinzhu/copier"
+)
+
+const (
+	adminUsersDefaultLimit = 20
+)
+
+type AdminUserService struct {
+	log            *slog.Logger
+	adminStorage   adminStorage
+	sessionStorage adminSessionStorage
+	cfg            *config.Config
+}
+
+type adminStorage interface {
+	ListUsers(ctx context.Context, filter models.UsersFilter) ([]models.UserEntity, int64, *errorsApp.DbError)
+	GetRoles(ctx context.Context) ([]models.RoleEntity, *errorsApp.DbError)
+	GetRoleById(ctx context.Context, id int64) (models.RoleEntity, *errorsApp.DbError)
+	GetUserById(ctx context.Context, id int64) (models.UserEntity, *errorsApp.DbError)
+	UpdateUserRole(ctx context.Context, id int64, roleId int64) *errorsApp.DbError
+	SetUserBlocked(ctx context.Context, id int64, blocked bool) *errorsApp.DbError
+	DeleteUser(ctx context.Context, id int64) *errorsApp.DbError
+}
+
+type adminSessionStorage interface {
+	GetSessionsByUserId(ctx context.Context, userId int64) ([]cache.SessionData, *errorsApp.DbError)
+	DeleteSessionsByUserId(ctx context.Context, userId int64) *errorsApp.DbError
+}
+
+func NewAdminUserService(log *slog.Logger,
+	adminStorage adminStorage,
+	sessionStorage adminSessionStorage,
+	cfg *config.Config) *AdminUserService {
+	return &AdminUserService{
+		log:            log,
+		adminStorage:   adminStorage,
+		sessionStorage: sessionStorage,
+		cfg:            cfg,
+	}
+}
+
+func (s *AdminUserService) ListUsers(ctx context.Context, params dto.AdminUsersQueryParams) (dto.AdminUsersResponse, error) {
+	op := "services.AdminUserService.ListUsers"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AdminUsersResponse{Users: make([]dto.AdminUser, 0)}
+
+	if params.Page == 0 {
+		params.Page = 1
+	}
+	if params.Limit == 0 {
+		params.Limit = adminUsersDefaultLimit
+	}
+	response.Page = params.Page
+	response.Limit = params.Limit
+
+	filter := models.UsersFilter{
+		RoleName: params.Role,
+		Limit:    params.Limit,
+		Offset:   (params.Page - 1) * params.Limit,
+	}
+	if params.Verified != "" {
+		filter.Verified = null.BoolFrom(params.Verified == "true")
+	}
+	if params.CreatedFrom != "" {
+		from, err := time.ParseInLocation(time.DateOnly, params.CreatedFrom, time.Local)
+		if err != nil {
+			return response, errorsApp.ErrBadRequest.Error
+		}
+		filter.CreatedFrom = null.TimeFrom(from)
+	}
+	if params.CreatedTo != "" {
+		to, err := time.ParseInLocation(time.DateOnly, params.CreatedTo, time.Local)
+		if err != nil {
+			return response, errorsApp.ErrBadRequest.Error
+		}
+		// дата включительно
+		filter.CreatedTo = null.TimeFrom(to.AddDate(0, 0, 1))
+	}
+
+	users, total, dbError := s.adminStorage.ListUsers(ctx, filter)
+	if dbError != nil {
+		log.Error("error list users", slog.String("err", dbError.Message))
+		return response, errorsApp.ErrInternalError.Error
+	}
+	roles, dbError := s.adminStorage.GetRoles(ctx)
+	if dbError != nil {
+		log.Error("error get roles", slog.String("err", dbError.Message))
+		return response, errorsApp.ErrInternalError.Error
+	}
+	roleNames := make(map[int64]string, len(roles))
+	for _, role := range roles {
+		roleNames[role.Id] = role.Name
+	}
+
+	errCopy := copier.Copy(&response.Users, &users)
+	if errCopy != nil {
+		log.Error("", slog.String("err", errCopy.Error()))
+		return response, errCopy
+	}
+	for i := range response.Users {
+		response.Users[i].Role_name = roleNames[response.Users[i].Role_id]
+	}
+	response.Total = total
+
+	return response, nil
+}
+
+// UpdateRole меняет роль и завершает сессии, чтобы новые токены получили актуальный role_id
+func (s *AdminUserService) UpdateRole(ctx context.Context, adminId int64, userId int64, roleId int64) error {
+	op := "services.AdminUserService.UpdateRole"
+	log := s.log.With(slog.String("op", op))
+
+	if adminId == userId {
+		log.Warn("admin tries to change own role", slog.Int64("user_id", userId))
+		return errorsApp.ErrForbidden.Error
+	}
+
+	if _, dbError := s.adminStorage.GetRoleById(ctx, roleId); dbError != nil {
+		log.Warn("role not found", slog.Int64("role_id", roleId))
+		return errorsApp.ErrBadRequest.Error
+	}
+
+	dbError := s.adminStorage.UpdateUserRole(ctx, userId, roleId)
+	if dbError != nil {
+		return s.mapUserError(log, dbError)
+	}
+	log.Info("user role changed", slog.Int64("admin_id", adminId), slog.Int64("user_id", userId), slog.Int64("role_id", roleId))
+
+	return s.revokeSessions(ctx, userId)
+}
+
+func (s *AdminUserService) Sessions(ctx context.Context, userId int64) (dto.AuthSessionResponse, error) {
+	op := "services.AdminUserService.Sessions"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthSessionResponse{Sessions: make([]dto.AuthSession, 0)}
+
+	userData, dbError := s.adminStorage.GetUserById(ctx, userId)
+	if dbError != nil {
+		return response, s.mapUserError(log, dbError)
+	}
+
+	sessionData, dbError := s.sessionStorage.GetSessionsByUserId(ctx, userId)
+	if dbError != nil {
+		log.Error("error get sessions by user id", slog.String("err", dbError.Message))
+		return response, errorsApp.ErrInternalError.Error
+	}
+
+	for _, session := range sessionData {
+		response.Sessions = append(response.Sessions, sessionDto(session, userData))
+	}
+	return response, nil
+}
+
+// Logout завершает все сессии пользователя
+func (s *AdminUserService) Logout(ctx context.Context, adminId int64, userId int64) error {
+	op := "services.AdminUserService.Logout"
+	log := s.log.With(slog.String("op", op))
+
+	if _, dbError := s.adminStorage.GetUserById(ctx, userId); dbError != nil {
+		return s.mapUserError(log, dbError)
+	}
+	log.Info("force logout", slog.Int64("admin_id", adminId), slog.Int64("user_id", userId))
+
+	return s.revokeSessions(ctx, userId)
+}
+
+// SetBlocked блокирует пользователя (с завершением сессий) или снимает блокировку
+func (s *AdminUserService) SetBlocked(ctx context.Context, adminId int64, userId int64, blocked bool) error {
+	op := "services.AdminUserService.SetBlocked"
+	log := s.log.With(slog.String("op", op))
+
+	if adminId == userId {
+		log.Warn("admin tries to block himself", slog.Int64("user_id", userId))
+		return errorsApp.ErrForbidden.Error
+	}
+
+	dbError := s.adminStorage.SetUserBlocked(ctx, userId, blocked)
+	if dbError != nil {
+		return s.mapUserError(log, dbError)
+	}
+	log.Info("user block changed", slog.Int64("admin_id", adminId), slog.Int64("user_id", userId), slog.Bool("blocked", blocked))
+
+	if !blocked {
+		return nil
+	}
+	return s.revokeSessions(ctx, userId)
+}
+
+func (s *AdminUserService) DeleteUser(ctx context.Context, adminId int64, userId int64) error {
+	op := "services.AdminUserService.DeleteUser"
+	log := s.log.With(slog.String("op", op))
+
+	if adminId == userId {
+		log.Warn("admin tries to delete himself", slog.Int64("user_id", userId))
+		return errorsApp.ErrForbidden.Error
+	}
+
+	dbError := s.adminStorage.DeleteUser(ctx, userId)
+	if dbError != nil {
+		return s.mapUserError(log, dbError)
+	}
+	log.Info("user deleted", slog.Int64("admin_id", adminId), slog.Int64("user_id", userId))
+
+	return s.revokeSessions(ctx, userId)
+}
+
+func (s *AdminUserService) revokeSessions(ctx context.Context, userId int64) error {
+	dbError := s.sessionStorage.DeleteSessionsByUserId(ctx, userId)
+	if dbError != nil {
+		s.log.Error("error delete sessions by user id", slog.Int64("user_id", userId), slog.String("err", dbError.Message))
+		return errorsApp.ErrInternalError.Error
+	}
+	return nil
+}
+
+func (s *AdminUserService) mapUserError(log *slog.Logger, dbError *errorsApp.DbError) error {
+	log.Warn("user storage error", slog.String("err", dbError.Message))
+	if dbError.Type == "not_found" {
+		return errorsApp.ErrUserNotFound.Error
+	}
+	return errorsApp.ErrInternalError.Error
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
+	"github.com/guregu/null/v6"
+)
+
+// authEvent - событие журнала auth_events. Err == nil - успех, иначе неудача с причиной Err
+type authEvent struct {
+	Type      models.AuthEventType
+	UserId    int64 // 0 - пользователь не найден
+	Identity  string
+	IP        string
+	UserAgent string
+	Err       error
+	Reason    string // для успеха, необязательно
+}
+
+// audit пишет событие в журнал auth_events, ошибка записи только логируется и не влияет на запрос
+func (s *AuthService) audit(ctx context.Context, event authEvent) {
+	op := "services.audit"
+	log := s.log.With(slog.String("op", op))
+
+	entity := models.AuthEventEntity{
+		Event_type: event.Type,
+		Outcome:    models.AuthEventSuccess,
+		Reason:     null.NewString(event.Reason, event.Reason != ""),
+		Identity:   null.NewString(event.Identity, event.Identity != ""),
+		Ip:         null.NewString(event.IP, event.IP != ""),
+		User_agent: null.NewString(event.UserAgent, event.UserAgent != ""),
+	}
+	if event.UserId != 0 {
+		entity.User_id = null.IntFrom(event.UserId)
+	}
+	if event.Err != nil {
+		entity.Outcome = models.AuthEventFailure
+		entity.Reason = null.StringFrom(event.Err.Error())
+	}
+
+	if dbError := s.authStorage.NewAuthEvent(ctx, entity); dbError != nil {
+		log.Error("error save auth event", slog.String("type", string(event.Type)), slog.String("err", dbError.Message))
+	}
+}
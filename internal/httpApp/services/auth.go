@@ -2,7 +2,6 @@ package services
 
 import (
 	"context"
-	"fmt"
 	"log/slog"
 	"time"
 
@@ -12,6 +11,7 @@ import (
 	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
 	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
 	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
 	"github.com/guregu/null/v6"
@@ -23,6 +23,11 @@ type AuthService struct {
 	authStorage    authStorage
 	sessionStorage sessionStorage
 	otpStorage     otpStorage
+	limitStorage   limitStorage
+	oauthRegistry  *OauthRegistry
+	webauthn       *webauthn.WebAuthn // nil - WebAuthn не настроен
+	jwtKeys        *lib.JWTKeySet
+	geoIP          *lib.GeoIP
 	cfg            *config.Config
 }
 
@@ -35,6 +40,27 @@ type authStorage interface {
 	UpdateUserEmailVerifyTimestamp(ctx context.Context, id int64) *errorsApp.DbError
 	UpdateUserPhoneVerifyTimestamp(ctx context.Context, id int64) *errorsApp.DbError
 	UpdatePassword(ctx context.Context, id int64, password string) *errorsApp.DbError
+	UpdateUserEmail(ctx context.Context, id int64, email string) *errorsApp.DbError
+	UpdateUserPhoneNumber(ctx context.Context, id int64, phoneNumber string) *errorsApp.DbError
+	NewOauthAccount(ctx context.Context, account models.OauthAccountEntity) (models.OauthAccountEntity, *errorsApp.DbError)
+	GetOauthAccountByProvider(ctx context.Context, provider string, providerUserId string) (models.OauthAccountEntity, *errorsApp.DbError)
+	NewUserWithOauthAccount(ctx context.Context, user models.UserEntity, account models.OauthAccountEntity) (models.UserEntity, *errorsApp.DbError)
+	GetOauthAccountsByUserId(ctx context.Context, id int64) ([]models.OauthAccountEntity, *errorsApp.DbError)
+	DeleteOauthAccount(ctx context.Context, id int64, userId int64) *errorsApp.DbError
+	SetUserTotpSecret(ctx context.Context, id int64, secret string) *errorsApp.DbError
+	EnableUserTotp(ctx context.Context, id int64, codeHashes []string) *errorsApp.DbError
+	DisableUserTotp(ctx context.Context, id int64) *errorsApp.DbError
+	UseRecoveryCode(ctx context.Context, id int64, codeHash string) (bool, *errorsApp.DbError)
+	CountRecoveryCodes(ctx context.Context, id int64) (int64, *errorsApp.DbError)
+	NewWebauthnCredential(ctx context.Context, credential models.WebauthnCredentialEntity) (models.WebauthnCredentialEntity, *errorsApp.DbError)
+	GetWebauthnCredentialsByUserId(ctx context.Context, id int64) ([]models.WebauthnCredentialEntity, *errorsApp.DbError)
+	GetWebauthnCredentialByCredentialId(ctx context.Context, credentialId []byte) (models.WebauthnCredentialEntity, *errorsApp.DbError)
+	UpdateWebauthnCredentialUsage(ctx context.Context, id int64, signCount int64, backupState bool) *errorsApp.DbError
+	DeleteWebauthnCredential(ctx context.Context, id int64, userId int64) *errorsApp.DbError
+	RequestUserDeletion(ctx context.Context, id int64) *errorsApp.DbError
+	CancelUserDeletion(ctx context.Context, id int64) *errorsApp.DbError
+	NewAuthEvent(ctx context.Context, event models.AuthEventEntity) *errorsApp.DbError
+	ListAuthEvents(ctx context.Context, filter models.AuthEventsFilter) ([]models.AuthEventEntity, int64, *errorsApp.DbError)
 }
 
 type sessionStorage interface {
@@ -42,29 +68,65 @@ type sessionStorage interface {
 	GetSessionByJti(ctx context.Context, jti string) (cache.SessionData, *errorsApp.DbError)
 	GetSessionsByUserId(ctx context.Context, userId int64) ([]cache.SessionData, *errorsApp.DbError)
 	DeleteSessionByJti(ctx context.Context, jti string) *errorsApp.DbError
+	DeleteSessionsByUserId(ctx context.Context, userId int64) *errorsApp.DbError
+	DeleteSessionsByUserIdExcept(ctx context.Context, userId int64, keepJti string) (int64, *errorsApp.DbError)
+	SaveOauthState(ctx context.Context, data cache.OauthStateData, ttlMinutes int) *errorsApp.DbError
+	PopOauthState(ctx context.Context, state string) (cache.OauthStateData, *errorsApp.DbError)
+	RotateSession(ctx context.Context, oldJti string, newJti string, data cache.SessionData, ttlHours int) *errorsApp.DbError
+	GetRetiredJtiFamily(ctx context.Context, jti string) (string, *errorsApp.DbError)
+	DeleteSessionFamily(ctx context.Context, familyId string, userId int64) *errorsApp.DbError
+	SaveMfaChallenge(ctx context.Context, data cache.MfaChallengeData, ttl time.Duration) *errorsApp.DbError
+	GetMfaChallenge(ctx context.Context, token string) (cache.MfaChallengeData, *errorsApp.DbError)
+	DeleteMfaChallenge(ctx context.Context, token string) (bool, *errorsApp.DbError)
+	SaveWebauthnChallenge(ctx context.Context, data cache.WebauthnChallengeData, ttl time.Duration) *errorsApp.DbError
+	PopWebauthnChallenge(ctx context.Context, token string) (cache.WebauthnChallengeData, *errorsApp.DbError)
+	ClaimDataExport(ctx context.Context, userId int64, id string, ttl time.Duration) (cache.DataExportClaim, *errorsApp.DbError)
+	ReleaseDataExport(ctx context.Context, userId int64, id string) *errorsApp.DbError
+	SaveDataExport(ctx context.Context, data cache.DataExportData) *errorsApp.DbError
+	GetDataExport(ctx context.Context, id string) (cache.DataExportData, *errorsApp.DbError)
+	SaveDataExportFile(ctx context.Context, id string, file []byte, expiresAt time.Time) *errorsApp.DbError
+	PopDataExportFile(ctx context.Context, id string) ([]byte, *errorsApp.DbError)
 }
 
 type otpStorage interface {
 	SaveOtp(ctx context.Context, data cache.OtpData, ttlMinutes int) *errorsApp.DbError
 	DeleteOtp(ctx context.Context, address string, typeM string) *errorsApp.DbError
 	GetOtp(ctx context.Context, address string, typeM string) (cache.OtpData, *errorsApp.DbError)
+	IncrOtpAttempts(ctx context.Context, address string, typeM string) (int64, *errorsApp.DbError)
+	ClaimOtpCooldown(ctx context.Context, address string, cooldown time.Duration) (time.Duration, *errorsApp.DbError)
+	HitOtpQuotas(ctx context.Context, quotas []cache.OtpQuota) (cache.OtpQuotaResult, *errorsApp.DbError)
+	UseTotpStep(ctx context.Context, userId int64, step int64, ttl time.Duration) (bool, *errorsApp.DbError)
+}
+
+type limitStorage interface {
+	GetLoginLock(ctx context.Context, key string) (time.Duration, *errorsApp.DbError)
+	AddLoginFailure(ctx context.Context, key string, window time.Duration, maxAttempts int, lockBase time.Duration, lockMax time.Duration) (time.Duration, *errorsApp.DbError)
+	ResetLoginFailures(ctx context.Context, keys ...string) *errorsApp.DbError
 }
 
 func NewAuthService(log *slog.Logger,
 	authStorage authStorage,
 	sessionStorage sessionStorage,
 	otpStorage otpStorage,
+	limitStorage limitStorage,
+	jwtKeys *lib.JWTKeySet,
+	geoIP *lib.GeoIP,
 	cfg *config.Config) *AuthService {
 	return &AuthService{
 		log:            log,
 		authStorage:    authStorage,
 		sessionStorage: sessionStorage,
 		otpStorage:     otpStorage,
+		limitStorage:   limitStorage,
+		oauthRegistry:  NewOauthRegistry(cfg),
+		webauthn:       newWebAuthn(cfg, log),
+		jwtKeys:        jwtKeys,
+		geoIP:          geoIP,
 		cfg:            cfg,
 	}
 }
 
-func (s *AuthService) Register(ctx context.Context, user dto.AuthRegisterRequest) (dto.AuthRegisterResponse, error) {
+func (s *AuthService) Register(ctx context.Context, user dto.AuthRegisterRequest, ip string) (dto.AuthRegisterResponse, error) {
 	op := "services.Register"
 	log := s.log.With(slog.String("op", op))
 
@@ -105,17 +167,18 @@ func (s *AuthService) Register(ctx context.Context, user dto.AuthRegisterRequest
 		responseSend, errSendVerify := s.SendVerify(ctx, dto.AuthSendVerifyRequest{
 			Type:    "phone",
 			Address: user.Phone_number.String,
-		})
+		}, ip)
 		if errSendVerify != nil {
 			log.Warn("error send verify", slog.String("err", errSendVerify.Error()))
 			return response, errSendVerify
 		}
 		response.OtpExpiresAt = responseSend.OtpExpiresAt
+		response.ResendAvailableAt = responseSend.ResendAvailableAt
 	case "email":
 		responseSend, errSendVerify := s.SendVerify(ctx, dto.AuthSendVerifyRequest{
 			Type:    "email",
 			Address: user.Email.String,
-		})
+		}, ip)
 		if errSendVerify != nil {
 			if errSendVerify == errorsApp.ErrAlreadyOtp.Error {
 				return response, errorsApp.ErrAlreadyOtp.Error
@@ -124,6 +187,7 @@ func (s *AuthService) Register(ctx context.Context, user dto.AuthRegisterRequest
 			return response, errSendVerify
 		}
 		response.OtpExpiresAt = responseSend.OtpExpiresAt
+		response.ResendAvailableAt = responseSend.ResendAvailableAt
 	default:
 		log.Warn("invalid confirm type", slog.String("confirm_type", user.ConfirmType))
 		return response, errorsApp.ErrBadRequest.Error
@@ -138,6 +202,15 @@ func (s *AuthService) Login(ctx context.Context, user dto.AuthLoginRequest, ip s
 	dto := dto.AuthLoginResponse{}
 	userEntity := models.UserEntity{}
 
+	identity := user.Email.String
+	if user.Phone_number.Valid {
+		identity = user.Phone_number.String
+	}
+	if err := s.checkLoginLock(ctx, identity, ip); err != nil {
+		s.audit(ctx, authEvent{Type: models.AuthEventLogin, Identity: identity, IP: ip, UserAgent: user_agent, Err: err})
+		return dto, err
+	}
+
 	// проверяем наличие пользователя по email
 	if user.Email.Valid {
 		//log.Debug("login with email", slog.String("email", user.Email.String))
@@ -145,6 +218,8 @@ func (s *AuthService) Login(ctx context.Context, user dto.AuthLoginRequest, ip s
 		if dbError != nil {
 			if dbError.Message == "user not found" {
 				log.Warn("user not found with email", slog.String("email", user.Email.String))
+				s.loginFailed(ctx, identity, ip)
+				s.audit(ctx, authEvent{Type: models.AuthEventLogin, Identity: identity, IP: ip, UserAgent: user_agent, Err: errorsApp.ErrUserNotFound.Error})
 				return dto, errorsApp.ErrAuthentication.Error
 			}
 
@@ -154,6 +229,7 @@ func (s *AuthService) Login(ctx context.Context, user dto.AuthLoginRequest, ip s
 		// проверяем наличие верификацию пользователя по email
 		if !userEntityByEmail.Email_verified_at.Valid {
 			log.Warn("user not verified", slog.String("name", userEntityByEmail.Name))
+			s.audit(ctx, authEvent{Type: models.AuthEventLogin, UserId: userEntityByEmail.Id, Identity: identity, IP: ip, UserAgent: user_agent, Err: errorsApp.ErrVerifyNotFound.Error})
 			return dto, errorsApp.ErrVerifyNotFound.Error
 		}
 		userEntity = userEntityByEmail
@@ -166,6 +242,8 @@ func (s *AuthService) Login(ctx context.Context, user dto.AuthLoginRequest, ip s
 		if dbError != nil {
 			if dbError.Message == "user not found" {
 				log.Warn("user not found with phone number", slog.String("phone_number", user.Phone_number.String))
+				s.loginFailed(ctx, identity, ip)
+				s.audit(ctx, authEvent{Type: models.AuthEventLogin, Identity: identity, IP: ip, UserAgent: user_agent, Err: errorsApp.ErrUserNotFound.Error})
 				return dto, errorsApp.ErrAuthentication.Error
 			}
 			log.Warn("error get user by phone number", slog.String("err", dbError.Message))
@@ -174,6 +252,7 @@ func (s *AuthService) Login(ctx context.Context, user dto.AuthLoginRequest, ip s
 		// проверяем наличие верификацию пользователя по email
 		if !userEntityByPhone.Email_verified_at.Valid {
 			log.Warn("user not verified", slog.String("name", userEntityByPhone.Name))
+			s.audit(ctx, authEvent{Type: models.AuthEventLogin, UserId: userEntityByPhone.Id, Identity: identity, IP: ip, UserAgent: user_agent, Err: errorsApp.ErrVerifyNotFound.Error})
 			return dto, errorsApp.ErrVerifyNotFound.Error
 		}
 		userEntity = userEntityByPhone
@@ -182,6 +261,74 @@ func (s *AuthService) Login(ctx context.Context, user dto.AuthLoginRequest, ip s
 	err := lib.CheckPassword(userEntity.Password_hash.String, user.Password)
 	if err != nil {
 		log.Warn("invalid login or password", slog.String("err", err.Error()))
+		s.loginFailed(ctx, identity, ip)
+		s.audit(ctx, authEvent{Type: models.AuthEventLogin, UserId: userEntity.Id, Identity: identity, IP: ip, UserAgent: user_agent, Err: errorsApp.ErrAuthentication.Error})
+		return dto, errorsApp.ErrAuthentication.Error
+	}
+
+	// счетчик по IP не сбрасываем, иначе один свой аккаунт позволит перебирать чужие
+	if dbError := s.limitStorage.ResetLoginFailures(ctx, "user:"+identity); dbError != nil {
+		log.Warn("error reset login failures", slog.String("err", dbError.Message))
+	}
+
+	return s.completeLogin(ctx, userEntity, ip, user_agent)
+}
+
+// checkLoginLock возвращает RetryError, если вход заблокирован по email/телефону или по IP
+func (s *AuthService) checkLoginLock(ctx context.Context, identity string, ip string) error {
+	op := "services.checkLoginLock"
+	log := s.log.With(slog.String("op", op))
+
+	var retryAfter time.Duration
+	for _, key := range []string{"user:" + identity, "ip:" + ip} {
+		lock, dbError := s.limitStorage.GetLoginLock(ctx, key)
+		if dbError != nil {
+			log.Error("error get login lock", slog.String("err", dbError.Message))
+			return errorsApp.ErrInternalError.Error
+		}
+		retryAfter = max(retryAfter, lock)
+	}
+	if retryAfter > 0 {
+		log.Warn("login locked", slog.String("identity", identity), slog.String("ip", ip), slog.Duration("retry_after", retryAfter))
+		return &errorsApp.RetryError{Err: errorsApp.ErrTooManyAttempts, RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// loginFailed учитывает неудачную попытку входа, ошибки Redis только логируются
+func (s *AuthService) loginFailed(ctx context.Context, identity string, ip string) {
+	op := "services.loginFailed"
+	log := s.log.With(slog.String("op", op))
+
+	_, dbError := s.limitStorage.AddLoginFailure(ctx, "user:"+identity, s.cfg.AUTH_LOGIN_ATTEMPTS_WINDOW,
+		s.cfg.AUTH_LOGIN_MAX_ATTEMPTS, s.cfg.AUTH_LOGIN_LOCK_BASE, s.cfg.AUTH_LOGIN_LOCK_MAX)
+	if dbError != nil {
+		log.Error("error add login failure", slog.String("err", dbError.Message))
+	}
+	_, dbError = s.limitStorage.AddLoginFailure(ctx, "ip:"+ip, s.cfg.AUTH_LOGIN_ATTEMPTS_WINDOW,
+		s.cfg.AUTH_LOGIN_IP_MAX_ATTEMPTS, s.cfg.AUTH_LOGIN_LOCK_BASE, s.cfg.AUTH_LOGIN_LOCK_MAX)
+	if dbError != nil {
+		log.Error("error add login failure", slog.String("err", dbError.Message))
+	}
+}
+
+// issueTokens выдает пару access/refresh токенов и сохраняет сессию в Redis,
+// общий путь для всех способов входа
+func (s *AuthService) issueTokens(ctx context.Context, userEntity models.UserEntity, ip string, user_agent string) (dto.AuthLoginResponse, error) {
+	op := "services.issueTokens"
+	log := s.log.With(slog.String("op", op))
+
+	dto := dto.AuthLoginResponse{}
+
+	// заблокированный администратором пользователь не получает токены ни одним способом входа
+	if userEntity.Blocked_at.Valid {
+		log.Warn("user is blocked", slog.Int64("user_id", userEntity.Id))
+		s.audit(ctx, authEvent{Type: models.AuthEventLogin, UserId: userEntity.Id, IP: ip, UserAgent: user_agent, Err: errorsApp.ErrUserBlocked.Error})
+		return dto, errorsApp.ErrUserBlocked.Error
+	}
+	// обезличенный аккаунт сюда попасть не должен: способов входа у него не осталось
+	if userEntity.Deleted_at.Valid {
+		log.Warn("user is deleted", slog.Int64("user_id", userEntity.Id))
 		return dto, errorsApp.ErrAuthentication.Error
 	}
 
@@ -190,6 +337,13 @@ func (s *AuthService) Login(ctx context.Context, user dto.AuthLoginRequest, ip s
 		log.Error("", slog.String("err", errCopy.Error()))
 		return dto, errCopy
 	}
+
+	if userEntity.Deletion_requested_at.Valid {
+		if err := s.restoreAccount(ctx, userEntity); err != nil {
+			return dto, err
+		}
+		dto.AccountRestored = true
+	}
 	role, dbError := s.authStorage.GetRoleById(ctx, userEntity.Role_id)
 
 	if dbError != nil {
@@ -200,13 +354,14 @@ func (s *AuthService) Login(ctx context.Context, user dto.AuthLoginRequest, ip s
 
 	jti := uuid.New().String()
 
+	var err error
 	dto.AccessToken, err = lib.CreateJWT(lib.JWTClaims{
 		UserId:   userEntity.Id,
 		UserName: userEntity.Name,
 		RoleId:   userEntity.Role_id,
 		Jti:      jti,
 		Iss:      s.cfg.SERVICE_NAME,
-	}, s.cfg.AUTH_SECRET_KEY,
+	}, s.jwtKeys,
 		time.Duration(s.cfg.AUTH_ACCESS_TOKEN_EXP_MINUTES)*time.Minute,
 		"access")
 
@@ -221,7 +376,7 @@ func (s *AuthService) Login(ctx context.Context, user dto.AuthLoginRequest, ip s
 		RoleId:   userEntity.Role_id,
 		Jti:      jti,
 		Iss:      s.cfg.SERVICE_NAME,
-	}, s.cfg.AUTH_SECRET_KEY,
+	}, s.jwtKeys,
 		time.Duration(s.cfg.AUTH_REFRESH_TOKEN_EXP_HOURS)*time.Hour,
 		"refresh")
 
@@ -230,17 +385,24 @@ func (s *AuthService) Login(ctx context.Context, user dto.AuthLoginRequest, ip s
 		return dto, err
 	}
 
-	err2 := s.sessionStorage.SaveSession(ctx, jti, cache.SessionData{
-		Jti:       jti,
-		UserID:    userEntity.Id,
-		RoleID:    userEntity.Role_id,
-		UserAgent: user_agent,
-		IP:        ip,
-	}, s.cfg.AUTH_REFRESH_TOKEN_EXP_HOURS)
+	session := cache.SessionData{
+		Jti:      jti,
+		UserID:   userEntity.Id,
+		RoleID:   userEntity.Role_id,
+		FamilyID: jti, // каждый вход начинает новое семейство refresh-токенов
+	}
+	s.setSessionClient(&session, ip, user_agent)
+	err2 := s.sessionStorage.SaveSession(ctx, jti, session, s.cfg.AUTH_REFRESH_TOKEN_EXP_HOURS)
 	if err2 != nil {
 		log.Error("error save session", slog.String("err", err2.Message))
 		return dto, err2.Error
 	}
+	// успешный вход любым способом пишется здесь, неудачи - там, где проверяются учетные данные
+	newDevice := s.isNewDevice(ctx, userEntity.Id, user_agent)
+	s.audit(ctx, authEvent{Type: models.AuthEventLogin, UserId: userEntity.Id, IP: ip, UserAgent: user_agent})
+	if newDevice {
+		s.securityNotify(userEntity, securityNoticeNewDevice, ip, user_agent, "")
+	}
 
 	return dto, nil
 }
@@ -250,7 +412,7 @@ func (s *AuthService) Hello(ctx context.Context, token string) (dto.AuthHelloRes
 	log := s.log.With(slog.String("op", op))
 
 	dto := dto.AuthHelloResponse{}
-	userId, err := lib.GetUserIdFromAccessToken(token, s.cfg.AUTH_SECRET_KEY, s.cfg.SERVICE_NAME)
+	userId, err := lib.GetUserIdFromAccessToken(token, s.jwtKeys, s.cfg.SERVICE_NAME)
 	if err != nil || userId == 0 {
 		log.Warn("error get user id from token", slog.String("err", err.Error()))
 		return dto, err
@@ -278,14 +440,15 @@ func (s *AuthService) Hello(ctx context.Context, token string) (dto.AuthHelloRes
 	return dto, nil
 }
 
-func (s *AuthService) Refresh(ctx context.Context, token string) (dto.AuthLoginResponse, error) {
+func (s *AuthService) Refresh(ctx context.Context, token string, ip string, user_agent string) (dto.AuthLoginResponse, error) {
 	op := "services.Refresh"
 	log := s.log.With(slog.String("op", op))
 
 	dto := dto.AuthLoginResponse{}
-	claims, err := lib.GetClaimsFromRefreshToken(token, s.cfg.AUTH_SECRET_KEY, s.cfg.SERVICE_NAME)
+	claims, err := lib.GetClaimsFromRefreshToken(token, s.jwtKeys, s.cfg.SERVICE_NAME)
 	if err != nil || claims.UserId == 0 {
 		log.Warn("error get user id from token", slog.String("err", err.Error()))
+		s.audit(ctx, authEvent{Type: models.AuthEventRefresh, IP: ip, UserAgent: user_agent, Err: err})
 		return dto, err
 	}
 
@@ -293,39 +456,65 @@ func (s *AuthService) Refresh(ctx context.Context, token string) (dto.AuthLoginR
 	data, err2 := s.sessionStorage.GetSessionByJti(ctx, claims.Jti)
 	if err2 != nil {
 		log.Warn("error get session by jti", slog.String("err", err2.Message))
-		return dto, errorsApp.ErrSessionNotFound.Error
+		err = s.checkRefreshReuse(ctx, claims.Jti, claims.UserId)
+		s.audit(ctx, authEvent{Type: models.AuthEventRefresh, UserId: claims.UserId, IP: ip, UserAgent: user_agent, Err: err})
+		return dto, err
 	}
 	if data.UserID != claims.UserId {
 		log.Warn("refresh-token user_id not match session user_id", slog.Int64("user_id", data.UserID), slog.Int64("claims_user_id", claims.UserId))
+		s.audit(ctx, authEvent{Type: models.AuthEventRefresh, UserId: claims.UserId, IP: ip, UserAgent: user_agent, Err: errorsApp.ErrSessionNotFound.Error})
 		return dto, errorsApp.ErrSessionNotFound.Error
 	}
+	// токены собираются из текущей строки users, а не из старого токена: блокировка, удаление
+	// и смена роли действуют, даже если сессии по какой-то причине не были удалены
+	userEntity, err := s.refreshUser(ctx, claims.UserId, claims.Jti)
+	if err != nil {
+		s.audit(ctx, authEvent{Type: models.AuthEventRefresh, UserId: claims.UserId, IP: ip, UserAgent: user_agent, Err: err})
+		return dto, err
+	}
+	role, dbError := s.authStorage.GetRoleById(ctx, userEntity.Role_id)
+	if dbError != nil {
+		log.Warn("error get role by id", slog.String("err", dbError.Message))
+		return dto, errorsApp.ErrInternalError.Error
+	}
+	data.RoleID = userEntity.Role_id
 
-	// удаляем старую сессию
-	err3 := s.sessionStorage.DeleteSessionByJti(ctx, "jti:"+claims.Jti)
-	if err3 != nil {
-		log.Warn("error delete session by jti", slog.String("err", err3.Message))
-		// ничего не делаем если не удалось удалить сессию
+	// сессии, созданные до появления семейств
+	if data.FamilyID == "" {
+		data.FamilyID = data.Jti
 	}
+	// в сессии показываем, откуда ей пользовались последний раз
+	s.setSessionClient(&data, ip, user_agent)
 
-	// сохраняем новую сессию с другим jti
+	// заменяем старую сессию новой с другим jti, старый jti запоминаем как выведенный
 	newJti := uuid.NewString()
-	err4 := s.sessionStorage.SaveSession(ctx, newJti, data, s.cfg.AUTH_REFRESH_TOKEN_EXP_HOURS)
-	if err4 != nil {
-		log.Error("error save session by jti", slog.String("err", err4.Message))
+	err3 := s.sessionStorage.RotateSession(ctx, claims.Jti, newJti, data, s.cfg.AUTH_REFRESH_TOKEN_EXP_HOURS)
+	if err3 != nil {
+		if err3.Type == "not_found" {
+			// параллельный refresh тем же токеном успел раньше
+			log.Warn("session rotated concurrently", slog.String("jti", claims.Jti))
+			err = s.checkRefreshReuse(ctx, claims.Jti, claims.UserId)
+			s.audit(ctx, authEvent{Type: models.AuthEventRefresh, UserId: claims.UserId, IP: ip, UserAgent: user_agent, Err: err})
+			return dto, err
+		}
+		log.Error("error rotate session", slog.String("err", err3.Message))
 		return dto, errorsApp.ErrInternalError.Error
 	}
 
-	dto.Id = claims.UserId
-	dto.Name = claims.UserName
-	dto.Role_name = "id:" + fmt.Sprint(claims.RoleId)
+	errCopy := copier.Copy(&dto, &userEntity)
+	if errCopy != nil {
+		log.Error("", slog.String("err", errCopy.Error()))
+		return dto, errCopy
+	}
+	dto.Role_name = role.Name
 
 	dto.AccessToken, err = lib.CreateJWT(lib.JWTClaims{
-		UserId:   claims.UserId,
-		UserName: claims.UserName,
+		UserId:   userEntity.Id,
+		UserName: userEntity.Name,
 		Jti:      newJti,
-		RoleId:   claims.RoleId,
+		RoleId:   userEntity.Role_id,
 		Iss:      s.cfg.SERVICE_NAME,
-	}, s.cfg.AUTH_SECRET_KEY,
+	}, s.jwtKeys,
 		time.Duration(s.cfg.AUTH_ACCESS_TOKEN_EXP_MINUTES)*time.Minute,
 		"access")
 	if err != nil {
@@ -334,12 +523,12 @@ func (s *AuthService) Refresh(ctx context.Context, token string) (dto.AuthLoginR
 	}
 
 	dto.RefreshToken, err = lib.CreateJWT(lib.JWTClaims{
-		UserId:   claims.UserId,
-		UserName: claims.UserName,
-		RoleId:   claims.RoleId,
+		UserId:   userEntity.Id,
+		UserName: userEntity.Name,
+		RoleId:   userEntity.Role_id,
 		Jti:      newJti,
 		Iss:      s.cfg.SERVICE_NAME,
-	}, s.cfg.AUTH_SECRET_KEY,
+	}, s.jwtKeys,
 		time.Duration(s.cfg.AUTH_REFRESH_TOKEN_EXP_HOURS)*time.Hour,
 		"refresh")
 	if err != nil {
@@ -347,9 +536,74 @@ func (s *AuthService) Refresh(ctx context.Context, token string) (dto.AuthLoginR
 		return dto, err
 	}
 
+	s.audit(ctx, authEvent{Type: models.AuthEventRefresh, UserId: claims.UserId, IP: ip, UserAgent: user_agent})
 	return dto, nil
 }
 
+// refreshUser загружает пользователя для refresh. Заблокированный, удаленный или ожидающий удаления
+// пользователь refresh не проходит, его сессия удаляется. Восстановить аккаунт можно только входом
+func (s *AuthService) refreshUser(ctx context.Context, userId int64, jti string) (models.UserEntity, error) {
+	op := "services.refreshUser"
+	log := s.log.With(slog.String("op", op))
+
+	userEntity, dbError := s.authStorage.GetUserById(ctx, userId)
+	if dbError != nil {
+		if dbError.Type != "not_found" {
+			log.Error("error get user by id", slog.String("err", dbError.Message))
+			return userEntity, errorsApp.ErrInternalError.Error
+		}
+		log.Warn("user not found", slog.Int64("user_id", userId))
+	}
+
+	var err error
+	switch {
+	case dbError != nil:
+		err = errorsApp.ErrAuthentication.Error
+	case userEntity.Blocked_at.Valid:
+		log.Warn("user is blocked", slog.Int64("user_id", userId))
+		err = errorsApp.ErrUserBlocked.Error
+	case userEntity.Deleted_at.Valid || userEntity.Deletion_requested_at.Valid:
+		log.Warn("user is deleted", slog.Int64("user_id", userId))
+		err = errorsApp.ErrAuthentication.Error
+	default:
+		return userEntity, nil
+	}
+
+	if dbError := s.sessionStorage.DeleteSessionByJti(ctx, "jti:"+jti); dbError != nil {
+		log.Warn("error delete session by jti", slog.String("err", dbError.Message))
+	}
+	return userEntity, err
+}
+
+// checkRefreshReuse вызывается, когда refresh-токена нет среди живых сессий.
+// Если jti уже был ротирован - токен украден или переигран: отзываем все семейство
+func (s *AuthService) checkRefreshReuse(ctx context.Context, jti string, userId int64) error {
+	op := "services.checkRefreshReuse"
+	log := s.log.With(slog.String("op", op))
+
+	familyId, dbError := s.sessionStorage.GetRetiredJtiFamily(ctx, jti)
+	if dbError != nil {
+		log.Error("error get retired jti", slog.String("err", dbError.Message))
+		return errorsApp.ErrInternalError.Error
+	}
+	if familyId == "" {
+		return errorsApp.ErrSessionNotFound.Error
+	}
+
+	log.Warn("security event: refresh token reuse, revoke session family",
+		slog.String("event", "refresh_token_reuse"),
+		slog.Int64("user_id", userId),
+		slog.String("jti", jti),
+		slog.String("family_id", familyId))
+
+	dbError = s.sessionStorage.DeleteSessionFamily(ctx, familyId, userId)
+	if dbError != nil {
+		log.Error("error delete session family", slog.String("err", dbError.Message))
+		return errorsApp.ErrInternalError.Error
+	}
+	return errorsApp.ErrRefreshTokenReused.Error
+}
+
 func (s *AuthService) Sessions(ctx context.Context, id int64) (dto.AuthSessionResponse, error) {
 	op := "services.Sessions"
 	log := s.log.With(slog.String("op", op))
@@ -370,17 +624,7 @@ func (s *AuthService) Sessions(ctx context.Context, id int64) (dto.AuthSessionRe
 	}
 
 	for _, session := range sessionData {
-		response.Sessions = append(response.Sessions, dto.AuthSession{
-			Jti:               session.Jti,
-			User_id:           session.UserID,
-			User_name:         userData.Name,
-			User_email:        userData.Email.String,
-			User_phone_number: userData.Phone_number.String,
-			Role_id:           session.RoleID,
-			User_agent:        session.UserAgent,
-			IP:                session.IP,
-			Created_at:        session.CreatedAt,
-		})
+		response.Sessions = append(response.Sessions, sessionDto(session, userData))
 	}
 
 	return response, nil
@@ -402,6 +646,7 @@ func (s *AuthService) RevokeSession(ctx fiber.Ctx, jtiString string) error {
 	}
 	if data.UserID != userId {
 		log.Warn("jti user_id not match session user_id", slog.String("err", "jti user_id not match session user_id"))
+		s.audit(ctx, authEvent{Type: models.AuthEventSessionRevoke, UserId: userId, IP: ctx.IP(), UserAgent: string(ctx.Get(fiber.HeaderUserAgent)), Err: errorsApp.ErrForbidden.Error})
 		return errorsApp.ErrForbidden.Error
 	}
 
@@ -418,5 +663,14 @@ func (s *AuthService) RevokeSession(ctx fiber.Ctx, jtiString string) error {
 		}
 	}
 
+	s.audit(ctx, authEvent{Type: models.AuthEventSessionRevoke, UserId: userId, IP: ctx.IP(), UserAgent: string(ctx.Get(fiber.HeaderUserAgent)), Reason: "jti " + jtiString})
+
+	user, dbError := s.authStorage.GetUserById(ctx, userId)
+	if dbError != nil {
+		log.Warn("error get user by id", slog.String("err", dbError.Message))
+		return nil
+	}
+	target := joinNotEmpty(deviceDescription(data.UserAgent), data.IP)
+	s.securityNotify(user, securityNoticeSessionRevoked, ctx.IP(), string(ctx.Get(fiber.HeaderUserAgent)), target)
 	return nil
 }
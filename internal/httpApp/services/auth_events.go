@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/config"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
+	"github.com/guregu/null/v6"
+	"github.com/jinzhu/copier"
+)
+
+const (
+	authEventsDefaultLimit = 20
+)
+
+// AuthEventService - чтение журнала auth_events, запись идет из AuthService.audit
+type AuthEventService struct {
+	log          *slog.Logger
+	eventStorage authEventStorage
+	cfg          *config.Config
+}
+
+type authEventStorage interface {
+	ListAuthEvents(ctx context.Context, filter models.AuthEventsFilter) ([]models.AuthEventEntity, int64, *errorsApp.DbError)
+}
+
+func NewAuthEventService(log *slog.Logger,
+	eventStorage authEventStorage,
+	cfg *config.Config) *AuthEventService {
+	return &AuthEventService{
+		log:          log,
+		eventStorage: eventStorage,
+		cfg:          cfg,
+	}
+}
+
+// UserEvents - события текущего пользователя
+func (s *AuthEventService) UserEvents(ctx context.Context, userId int64, params dto.AuthEventsQueryParams) (dto.AuthEventsResponse, error) {
+	filter := models.AuthEventsFilter{UserId: null.IntFrom(userId)}
+	return s.listEvents(ctx, filter, params.Page, params.Limit)
+}
+
+// ListEvents - события всех пользователей с фильтрами, для администратора
+func (s *AuthEventService) ListEvents(ctx context.Context, params dto.AdminAuthEventsQueryParams) (dto.AuthEventsResponse, error) {
+	filter := models.AuthEventsFilter{
+		EventType: params.Type,
+		Outcome:   params.Outcome,
+		Ip:        params.Ip,
+		Identity:  params.Identity,
+	}
+	if params.UserId != 0 {
+		filter.UserId = null.IntFrom(params.UserId)
+	}
+	if params.From != "" {
+		from, err := time.ParseInLocation(time.DateOnly, params.From, time.Local)
+		if err != nil {
+			return dto.AuthEventsResponse{}, errorsApp.ErrBadRequest.Error
+		}
+		filter.From = null.TimeFrom(from)
+	}
+	if params.To != "" {
+		to, err := time.ParseInLocation(time.DateOnly, params.To, time.Local)
+		if err != nil {
+			return dto.AuthEventsResponse{}, errorsApp.ErrBadRequest.Error
+		}
+		// дата включительно
+		filter.To = null.TimeFrom(to.AddDate(0, 0, 1))
+	}
+	return s.listEvents(ctx, filter, params.Page, params.Limit)
+}
+
+func (s *AuthEventService) listEvents(ctx context.Context, filter models.AuthEventsFilter, page int, limit int) (dto.AuthEventsResponse, error) {
+	op := "services.AuthEventService.listEvents"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthEventsResponse{Events: make([]dto.AuthEvent, 0)}
+
+	if page == 0 {
+		page = 1
+	}
+	if limit == 0 {
+		limit = authEventsDefaultLimit
+	}
+	response.Page = page
+	response.Limit = limit
+	filter.Limit = limit
+	filter.Offset = (page - 1) * limit
+
+	events, total, dbError := s.eventStorage.ListAuthEvents(ctx, filter)
+	if dbError != nil {
+		log.Error("error list auth events", slog.String("err", dbError.Message))
+		return response, errorsApp.ErrInternalError.Error
+	}
+
+	errCopy := copier.Copy(&response.Events, &events)
+	if errCopy != nil {
+		log.Error("", slog.String("err", errCopy.Error()))
+		return response, errCopy
+	}
+	response.Total = total
+
+	return response, nil
+}
@@ -1 +1,12 @@
 package services
+
+import "github.com/AlmasNurbayev/go_fiber_boilerplate/internal/config"
+
+func newGoogleProvider(cfg *config.Config) OauthProvider {
+	return newOidcProvider("google", cfg, oidcEndpoints{
+		AuthURL:     cfg.GOOGLE_AUTH_URL,
+		TokenURL:    cfg.GOOGLE_TOKEN_URL,
+		UserinfoURL: cfg.GOOGLE_USERINFO_URL,
+		Scopes:      []string{"openid", "email", "profile"},
+	}, cfg.GOOGLE_CLIENT_ID, cfg.GOOGLE_CLIENT_SECRET)
+}
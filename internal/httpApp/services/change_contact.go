@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+)
+
+// otpTypeChangeContact - код на новый адрес привязан к пользователю, чтобы его нельзя было подтвердить из чужой сессии
+func otpTypeChangeContact(userId int64) string {
+	return "change:" + strconv.FormatInt(userId, 10)
+}
+
+// otpTypeChangeContactCurrent - код на текущий подтвержденный адрес, подтверждает смену адреса без пароля
+func otpTypeChangeContactCurrent(userId int64) string {
+	return "change-current:" + strconv.FormatInt(userId, 10)
+}
+
+// ChangeContactCode отправляет код на текущий подтвержденный адрес пользователя,
+// нужен для ChangeContact тем, у кого нет пароля (oauth, вход по коду, passkey)
+func (s *AuthService) ChangeContactCode(ctx context.Context, userId int64, body dto.AuthChangeContactCodeRequest, ip string) (dto.AuthSendVerifyResponse, error) {
+	op := "services.ChangeContactCode"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthSendVerifyResponse{}
+
+	user, dbError := s.authStorage.GetUserById(ctx, userId)
+	if dbError != nil {
+		log.Warn("error get user by id", slog.String("err", dbError.Message))
+		return response, errorsApp.ErrUserNotFound.Error
+	}
+	if !addressVerified(user, body.Type) {
+		log.Warn("address not verified", slog.Int64("user_id", userId), slog.String("type", body.Type))
+		return response, errorsApp.ErrBadRequest.Error
+	}
+
+	return s.issueOtp(ctx, otpTypeChangeContactCurrent(userId), body.Type, userAddress(user, body.Type), ip, "Confirm address change for "+s.cfg.SERVICE_NAME, "Your code to change the account address is: ")
+}
+
+// ChangeContact после подтверждения паролем или кодом с текущего адреса отправляет код на новый email/телефон.
+// Одного access-токена недостаточно, иначе украденный токен позволяет увести аккаунт сменой адреса.
+// Текущий адрес не меняется до ConfirmChangeContact
+func (s *AuthService) ChangeContact(ctx context.Context, userId int64, body dto.AuthChangeContactRequest, ip string) (dto.AuthSendVerifyResponse, error) {
+	op := "services.ChangeContact"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthSendVerifyResponse{}
+
+	user, dbError := s.authStorage.GetUserById(ctx, userId)
+	if dbError != nil {
+		log.Warn("error get user by id", slog.String("err", dbError.Message))
+		return response, errorsApp.ErrUserNotFound.Error
+	}
+	current := user.Email.String
+	if body.Type == "phone" {
+		current = user.Phone_number.String
+	}
+	if current == body.Address {
+		log.Warn("address not changed", slog.Int64("user_id", userId))
+		return response, errorsApp.ErrBadRequest.Error
+	}
+
+	if err := s.reauthenticate(ctx, user, otpTypeChangeContactCurrent(userId), body.Password, body.CurrentType, body.CurrentCode); err != nil {
+		return response, err
+	}
+
+	// адрес занят другим пользователем - сразу 409, окончательно проверит ограничение уникальности.
+	// Проверка после reauthenticate, иначе по одному access-токену можно перебирать зарегистрированные адреса
+	_, err := s.getUserByAddress(ctx, body.Type, body.Address)
+	if err == nil {
+		log.Warn("address already in use", slog.Int64("user_id", userId), slog.String("type", body.Type))
+		return response, errorsApp.ErrContactTaken.Error
+	}
+	if err != errorsApp.ErrUserNotFound.Error {
+		return response, err
+	}
+
+	return s.issueOtp(ctx, otpTypeChangeContact(userId), body.Type, body.Address, ip, "Confirm new address for "+s.cfg.SERVICE_NAME, "Your code to confirm the new address is: ")
+}
+
+// ConfirmChangeContact проверяет код с нового адреса, меняет адрес (он сразу считается подтвержденным)
+// и уведомляет прежний адрес
+func (s *AuthService) ConfirmChangeContact(ctx context.Context, userId int64, body dto.AuthConfirmChangeContactRequest) error {
+	op := "services.ConfirmChangeContact"
+	log := s.log.With(slog.String("op", op))
+
+	otpType := otpTypeChangeContact(userId)
+	if err := s.checkOtp(ctx, otpType, body.Address, body.Code); err != nil {
+		return err
+	}
+
+	user, dbError := s.authStorage.GetUserById(ctx, userId)
+	if dbError != nil {
+		log.Warn("error get user by id", slog.String("err", dbError.Message))
+		return errorsApp.ErrUserNotFound.Error
+	}
+
+	previous := user.Email.String
+	if body.Type == "phone" {
+		previous = user.Phone_number.String
+		dbError = s.authStorage.UpdateUserPhoneNumber(ctx, userId, body.Address)
+	} else {
+		dbError = s.authStorage.UpdateUserEmail(ctx, userId, body.Address)
+	}
+	if dbError != nil {
+		if dbError.Type == "unique_violation" {
+			log.Warn("address already in use", slog.Int64("user_id", userId), slog.String("type", body.Type))
+			return errorsApp.ErrContactTaken.Error
+		}
+		log.Error("error update user contact", slog.String("err", dbError.Message))
+		return errorsApp.ErrInternalError.Error
+	}
+
+	// код одноразовый
+	s.invalidateOtp(ctx, body.Address, otpType)
+
+	log.Info("user contact changed", slog.Int64("user_id", userId), slog.String("type", body.Type))
+
+	s.notify(body.Type, previous, "Your "+body.Type+" was changed",
+		"The "+body.Type+" of your "+s.cfg.SERVICE_NAME+" account was changed to "+body.Address+
+			". If it was not you, contact support.")
+	return nil
+}
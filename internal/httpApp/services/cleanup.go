@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/config"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+)
+
+const (
+	CleanupUnverifiedDelete  = "delete"
+	CleanupUnverifiedArchive = "archive" // обезличить, как удаленный аккаунт
+	CleanupUnverifiedOff     = "off"
+)
+
+// CleanupService - периодическая очистка: обезличивание аккаунтов после льготного периода удаления,
+// неподтвержденные пользователи и индексы сессий в Redis
+type CleanupService struct {
+	log            *slog.Logger
+	cleanupStorage cleanupStorage
+	sessionStorage cleanupSessionStorage
+	cfg            *config.Config
+}
+
+type cleanupStorage interface {
+	GetUserIdsForPurge(ctx context.Context, before time.Time) ([]int64, *errorsApp.DbError)
+	GetUnverifiedUserIds(ctx context.Context, before time.Time) ([]int64, *errorsApp.DbError)
+	GetActiveUserIds(ctx context.Context, ids []int64) ([]int64, *errorsApp.DbError)
+	AnonymizeDeletedUser(ctx context.Context, id int64, before time.Time) *errorsApp.DbError
+	AnonymizeUnverifiedUser(ctx context.Context, id int64, before time.Time) *errorsApp.DbError
+	DeleteUser(ctx context.Context, id int64) *errorsApp.DbError
+}
+
+type cleanupSessionStorage interface {
+	DeleteSessionsByUserId(ctx context.Context, userId int64) *errorsApp.DbError
+	GetSessionIndexUserIds(ctx context.Context) ([]int64, *errorsApp.DbError)
+	PruneSessionIndex(ctx context.Context, userId int64, dryRun bool) (int64, *errorsApp.DbError)
+}
+
+func NewCleanupService(log *slog.Logger,
+	cleanupStorage cleanupStorage,
+	sessionStorage cleanupSessionStorage,
+	cfg *config.Config) *CleanupService {
+	return &CleanupService{
+		log:            log,
+		cleanupStorage: cleanupStorage,
+		sessionStorage: sessionStorage,
+		cfg:            cfg,
+	}
+}
+
+// Run выполняет все виды очистки. Ошибки по отдельным пользователям считаются в report.Errors
+// и не останавливают остальных, ошибка возвращается, только если не удалось получить выборку
+func (s *CleanupService) Run(ctx context.Context, dryRun bool) (dto.CleanupReport, error) {
+	report := dto.CleanupReport{DryRun: dryRun, UnverifiedMode: s.cfg.CLEANUP_UNVERIFIED_MODE}
+
+	var errCount int
+	var err error
+
+	report.UnverifiedUsers, errCount, err = s.CleanUnverifiedUsers(ctx, dryRun)
+	report.Errors += errCount
+	if err != nil {
+		return report, err
+	}
+
+	report.PurgedUsers, errCount, err = s.PurgeDeletedUsers(ctx, dryRun)
+	report.Errors += errCount
+	if err != nil {
+		return report, err
+	}
+
+	// индексы чистим последними, чтобы учесть пользователей, удаленных выше
+	errCount, err = s.SweepSessionIndexes(ctx, dryRun, &report)
+	report.Errors += errCount
+	return report, err
+}
+
+// PurgeDeletedUsers обезличивает аккаунты с истекшим AUTH_ACCOUNT_DELETION_GRACE и удаляет их сессии.
+// Возвращает обработанных пользователей и число ошибок, пользователь с ошибкой попадет в следующий запуск
+func (s *CleanupService) PurgeDeletedUsers(ctx context.Context, dryRun bool) ([]int64, int, error) {
+	op := "services.CleanupService.PurgeDeletedUsers"
+	log := s.log.With(slog.String("op", op))
+
+	before := time.Now().Add(-s.cfg.AUTH_ACCOUNT_DELETION_GRACE)
+	ids, dbError := s.cleanupStorage.GetUserIdsForPurge(ctx, before)
+	if dbError != nil {
+		log.Error("error get users for purge", slog.String("err", dbError.Message))
+		return nil, 0, errorsApp.ErrInternalError.Error
+	}
+	if dryRun {
+		return ids, 0, nil
+	}
+
+	purged := make([]int64, 0, len(ids))
+	errCount := 0
+	for _, id := range ids {
+		dbError := s.cleanupStorage.AnonymizeDeletedUser(ctx, id, before)
+		if dbError != nil && dbError.Type == "not_found" {
+			// вошел и снял запрос на удаление после выборки
+			log.Info("user purge skipped", slog.Int64("user_id", id))
+			continue
+		}
+		if dbError != nil {
+			log.Error("error anonymize user", slog.Int64("user_id", id), slog.String("err", dbError.Message))
+			errCount++
+			continue
+		}
+		if dbError := s.sessionStorage.DeleteSessionsByUserId(ctx, id); dbError != nil {
+			log.Error("error delete sessions by user id", slog.Int64("user_id", id), slog.String("err", dbError.Message))
+			errCount++
+		}
+		purged = append(purged, id)
+		log.Info("user purged", slog.Int64("user_id", id))
+	}
+	return purged, errCount, nil
+}
+
+// CleanUnverifiedUsers удаляет или обезличивает (CLEANUP_UNVERIFIED_MODE) пользователей,
+// не подтвердивших ни email, ни телефон за CLEANUP_UNVERIFIED_AFTER
+func (s *CleanupService) CleanUnverifiedUsers(ctx context.Context, dryRun bool) ([]int64, int, error) {
+	op := "services.CleanupService.CleanUnverifiedUsers"
+	log := s.log.With(slog.String("op", op))
+
+	mode := s.cfg.CLEANUP_UNVERIFIED_MODE
+	if mode != CleanupUnverifiedDelete && mode != CleanupUnverifiedArchive {
+		return []int64{}, 0, nil
+	}
+
+	before := time.Now().Add(-s.cfg.CLEANUP_UNVERIFIED_AFTER)
+	ids, dbError := s.cleanupStorage.GetUnverifiedUserIds(ctx, before)
+	if dbError != nil {
+		log.Error("error get unverified users", slog.String("err", dbError.Message))
+		return nil, 0, errorsApp.ErrInternalError.Error
+	}
+	if dryRun {
+		return ids, 0, nil
+	}
+
+	cleaned := make([]int64, 0, len(ids))
+	errCount := 0
+	for _, id := range ids {
+		if mode == CleanupUnverifiedDelete {
+			dbError = s.cleanupStorage.DeleteUser(ctx, id)
+		} else {
+			dbError = s.cleanupStorage.AnonymizeUnverifiedUser(ctx, id, before)
+		}
+		if dbError != nil && dbError.Type == "not_found" && mode == CleanupUnverifiedArchive {
+			// подтвердил адрес или добавил способ входа после выборки
+			log.Info("unverified user cleanup skipped", slog.Int64("user_id", id))
+			continue
+		}
+		if dbError != nil {
+			log.Error("error clean unverified user", slog.Int64("user_id", id), slog.String("err", dbError.Message))
+			errCount++
+			continue
+		}
+		cleaned = append(cleaned, id)
+		log.Info("unverified user cleaned", slog.Int64("user_id", id), slog.String("mode", mode))
+	}
+	return cleaned, errCount, nil
+}
+
+// SweepSessionIndexes обходит индексы user_id:* в Redis: у существующих пользователей убирает ссылки
+// на истекшие сессии, у удаленных или обезличенных - удаляет все сессии
+func (s *CleanupService) SweepSessionIndexes(ctx context.Context, dryRun bool, report *dto.CleanupReport) (int, error) {
+	op := "services.CleanupService.SweepSessionIndexes"
+	log := s.log.With(slog.String("op", op))
+
+	report.OrphanedIndexes = make([]int64, 0)
+
+	ids, dbError := s.sessionStorage.GetSessionIndexUserIds(ctx)
+	if dbError != nil {
+		return 0, errorsApp.ErrInternalError.Error
+	}
+	report.SessionIndexes = len(ids)
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	active, dbError := s.cleanupStorage.GetActiveUserIds(ctx, ids)
+	if dbError != nil {
+		log.Error("error get active users", slog.String("err", dbError.Message))
+		return 0, errorsApp.ErrInternalError.Error
+	}
+
+	errCount := 0
+	for _, id := range ids {
+		if !slices.Contains(active, id) {
+			report.OrphanedIndexes = append(report.OrphanedIndexes, id)
+			if dryRun {
+				continue
+			}
+			if dbError := s.sessionStorage.DeleteSessionsByUserId(ctx, id); dbError != nil {
+				errCount++
+			}
+			continue
+		}
+		stale, dbError := s.sessionStorage.PruneSessionIndex(ctx, id, dryRun)
+		if dbError != nil {
+			errCount++
+			continue
+		}
+		report.StaleSessionRefs += stale
+	}
+	log.Info("session indexes swept", slog.Int("indexes", report.SessionIndexes),
+		slog.Int64("stale", report.StaleSessionRefs), slog.Int("orphaned", len(report.OrphanedIndexes)), slog.Bool("dry_run", dryRun))
+	return errCount, nil
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/db/cache"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
+	"github.com/google/uuid"
+	"github.com/guregu/null/v6"
+	"github.com/jinzhu/copier"
+)
+
+const (
+	dataExportPending = "pending"
+	dataExportReady   = "ready"
+	dataExportFailed  = "failed"
+
+	dataExportBuildTimeout = time.Minute
+	// журнал входов в выгрузке ограничен последними событиями
+	dataExportAuthEventsLimit = 10000
+)
+
+// StartDataExport ставит выгрузку персональных данных в работу, архив собирается в фоне,
+// готовность и ссылку на скачивание возвращает DataExportStatus.
+// Выгрузка одна на DATA_EXPORT_TTL: пока она собирается или ждет скачивания, возвращается она же
+func (s *AuthService) StartDataExport(ctx context.Context, userId int64) (dto.DataExportStatusResponse, error) {
+	op := "services.StartDataExport"
+	log := s.log.With(slog.String("op", op))
+
+	data := cache.DataExportData{
+		Id:        uuid.NewString(),
+		UserID:    userId,
+		Status:    dataExportPending,
+		CreatedAt: time.Now(),
+	}
+	data.ExpiresAt = data.CreatedAt.Add(s.cfg.DATA_EXPORT_TTL)
+
+	claim, dbError := s.sessionStorage.ClaimDataExport(ctx, userId, data.Id, s.cfg.DATA_EXPORT_TTL)
+	if dbError != nil {
+		return dto.DataExportStatusResponse{}, errorsApp.ErrInternalError.Error
+	}
+	if claim.Id != data.Id {
+		existing, dbError := s.sessionStorage.GetDataExport(ctx, claim.Id)
+		if dbError == nil && existing.UserID == userId && existing.Status != dataExportFailed {
+			log.Info("data export already started", slog.Int64("user_id", userId), slog.String("id", existing.Id))
+			return s.dataExportStatusDto(existing), nil
+		}
+		// прежняя выгрузка уже скачана
+		log.Warn("data export limit", slog.Int64("user_id", userId), slog.Duration("retry_after", claim.RetryAfter))
+		return dto.DataExportStatusResponse{}, &errorsApp.RetryError{Err: errorsApp.ErrDataExportLimit, RetryAfter: claim.RetryAfter}
+	}
+
+	dbError = s.sessionStorage.SaveDataExport(ctx, data)
+	if dbError != nil {
+		log.Error("error save data export", slog.String("err", dbError.Message))
+		s.releaseDataExport(ctx, data)
+		return dto.DataExportStatusResponse{}, errorsApp.ErrInternalError.Error
+	}
+	log.Info("data export started", slog.Int64("user_id", userId), slog.String("id", data.Id))
+
+	// контекст запроса завершится раньше сборки архива
+	go s.buildDataExport(data)
+
+	return s.dataExportStatusDto(data), nil
+}
+
+func (s *AuthService) DataExportStatus(ctx context.Context, userId int64, id string) (dto.DataExportStatusResponse, error) {
+	op := "services.DataExportStatus"
+	log := s.log.With(slog.String("op", op))
+
+	data, dbError := s.sessionStorage.GetDataExport(ctx, id)
+	if dbError != nil {
+		if dbError.Type == "not_found" {
+			return dto.DataExportStatusResponse{}, errorsApp.ErrDataExportNotFound.Error
+		}
+		return dto.DataExportStatusResponse{}, errorsApp.ErrInternalError.Error
+	}
+	if data.UserID != userId {
+		log.Warn("data export of another user", slog.Int64("user_id", userId), slog.String("id", id))
+		return dto.DataExportStatusResponse{}, errorsApp.ErrDataExportNotFound.Error
+	}
+	return s.dataExportStatusDto(data), nil
+}
+
+// DownloadDataExport проверяет подпись ссылки и отдает архив, после скачивания он удаляется
+func (s *AuthService) DownloadDataExport(ctx context.Context, id string, expires string, signature string) ([]byte, error) {
+	op := "services.DownloadDataExport"
+	log := s.log.With(slog.String("op", op))
+
+	expiresUnix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !lib.VerifySignedString(id+":"+expires, signature, s.cfg.AUTH_SECRET_KEY) {
+		log.Warn("invalid data export signature", slog.String("id", id))
+		return nil, errorsApp.ErrDataExportNotFound.Error
+	}
+	if time.Now().Unix() > expiresUnix {
+		log.Warn("data export link expired", slog.String("id", id))
+		return nil, errorsApp.ErrDataExportNotFound.Error
+	}
+
+	file, dbError := s.sessionStorage.PopDataExportFile(ctx, id)
+	if dbError != nil {
+		if dbError.Type == "not_found" {
+			return nil, errorsApp.ErrDataExportNotFound.Error
+		}
+		return nil, errorsApp.ErrInternalError.Error
+	}
+	log.Info("data export downloaded", slog.String("id", id))
+	return file, nil
+}
+
+func (s *AuthService) buildDataExport(data cache.DataExportData) {
+	op := "services.buildDataExport"
+	log := s.log.With(slog.String("op", op), slog.Int64("user_id", data.UserID), slog.String("id", data.Id))
+
+	ctx, cancel := context.WithTimeout(context.Background(), dataExportBuildTimeout)
+	defer cancel()
+
+	data.Status = dataExportReady
+	file, err := s.dataExportArchive(ctx, data.UserID)
+	if err == nil {
+		if dbError := s.sessionStorage.SaveDataExportFile(ctx, data.Id, file, data.ExpiresAt); dbError != nil {
+			err = dbError.Error
+		}
+	}
+	if err != nil {
+		log.Error("error build data export", slog.String("err", err.Error()))
+		data.Status = dataExportFailed
+		// после ошибки можно сразу запросить новую выгрузку
+		s.releaseDataExport(ctx, data)
+	}
+
+	if dbError := s.sessionStorage.SaveDataExport(ctx, data); dbError != nil {
+		log.Error("error save data export", slog.String("err", dbError.Message))
+		return
+	}
+	log.Info("data export finished", slog.String("status", data.Status), slog.Int("size", len(file)))
+}
+
+func (s *AuthService) releaseDataExport(ctx context.Context, data cache.DataExportData) {
+	if dbError := s.sessionStorage.ReleaseDataExport(ctx, data.UserID, data.Id); dbError != nil {
+		s.log.Warn("error release data export", slog.String("op", "services.releaseDataExport"), slog.String("err", dbError.Message))
+	}
+}
+
+// dataExportArchive собирает все данные пользователя в export.json внутри zip
+func (s *AuthService) dataExportArchive(ctx context.Context, userId int64) ([]byte, error) {
+	export := dto.DataExport{
+		ExportedAt: time.Now(),
+		Sessions:   make([]dto.AuthSession, 0),
+		AuthEvents: make([]dto.AuthEvent, 0),
+	}
+
+	user, dbError := s.authStorage.GetUserById(ctx, userId)
+	if dbError != nil {
+		return nil, dbError.Error
+	}
+	if err := copier.Copy(&export.User, &user); err != nil {
+		return nil, err
+	}
+	role, dbError := s.authStorage.GetRoleById(ctx, user.Role_id)
+	if dbError != nil {
+		return nil, dbError.Error
+	}
+	export.User.Role_name = role.Name
+
+	accounts, err := s.OauthAccounts(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	export.OauthAccounts = accounts.Accounts
+
+	credentials, err := s.WebauthnCredentials(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	export.WebauthnCredentials = credentials.Credentials
+
+	sessions, dbError := s.sessionStorage.GetSessionsByUserId(ctx, userId)
+	if dbError != nil {
+		return nil, dbError.Error
+	}
+	for _, session := range sessions {
+		export.Sessions = append(export.Sessions, sessionDto(session, user))
+	}
+
+	events, _, dbError := s.authStorage.ListAuthEvents(ctx, models.AuthEventsFilter{UserId: null.IntFrom(userId), Limit: dataExportAuthEventsLimit})
+	if dbError != nil {
+		return nil, dbError.Error
+	}
+	if err := copier.Copy(&export.AuthEvents, &events); err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.Buffer{}
+	zipWriter := zip.NewWriter(&buf)
+	w, err := zipWriter.Create("export.json")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(jsonData); err != nil {
+		return nil, err
+	}
+	if err := zipWriter.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *AuthService) dataExportStatusDto(data cache.DataExportData) dto.DataExportStatusResponse {
+	response := dto.DataExportStatusResponse{
+		Id:        data.Id,
+		Status:    data.Status,
+		CreatedAt: data.CreatedAt,
+		ExpiresAt: data.ExpiresAt,
+	}
+	if data.Status == dataExportReady {
+		expires := strconv.FormatInt(data.ExpiresAt.Unix(), 10)
+		query := url.Values{}
+		query.Set("expires", expires)
+		query.Set("signature", lib.SignString(data.Id+":"+expires, s.cfg.AUTH_SECRET_KEY))
+		response.DownloadUrl = "/api/data-export/" + data.Id + "?" + query.Encode()
+	}
+	return response
+}
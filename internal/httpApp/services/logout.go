@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
+)
+
+// Logout отзывает текущую сессию - jti берется из access-токена
+func (s *AuthService) Logout(ctx context.Context, userId int64, jti string, ip string, user_agent string) error {
+	op := "services.Logout"
+	log := s.log.With(slog.String("op", op))
+
+	data, dbError := s.sessionStorage.GetSessionByJti(ctx, jti)
+	if dbError != nil {
+		log.Warn("error get session by jti", slog.String("err", dbError.Message))
+		return errorsApp.ErrSessionNotFound.Error
+	}
+	if data.UserID != userId {
+		log.Warn("jti user_id not match session user_id", slog.Int64("user_id", userId))
+		return errorsApp.ErrForbidden.Error
+	}
+
+	dbError = s.sessionStorage.DeleteSessionByJti(ctx, "jti:"+jti)
+	if dbError != nil {
+		log.Warn("error delete session by jti", slog.String("err", dbError.Message))
+		if dbError.Type == "not_found" {
+			return errorsApp.ErrSessionNotFound.Error
+		}
+		return errorsApp.ErrInternalError.Error
+	}
+
+	log.Info("logout", slog.Int64("user_id", userId))
+	s.audit(ctx, authEvent{Type: models.AuthEventLogout, UserId: userId, IP: ip, UserAgent: user_agent})
+	return nil
+}
+
+// LogoutAll отзывает все сессии пользователя, при keepCurrent - кроме текущей
+func (s *AuthService) LogoutAll(ctx context.Context, userId int64, jti string, keepCurrent bool, ip string, user_agent string) (dto.AuthLogoutAllResponse, error) {
+	op := "services.LogoutAll"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthLogoutAllResponse{}
+
+	keepJti := ""
+	if keepCurrent {
+		keepJti = jti
+	}
+	revoked, dbError := s.sessionStorage.DeleteSessionsByUserIdExcept(ctx, userId, keepJti)
+	if dbError != nil {
+		log.Error("error delete sessions by user id", slog.String("err", dbError.Message))
+		return response, errorsApp.ErrInternalError.Error
+	}
+	response.Revoked = revoked
+
+	log.Info("logout all", slog.Int64("user_id", userId), slog.Int64("revoked", revoked), slog.Bool("keep_current", keepCurrent))
+	s.audit(ctx, authEvent{Type: models.AuthEventLogoutAll, UserId: userId, IP: ip, UserAgent: user_agent, Reason: "revoked " + strconv.FormatInt(revoked, 10)})
+	return response, nil
+}
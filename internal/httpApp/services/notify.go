@@ -0,0 +1,31 @@
+package services
+
+import (
+	"log/slog"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/notifications"
+)
+
+// notify асинхронно отправляет уведомление на телефон или email, ошибки отправки только логируются
+func (s *AuthService) notify(channel string, address string, subject string, text string) {
+	op := "services.notify"
+	log := s.log.With(slog.String("op", op), slog.String("channel", channel))
+
+	if address == "" {
+		return
+	}
+	go func() {
+		var err error
+		switch channel {
+		case "phone":
+			err = notifications.SMSC_SendSms(s.cfg, s.log, address, text)
+		case "email":
+			err = notifications.SendMail(s.cfg, address, subject, text)
+		}
+		if err != nil {
+			log.Warn("error send notification", slog.String("err", err.Error()))
+			return
+		}
+		log.Info("notification sent", slog.String("address", address))
+	}()
+}
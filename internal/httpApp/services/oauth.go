@@ -0,0 +1,320 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/config"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/db/cache"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
+	"github.com/google/uuid"
+	"github.com/guregu/null/v6"
+	"golang.org/x/oauth2"
+)
+
+// OauthProfile - профиль пользователя у внешнего провайдера, приведенный к общему виду
+type OauthProfile struct {
+	ProviderUserId string
+	Email          string
+	EmailVerified  bool
+	Name           string
+}
+
+// OauthProvider - внешний провайдер входа. Новый провайдер добавляется реализацией
+// интерфейса и регистрацией в NewOauthRegistry, хендлеры менять не нужно
+type OauthProvider interface {
+	Name() string
+	AuthCodeURL(state string, verifier string) string
+	Exchange(ctx context.Context, code string, verifier string) (*oauth2.Token, error)
+	Profile(ctx context.Context, token *oauth2.Token) (OauthProfile, error)
+}
+
+type OauthRegistry struct {
+	providers map[string]OauthProvider
+}
+
+// NewOauthRegistry регистрирует провайдеров, для которых в конфиге задан client id
+func NewOauthRegistry(cfg *config.Config) *OauthRegistry {
+	r := &OauthRegistry{providers: make(map[string]OauthProvider)}
+
+	if cfg.GOOGLE_CLIENT_ID != "" {
+		r.Register(newGoogleProvider(cfg))
+	}
+	if cfg.YANDEX_CLIENT_ID != "" {
+		r.Register(newYandexProvider(cfg))
+	}
+	if cfg.VK_CLIENT_ID != "" {
+		r.Register(newVkProvider(cfg))
+	}
+	if cfg.FACEBOOK_CLIENT_ID != "" {
+		r.Register(newFacebookProvider(cfg))
+	}
+	if cfg.OIDC_CLIENT_ID != "" {
+		r.Register(newOidcProvider(cfg.OIDC_NAME, cfg, oidcEndpoints{
+			AuthURL:     cfg.OIDC_AUTH_URL,
+			TokenURL:    cfg.OIDC_TOKEN_URL,
+			UserinfoURL: cfg.OIDC_USERINFO_URL,
+			Scopes:      cfg.OIDC_SCOPES,
+		}, cfg.OIDC_CLIENT_ID, cfg.OIDC_CLIENT_SECRET))
+	}
+	return r
+}
+
+func (r *OauthRegistry) Register(p OauthProvider) {
+	r.providers[p.Name()] = p
+}
+
+func (r *OauthRegistry) Get(name string) (OauthProvider, bool) {
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+func (r *OauthRegistry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// oauthBase - общая часть authorization code + PKCE поверх oauth2.Config
+type oauthBase struct {
+	name string
+	conf *oauth2.Config
+}
+
+func (b *oauthBase) Name() string {
+	return b.name
+}
+
+func (b *oauthBase) AuthCodeURL(state string, verifier string) string {
+	return b.conf.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier))
+}
+
+func (b *oauthBase) Exchange(ctx context.Context, code string, verifier string) (*oauth2.Token, error) {
+	return b.conf.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+}
+
+func redirectURL(cfg *config.Config, name string) string {
+	return cfg.OAUTH_REDIRECT_BASE_URL + "/" + name + "/callback"
+}
+
+func (s *AuthService) OauthProviders() dto.AuthOauthProvidersResponse {
+	return dto.AuthOauthProvidersResponse{Providers: s.oauthRegistry.Names()}
+}
+
+// OauthAuthURL формирует ссылку на страницу согласия провайдера, state и PKCE verifier хранятся в Redis.
+// Второе значение - привязка к браузеру, ее нужно выдать в cookie и передать в OauthCallback
+func (s *AuthService) OauthAuthURL(ctx context.Context, providerName string) (string, string, error) {
+	return s.startOauth(ctx, providerName, 0)
+}
+
+// startOauth сохраняет state и возвращает ссылку на провайдера и случайное значение для cookie браузера.
+// userId не 0 - callback привяжет аккаунт к этому пользователю вместо входа
+func (s *AuthService) startOauth(ctx context.Context, providerName string, userId int64) (string, string, error) {
+	op := "services.startOauth"
+	log := s.log.With(slog.String("op", op))
+
+	provider, ok := s.oauthRegistry.Get(providerName)
+	if !ok {
+		log.Warn("oauth provider not configured", slog.String("provider", providerName))
+		return "", "", errorsApp.ErrOauthNotConfigured.Error
+	}
+
+	state := uuid.NewString()
+	verifier := oauth2.GenerateVerifier()
+	// без привязки к браузеру ссылку на провайдера можно подсунуть жертве (login CSRF, привязка чужого аккаунта)
+	browser := uuid.NewString()
+
+	dbError := s.sessionStorage.SaveOauthState(ctx, cache.OauthStateData{
+		State:       state,
+		Provider:    providerName,
+		Verifier:    verifier,
+		UserID:      userId,
+		BrowserHash: lib.HashToken(browser),
+	}, s.cfg.OAUTH_STATE_TTL_MINUTES)
+	if dbError != nil {
+		log.Error("error save oauth state", slog.String("err", dbError.Message))
+		return "", "", errorsApp.ErrInternalError.Error
+	}
+
+	return provider.AuthCodeURL(state, verifier), browser, nil
+}
+
+// OauthCallback обменивает code на токен и получает профиль. При входе выдает пару токенов (или challenge 2FA) как Login,
+// при привязке (state создан через OauthLinkURL) возвращает привязанный аккаунт.
+// browser - значение cookie, выданной при старте, без совпадения callback отклоняется
+func (s *AuthService) OauthCallback(ctx context.Context, providerName string, code string, state string, browser string, ip string, user_agent string) (dto.AuthLoginResponse, *dto.AuthOauthAccount, error) {
+	op := "services.OauthCallback"
+	log := s.log.With(slog.String("op", op), slog.String("provider", providerName))
+
+	response := dto.AuthLoginResponse{}
+
+	provider, ok := s.oauthRegistry.Get(providerName)
+	if !ok {
+		log.Warn("oauth provider not configured")
+		return response, nil, errorsApp.ErrOauthNotConfigured.Error
+	}
+
+	stateData, dbError := s.sessionStorage.PopOauthState(ctx, state)
+	if dbError != nil || stateData.Provider != providerName {
+		log.Warn("oauth state not found or invalid", slog.String("state", state))
+		return response, nil, errorsApp.ErrOauthFailed.Error
+	}
+	if browser == "" || subtle.ConstantTimeCompare([]byte(lib.HashToken(browser)), []byte(stateData.BrowserHash)) != 1 {
+		log.Warn("oauth state started in another browser", slog.String("state", state))
+		return response, nil, errorsApp.ErrOauthFailed.Error
+	}
+
+	profile, err := s.oauthProfile(ctx, provider, code, stateData.Verifier)
+	if err != nil {
+		return response, nil, err
+	}
+
+	if stateData.UserID != 0 {
+		account, err := s.linkOauthAccount(ctx, stateData.UserID, providerName, profile)
+		return response, account, err
+	}
+
+	userEntity, err := s.findOrCreateOauthUser(ctx, providerName, profile)
+	if err != nil {
+		return response, nil, err
+	}
+
+	response, err = s.completeLogin(ctx, userEntity, ip, user_agent)
+	return response, nil, err
+}
+
+// oauthProfile выполняет обмен code и запрос профиля у провайдера
+func (s *AuthService) oauthProfile(ctx context.Context, provider OauthProvider, code string, verifier string) (OauthProfile, error) {
+	op := "services.oauthProfile"
+	log := s.log.With(slog.String("op", op), slog.String("provider", provider.Name()))
+
+	httpCtx := context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Timeout: s.cfg.HTTP_TIMEOUT})
+	token, err := provider.Exchange(httpCtx, code, verifier)
+	if err != nil {
+		log.Warn("error exchange code", slog.String("err", err.Error()))
+		return OauthProfile{}, errorsApp.ErrOauthFailed.Error
+	}
+
+	profile, err := provider.Profile(httpCtx, token)
+	if err != nil {
+		log.Warn("error get oauth profile", slog.String("err", err.Error()))
+		return profile, errorsApp.ErrOauthFailed.Error
+	}
+	if profile.ProviderUserId == "" {
+		log.Warn("oauth profile without user id")
+		return profile, errorsApp.ErrOauthFailed.Error
+	}
+	return profile, nil
+}
+
+// findOrCreateOauthUser ищет пользователя по привязке oauth_accounts,
+// затем по email, подтвержденному и провайдером, и у нас, иначе создает нового.
+// К аккаунту с тем же, но не подтвержденным у нас email вход не привязывается:
+// его мог заранее зарегистрировать кто угодно со своим паролем
+func (s *AuthService) findOrCreateOauthUser(ctx context.Context, providerName string, profile OauthProfile) (models.UserEntity, error) {
+	op := "services.findOrCreateOauthUser"
+	log := s.log.With(slog.String("op", op), slog.String("provider", providerName))
+
+	account, dbError := s.authStorage.GetOauthAccountByProvider(ctx, providerName, profile.ProviderUserId)
+	if dbError == nil {
+		userEntity, dbError := s.authStorage.GetUserById(ctx, account.User_id)
+		if dbError != nil {
+			log.Error("error get user by id", slog.String("err", dbError.Message))
+			return userEntity, errorsApp.ErrInternalError.Error
+		}
+		return userEntity, nil
+	}
+	if dbError.Type != "not_found" {
+		log.Error("error get oauth account", slog.String("err", dbError.Message))
+		return models.UserEntity{}, errorsApp.ErrInternalError.Error
+	}
+
+	// привязываем к существующему пользователю только если провайдер подтвердил email
+	if profile.EmailVerified && profile.Email != "" {
+		userEntity, dbError := s.authStorage.GetUserByEmail(ctx, profile.Email)
+		if dbError == nil {
+			if !userEntity.Email_verified_at.Valid {
+				log.Warn("email exists but not verified, oauth account not linked", slog.Int64("user_id", userEntity.Id))
+				return userEntity, errorsApp.ErrOauthEmailNotVerified.Error
+			}
+			_, dbError = s.authStorage.NewOauthAccount(ctx, models.OauthAccountEntity{
+				User_id:          userEntity.Id,
+				Provider:         providerName,
+				Provider_user_id: profile.ProviderUserId,
+			})
+			if dbError != nil {
+				log.Error("error link oauth account", slog.String("err", dbError.Message))
+				return userEntity, errorsApp.ErrInternalError.Error
+			}
+			log.Info("oauth account linked", slog.Int64("user_id", userEntity.Id))
+			return userEntity, nil
+		}
+		if dbError.Type != "not_found" {
+			log.Error("error get user by email", slog.String("err", dbError.Message))
+			return userEntity, errorsApp.ErrInternalError.Error
+		}
+	}
+
+	newUser := models.UserEntity{
+		Name:    profile.Name,
+		Role_id: 3, // default role user TODO - перенести в таблицу настроек
+	}
+	if newUser.Name == "" {
+		newUser.Name = profile.Email
+	}
+	if newUser.Name == "" {
+		newUser.Name = providerName + "_" + profile.ProviderUserId
+	}
+	if profile.EmailVerified && profile.Email != "" {
+		newUser.Email = null.StringFrom(profile.Email)
+		newUser.Email_verified_at = null.TimeFrom(time.Now())
+	}
+
+	userEntity, dbError := s.authStorage.NewUserWithOauthAccount(ctx, newUser, models.OauthAccountEntity{
+		Provider:         providerName,
+		Provider_user_id: profile.ProviderUserId,
+	})
+	if dbError != nil {
+		log.Error("error create user with oauth account", slog.String("err", dbError.Message))
+		return userEntity, errorsApp.ErrInternalError.Error
+	}
+	log.Info("user created with oauth account", slog.Int64("user_id", userEntity.Id))
+
+	return userEntity, nil
+}
+
+// getOauthJSON выполняет GET-запрос к API провайдера и декодирует JSON-ответ
+func getOauthJSON(ctx context.Context, client *http.Client, url string, header map[string]string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
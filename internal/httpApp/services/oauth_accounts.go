@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
+	"github.com/jinzhu/copier"
+)
+
+func (s *AuthService) OauthAccounts(ctx context.Context, userId int64) (dto.AuthOauthAccountsResponse, error) {
+	op := "services.OauthAccounts"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthOauthAccountsResponse{Accounts: make([]dto.AuthOauthAccount, 0)}
+
+	accounts, dbError := s.authStorage.GetOauthAccountsByUserId(ctx, userId)
+	if dbError != nil {
+		log.Error("error get oauth accounts", slog.String("err", dbError.Message))
+		return response, errorsApp.ErrInternalError.Error
+	}
+
+	errCopy := copier.Copy(&response.Accounts, &accounts)
+	if errCopy != nil {
+		log.Error("", slog.String("err", errCopy.Error()))
+		return response, errCopy
+	}
+	return response, nil
+}
+
+// OauthLinkURL - ссылка на провайдера для привязки к текущему пользователю и значение cookie браузера, как в OauthAuthURL
+func (s *AuthService) OauthLinkURL(ctx context.Context, providerName string, userId int64) (dto.AuthOauthLinkResponse, string, error) {
+	url, browser, err := s.startOauth(ctx, providerName, userId)
+	if err != nil {
+		return dto.AuthOauthLinkResponse{}, "", err
+	}
+	return dto.AuthOauthLinkResponse{Url: url}, browser, nil
+}
+
+func (s *AuthService) linkOauthAccount(ctx context.Context, userId int64, providerName string, profile OauthProfile) (*dto.AuthOauthAccount, error) {
+	op := "services.linkOauthAccount"
+	log := s.log.With(slog.String("op", op), slog.String("provider", providerName))
+
+	existing, dbError := s.authStorage.GetOauthAccountByProvider(ctx, providerName, profile.ProviderUserId)
+	if dbError == nil {
+		if existing.User_id != userId {
+			log.Warn("oauth account linked to another user", slog.Int64("user_id", userId), slog.Int64("owner_id", existing.User_id))
+			return nil, errorsApp.ErrOauthAlreadyLinked.Error
+		}
+		// уже привязан к этому же пользователю
+		return oauthAccountDto(existing), nil
+	}
+	if dbError.Type != "not_found" {
+		log.Error("error get oauth account", slog.String("err", dbError.Message))
+		return nil, errorsApp.ErrInternalError.Error
+	}
+
+	account, dbError := s.authStorage.NewOauthAccount(ctx, models.OauthAccountEntity{
+		User_id:          userId,
+		Provider:         providerName,
+		Provider_user_id: profile.ProviderUserId,
+	})
+	if dbError != nil {
+		log.Error("error link oauth account", slog.String("err", dbError.Message))
+		if dbError.Type == "unique_violation" {
+			return nil, errorsApp.ErrOauthAlreadyLinked.Error
+		}
+		return nil, errorsApp.ErrInternalError.Error
+	}
+	log.Info("oauth account linked", slog.Int64("user_id", userId))
+
+	return oauthAccountDto(account), nil
+}
+
+// UnlinkOauthAccount отвязывает провайдера, если у пользователя остается пароль или другой провайдер
+func (s *AuthService) UnlinkOauthAccount(ctx context.Context, userId int64, accountId int64) error {
+	op := "services.UnlinkOauthAccount"
+	log := s.log.With(slog.String("op", op))
+
+	userEntity, dbError := s.authStorage.GetUserById(ctx, userId)
+	if dbError != nil {
+		log.Warn("error get user by id", slog.String("err", dbError.Message))
+		return errorsApp.ErrUserNotFound.Error
+	}
+
+	accounts, dbError := s.authStorage.GetOauthAccountsByUserId(ctx, userId)
+	if dbError != nil {
+		log.Error("error get oauth accounts", slog.String("err", dbError.Message))
+		return errorsApp.ErrInternalError.Error
+	}
+
+	found := false
+	for _, account := range accounts {
+		if account.Id == accountId {
+			found = true
+			break
+		}
+	}
+	if !found {
+		log.Warn("oauth account not found", slog.Int64("account_id", accountId))
+		return errorsApp.ErrOauthAccountNotFound.Error
+	}
+
+	hasPassword := userEntity.Password_hash.Valid && userEntity.Password_hash.String != ""
+	if !hasPassword && len(accounts) <= 1 {
+		log.Warn("refuse to unlink last login method", slog.Int64("user_id", userId))
+		return errorsApp.ErrLastLoginMethod.Error
+	}
+
+	dbError = s.authStorage.DeleteOauthAccount(ctx, accountId, userId)
+	if dbError != nil {
+		log.Warn("error delete oauth account", slog.String("err", dbError.Message))
+		if dbError.Type == "not_found" {
+			return errorsApp.ErrOauthAccountNotFound.Error
+		}
+		return errorsApp.ErrInternalError.Error
+	}
+	log.Info("oauth account unlinked", slog.Int64("user_id", userId), slog.Int64("account_id", accountId))
+
+	return nil
+}
+
+func oauthAccountDto(account models.OauthAccountEntity) *dto.AuthOauthAccount {
+	return &dto.AuthOauthAccount{
+		Id:               account.Id,
+		Provider:         account.Provider,
+		Provider_user_id: account.Provider_user_id,
+		Create_date:      account.Create_date,
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/config"
+	"golang.org/x/oauth2"
+)
+
+// https://developers.facebook.com/docs/facebook-login/guides/advanced/manual-flow
+type facebookProvider struct {
+	oauthBase
+	meURL string
+}
+
+type facebookUserInfo struct {
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func newFacebookProvider(cfg *config.Config) OauthProvider {
+	return &facebookProvider{
+		oauthBase: oauthBase{
+			name: "facebook",
+			conf: &oauth2.Config{
+				ClientID:     cfg.FACEBOOK_CLIENT_ID,
+				ClientSecret: cfg.FACEBOOK_CLIENT_SECRET,
+				RedirectURL:  redirectURL(cfg, "facebook"),
+				Scopes:       []string{"public_profile", "email"},
+				Endpoint: oauth2.Endpoint{
+					AuthURL:   "https://www.facebook.com/v19.0/dialog/oauth",
+					TokenURL:  "https://graph.facebook.com/v19.0/oauth/access_token",
+					AuthStyle: oauth2.AuthStyleInParams,
+				},
+			},
+		},
+		meURL: "https://graph.facebook.com/v19.0/me",
+	}
+}
+
+func (p *facebookProvider) Profile(ctx context.Context, token *oauth2.Token) (OauthProfile, error) {
+	query := url.Values{}
+	query.Set("fields", "id,name,email")
+	query.Set("access_token", token.AccessToken)
+
+	info := facebookUserInfo{}
+	err := getOauthJSON(ctx, oauth2.NewClient(ctx, nil), p.meURL+"?"+query.Encode(), nil, &info)
+	if err != nil {
+		return OauthProfile{}, fmt.Errorf("facebook me: %w", err)
+	}
+
+	return OauthProfile{
+		ProviderUserId: info.Id,
+		Email:          info.Email,
+		// Facebook не сообщает, подтвержден ли email, поэтому не доверяем ему
+		EmailVerified: false,
+		Name:          info.Name,
+	}, nil
+}
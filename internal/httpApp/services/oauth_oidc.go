@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/config"
+	"golang.org/x/oauth2"
+)
+
+type oidcEndpoints struct {
+	AuthURL     string
+	TokenURL    string
+	UserinfoURL string
+	Scopes      []string
+}
+
+// oidcProvider - любой OpenID Connect провайдер со стандартным userinfo endpoint
+type oidcProvider struct {
+	oauthBase
+	userinfoURL string
+}
+
+type oidcUserInfo struct {
+	Sub           string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+func newOidcProvider(name string, cfg *config.Config, endpoints oidcEndpoints, clientId string, clientSecret string) *oidcProvider {
+	return &oidcProvider{
+		oauthBase: oauthBase{
+			name: name,
+			conf: &oauth2.Config{
+				ClientID:     clientId,
+				ClientSecret: clientSecret,
+				RedirectURL:  redirectURL(cfg, name),
+				Scopes:       endpoints.Scopes,
+				Endpoint: oauth2.Endpoint{
+					AuthURL:   endpoints.AuthURL,
+					TokenURL:  endpoints.TokenURL,
+					AuthStyle: oauth2.AuthStyleInParams,
+				},
+			},
+		},
+		userinfoURL: endpoints.UserinfoURL,
+	}
+}
+
+func (p *oidcProvider) Profile(ctx context.Context, token *oauth2.Token) (OauthProfile, error) {
+	info := oidcUserInfo{}
+
+	err := getOauthJSON(ctx, p.conf.Client(ctx, token), p.userinfoURL, nil, &info)
+	if err != nil {
+		return OauthProfile{}, fmt.Errorf("userinfo: %w", err)
+	}
+
+	return OauthProfile{
+		ProviderUserId: info.Sub,
+		Email:          info.Email,
+		EmailVerified:  info.EmailVerified,
+		Name:           info.Name,
+	}, nil
+}
@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/config"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/db/cache"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
+	"github.com/guregu/null/v6"
+)
+
+// fakeOidcServer - локальный OIDC-провайдер: authorize сразу редиректит с code,
+// token проверяет code и PKCE verifier, userinfo отдает профиль по access-токену
+func fakeOidcServer(t *testing.T, profile oidcUserInfo) *httptest.Server {
+	t.Helper()
+
+	const code = "test-code"
+	const accessToken = "test-access-token"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256" {
+			http.Error(w, "pkce required", http.StatusBadRequest)
+			return
+		}
+		redirect, err := url.Parse(query.Get("redirect_uri"))
+		if err != nil {
+			http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+			return
+		}
+		params := url.Values{}
+		params.Set("code", code)
+		params.Set("state", query.Get("state"))
+		redirect.RawQuery = params.Encode()
+		http.Redirect(w, r, redirect.String(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if r.PostForm.Get("code") != code || r.PostForm.Get("code_verifier") == "" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": accessToken,
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+accessToken {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(profile)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+type fakeOauthAuthStorage struct {
+	authStorage
+	usersByEmail map[string]models.UserEntity
+	created      []models.UserEntity
+}
+
+func (f *fakeOauthAuthStorage) GetOauthAccountByProvider(ctx context.Context, provider string, providerUserId string) (models.OauthAccountEntity, *errorsApp.DbError) {
+	return models.OauthAccountEntity{}, &errorsApp.DbError{Type: "not_found", Message: "oauth account not found"}
+}
+
+func (f *fakeOauthAuthStorage) GetUserByEmail(ctx context.Context, email string) (models.UserEntity, *errorsApp.DbError) {
+	user, ok := f.usersByEmail[email]
+	if !ok {
+		return user, &errorsApp.DbError{Type: "not_found", Message: "user not found"}
+	}
+	return user, nil
+}
+
+func (f *fakeOauthAuthStorage) NewUserWithOauthAccount(ctx context.Context, user models.UserEntity, account models.OauthAccountEntity) (models.UserEntity, *errorsApp.DbError) {
+	user.Id = int64(len(f.created) + 1)
+	f.created = append(f.created, user)
+	return user, nil
+}
+
+func (f *fakeOauthAuthStorage) GetRoleById(ctx context.Context, id int64) (models.RoleEntity, *errorsApp.DbError) {
+	return models.RoleEntity{Id: id, Name: "user"}, nil
+}
+
+func (f *fakeOauthAuthStorage) NewAuthEvent(ctx context.Context, event models.AuthEventEntity) *errorsApp.DbError {
+	return nil
+}
+
+func (f *fakeOauthAuthStorage) ListAuthEvents(ctx context.Context, filter models.AuthEventsFilter) ([]models.AuthEventEntity, int64, *errorsApp.DbError) {
+	return nil, 0, nil
+}
+
+type fakeOauthSessionStorage struct {
+	sessionStorage
+	states   map[string]cache.OauthStateData
+	sessions map[string]cache.SessionData
+}
+
+func (f *fakeOauthSessionStorage) SaveOauthState(ctx context.Context, data cache.OauthStateData, ttlMinutes int) *errorsApp.DbError {
+	f.states[data.State] = data
+	return nil
+}
+
+func (f *fakeOauthSessionStorage) PopOauthState(ctx context.Context, state string) (cache.OauthStateData, *errorsApp.DbError) {
+	data, ok := f.states[state]
+	if !ok {
+		return data, &errorsApp.DbError{Type: "not_found", Message: "oauth state not found"}
+	}
+	delete(f.states, state)
+	return data, nil
+}
+
+func (f *fakeOauthSessionStorage) SaveSession(ctx context.Context, jti string, data cache.SessionData, ttlHours int) *errorsApp.DbError {
+	f.sessions[jti] = data
+	return nil
+}
+
+func newOauthTestService(t *testing.T, server *httptest.Server, authStorage *fakeOauthAuthStorage) (*AuthService, *fakeOauthSessionStorage) {
+	t.Helper()
+
+	cfg := &config.Config{
+		SERVICE_NAME:                  "test",
+		AUTH_SECRET_KEY:               "test-secret",
+		AUTH_ACCESS_TOKEN_EXP_MINUTES: 15,
+		AUTH_REFRESH_TOKEN_EXP_HOURS:  24,
+		OAUTH_STATE_TTL_MINUTES:       10,
+		OAUTH_REDIRECT_BASE_URL:       "http://localhost/api/auth/oauth",
+		HTTP_TIMEOUT:                  5 * time.Second,
+		OIDC_NAME:                     "fake",
+		OIDC_CLIENT_ID:                "client",
+		OIDC_CLIENT_SECRET:            "secret",
+		OIDC_AUTH_URL:                 server.URL + "/authorize",
+		OIDC_TOKEN_URL:                server.URL + "/token",
+		OIDC_USERINFO_URL:             server.URL + "/userinfo",
+		OIDC_SCOPES:                   []string{"openid", "email", "profile"},
+	}
+	jwtKeys, err := lib.LoadJWTKeySet("HS256", cfg.AUTH_SECRET_KEY, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionStorage := &fakeOauthSessionStorage{
+		states:   make(map[string]cache.OauthStateData),
+		sessions: make(map[string]cache.SessionData),
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewAuthService(log, authStorage, sessionStorage, nil, nil, jwtKeys, nil, cfg), sessionStorage
+}
+
+// authorize проходит страницу согласия провайдера так, как это сделал бы браузер, и возвращает code и state
+func authorize(t *testing.T, authURL string) (string, string) {
+	t.Helper()
+
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status %d", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestOauthCallback(t *testing.T) {
+	tests := []struct {
+		name         string
+		profile      oidcUserInfo
+		users        map[string]models.UserEntity
+		otherBrowser bool // callback пришел без cookie браузера, начавшего вход
+		wantErr      error
+		wantCreated  int
+		wantSessions int
+	}{
+		{
+			name:         "new user is created",
+			profile:      oidcUserInfo{Sub: "42", Email: "new@example.com", EmailVerified: true, Name: "New User"},
+			users:        map[string]models.UserEntity{},
+			wantCreated:  1,
+			wantSessions: 1,
+		},
+		{
+			name:         "another browser is rejected",
+			profile:      oidcUserInfo{Sub: "42", Email: "new@example.com", EmailVerified: true},
+			users:        map[string]models.UserEntity{},
+			otherBrowser: true,
+			wantErr:      errorsApp.ErrOauthFailed.Error,
+		},
+		{
+			name:    "unverified local email is not linked",
+			profile: oidcUserInfo{Sub: "42", Email: "victim@example.com", EmailVerified: true},
+			users: map[string]models.UserEntity{
+				"victim@example.com": {Id: 7, Email: null.StringFrom("victim@example.com"), Password_hash: null.StringFrom("attacker")},
+			},
+			wantErr: errorsApp.ErrOauthEmailNotVerified.Error,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fakeOidcServer(t, tt.profile)
+			authStorage := &fakeOauthAuthStorage{usersByEmail: tt.users}
+			service, sessionStorage := newOauthTestService(t, server, authStorage)
+			ctx := context.Background()
+
+			authURL, browser, err := service.OauthAuthURL(ctx, "fake")
+			if err != nil {
+				t.Fatal(err)
+			}
+			code, state := authorize(t, authURL)
+			if tt.otherBrowser {
+				browser = "other-browser"
+			}
+
+			response, linked, err := service.OauthCallback(ctx, "fake", code, state, browser, "127.0.0.1", "test-agent")
+			if err != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if len(authStorage.created) != tt.wantCreated {
+				t.Fatalf("created users: %d, want %d", len(authStorage.created), tt.wantCreated)
+			}
+			if len(sessionStorage.sessions) != tt.wantSessions {
+				t.Fatalf("sessions: %d, want %d", len(sessionStorage.sessions), tt.wantSessions)
+			}
+			if len(sessionStorage.states) != 0 {
+				t.Fatal("state not consumed")
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if linked != nil {
+				t.Fatal("login flow returned linked account")
+			}
+			if response.AccessToken == "" || response.RefreshToken == "" {
+				t.Fatal("tokens not issued")
+			}
+			user := authStorage.created[0]
+			if user.Email.String != tt.profile.Email || !user.Email_verified_at.Valid || user.Name != tt.profile.Name {
+				t.Fatalf("unexpected user: %+v", user)
+			}
+		})
+	}
+}
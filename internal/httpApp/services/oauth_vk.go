@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/config"
+	"golang.org/x/oauth2"
+)
+
+// https://dev.vk.com/ru/api/access-token/authcode-flow-user
+type vkProvider struct {
+	oauthBase
+	apiURL string
+}
+
+type vkUsersGetResponse struct {
+	Response []struct {
+		Id        int64  `json:"id"`
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+	} `json:"response"`
+}
+
+func newVkProvider(cfg *config.Config) OauthProvider {
+	return &vkProvider{
+		oauthBase: oauthBase{
+			name: "vk",
+			conf: &oauth2.Config{
+				ClientID:     cfg.VK_CLIENT_ID,
+				ClientSecret: cfg.VK_CLIENT_SECRET,
+				RedirectURL:  redirectURL(cfg, "vk"),
+				Scopes:       []string{"email"},
+				Endpoint: oauth2.Endpoint{
+					AuthURL:   "https://oauth.vk.com/authorize",
+					TokenURL:  "https://oauth.vk.com/access_token",
+					AuthStyle: oauth2.AuthStyleInParams,
+				},
+			},
+		},
+		apiURL: "https://api.vk.com/method/users.get",
+	}
+}
+
+func (p *vkProvider) Profile(ctx context.Context, token *oauth2.Token) (OauthProfile, error) {
+	// VK возвращает user_id и email прямо в ответе на обмен кода
+	var userId string
+	switch v := token.Extra("user_id").(type) {
+	case float64:
+		userId = strconv.FormatInt(int64(v), 10)
+	case string:
+		userId = v
+	}
+	if userId == "" {
+		return OauthProfile{}, fmt.Errorf("vk user_id not found in token response")
+	}
+	email, _ := token.Extra("email").(string)
+
+	query := url.Values{}
+	query.Set("user_ids", userId)
+	query.Set("access_token", token.AccessToken)
+	query.Set("v", "5.199")
+
+	info := vkUsersGetResponse{}
+	err := getOauthJSON(ctx, oauth2.NewClient(ctx, nil), p.apiURL+"?"+query.Encode(), nil, &info)
+	if err != nil {
+		return OauthProfile{}, fmt.Errorf("vk users.get: %w", err)
+	}
+
+	name := ""
+	if len(info.Response) > 0 {
+		name = strings.TrimSpace(info.Response[0].FirstName + " " + info.Response[0].LastName)
+	}
+
+	return OauthProfile{
+		ProviderUserId: userId,
+		Email:          email,
+		// VK не сообщает, подтвержден ли email, поэтому не доверяем ему
+		EmailVerified: false,
+		Name:          name,
+	}, nil
+}
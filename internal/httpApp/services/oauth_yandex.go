@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/config"
+	"golang.org/x/oauth2"
+)
+
+// https://yandex.ru/dev/id/doc/ru/
+type yandexProvider struct {
+	oauthBase
+	infoURL string
+}
+
+type yandexUserInfo struct {
+	Id           string `json:"id"`
+	Login        string `json:"login"`
+	DefaultEmail string `json:"default_email"`
+	RealName     string `json:"real_name"`
+	DisplayName  string `json:"display_name"`
+}
+
+func newYandexProvider(cfg *config.Config) OauthProvider {
+	return &yandexProvider{
+		oauthBase: oauthBase{
+			name: "yandex",
+			conf: &oauth2.Config{
+				ClientID:     cfg.YANDEX_CLIENT_ID,
+				ClientSecret: cfg.YANDEX_CLIENT_SECRET,
+				RedirectURL:  redirectURL(cfg, "yandex"),
+				Scopes:       []string{"login:email", "login:info"},
+				Endpoint: oauth2.Endpoint{
+					AuthURL:   "https://oauth.yandex.ru/authorize",
+					TokenURL:  "https://oauth.yandex.ru/token",
+					AuthStyle: oauth2.AuthStyleInParams,
+				},
+			},
+		},
+		infoURL: "https://login.yandex.ru/info?format=json",
+	}
+}
+
+func (p *yandexProvider) Profile(ctx context.Context, token *oauth2.Token) (OauthProfile, error) {
+	info := yandexUserInfo{}
+
+	// Яндекс ожидает схему OAuth, а не Bearer
+	err := getOauthJSON(ctx, oauth2.NewClient(ctx, nil), p.infoURL, map[string]string{
+		"Authorization": "OAuth " + token.AccessToken,
+	}, &info)
+	if err != nil {
+		return OauthProfile{}, fmt.Errorf("yandex info: %w", err)
+	}
+
+	name := info.RealName
+	if name == "" {
+		name = info.DisplayName
+	}
+	if name == "" {
+		name = info.Login
+	}
+
+	return OauthProfile{
+		ProviderUserId: info.Id,
+		Email:          info.DefaultEmail,
+		// Яндекс не сообщает, подтвержден ли адрес, поэтому не доверяем ему
+		EmailVerified: false,
+		Name:          name,
+	}, nil
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
+)
+
+// OtpStart отправляет код для входа без пароля на подтвержденный телефон или email
+func (s *AuthService) OtpStart(ctx context.Context, body dto.AuthOtpStartRequest, ip string) (dto.AuthSendVerifyResponse, error) {
+	op := "services.OtpStart"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthSendVerifyResponse{}
+
+	// неизвестный, неподтвержденный или заблокированный адрес получает тот же ответ, что и настоящий
+	user, err := s.getUserByAddress(ctx, body.Type, body.Address)
+	if err == errorsApp.ErrUserNotFound.Error {
+		return s.skipOtp(ctx, body.Address, ip)
+	}
+	if err != nil {
+		return response, err
+	}
+	if !addressVerified(user, body.Type) {
+		log.Warn("address not verified", slog.Int64("user_id", user.Id), slog.String("type", body.Type))
+		return s.skipOtp(ctx, body.Address, ip)
+	}
+	if user.Blocked_at.Valid {
+		log.Warn("user is blocked", slog.Int64("user_id", user.Id))
+		return s.skipOtp(ctx, body.Address, ip)
+	}
+
+	response, err = s.issueOtp(ctx, otpTypeLogin, body.Type, body.Address, ip, "Login code for "+s.cfg.SERVICE_NAME, "Your login code is: ")
+	if err != nil {
+		return response, err
+	}
+
+	log.Debug("login code sent", slog.Int64("user_id", user.Id))
+
+	return response, nil
+}
+
+// OtpComplete проверяет код входа и завершает вход так же, как Login (включая 2FA)
+func (s *AuthService) OtpComplete(ctx context.Context, body dto.AuthOtpCompleteRequest, ip string, user_agent string) (dto.AuthLoginResponse, error) {
+	op := "services.OtpComplete"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthLoginResponse{}
+
+	if err := s.checkOtp(ctx, otpTypeLogin, body.Address, body.Code); err != nil {
+		s.audit(ctx, authEvent{Type: models.AuthEventLogin, Identity: body.Address, IP: ip, UserAgent: user_agent, Err: err})
+		return response, err
+	}
+	// код одноразовый
+	s.invalidateOtp(ctx, body.Address, otpTypeLogin)
+
+	user, err := s.getUserByAddress(ctx, body.Type, body.Address)
+	if err != nil {
+		s.audit(ctx, authEvent{Type: models.AuthEventLogin, Identity: body.Address, IP: ip, UserAgent: user_agent, Err: err})
+		return response, err
+	}
+	if !addressVerified(user, body.Type) {
+		log.Warn("address not verified", slog.Int64("user_id", user.Id), slog.String("type", body.Type))
+		s.audit(ctx, authEvent{Type: models.AuthEventLogin, UserId: user.Id, Identity: body.Address, IP: ip, UserAgent: user_agent, Err: errorsApp.ErrVerifyNotFound.Error})
+		return response, errorsApp.ErrVerifyNotFound.Error
+	}
+
+	return s.completeLogin(ctx, user, ip, user_agent)
+}
+
+func addressVerified(user models.UserEntity, channel string) bool {
+	if channel == "phone" {
+		return user.Phone_verified_at.Valid
+	}
+	return user.Email_verified_at.Valid
+}
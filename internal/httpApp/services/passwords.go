@@ -4,11 +4,13 @@ import (
 	"context"
 	"log/slog"
 
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
 	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
 	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
 )
 
-func (s *AuthService) UpdatePassword(ctx context.Context, id int64, oldpassword string, newpassword string) error {
+func (s *AuthService) UpdatePassword(ctx context.Context, id int64, oldpassword string, newpassword string, ip string, user_agent string) error {
 	op := "services.UpdatePassword"
 	log := s.log.With(slog.String("op", op))
 
@@ -21,6 +23,7 @@ func (s *AuthService) UpdatePassword(ctx context.Context, id int64, oldpassword
 	isValidErr := lib.CheckPassword(userEntity.Password_hash.String, oldpassword)
 	if isValidErr != nil {
 		log.Warn("error verify password", slog.String("err", "password not match"))
+		s.audit(ctx, authEvent{Type: models.AuthEventPasswordChange, UserId: id, IP: ip, UserAgent: user_agent, Err: errorsApp.ErrOldPasswordNotMatch.Error})
 		return errorsApp.ErrOldPasswordNotMatch.Error
 	}
 
@@ -31,6 +34,82 @@ func (s *AuthService) UpdatePassword(ctx context.Context, id int64, oldpassword
 	}
 
 	log.Debug("password updated", slog.Int64("user_id", id))
+	s.audit(ctx, authEvent{Type: models.AuthEventPasswordChange, UserId: id, IP: ip, UserAgent: user_agent})
+	s.securityNotify(userEntity, securityNoticePasswordChanged, ip, user_agent, "")
+
+	return nil
+}
+
+func (s *AuthService) ResetPassword(ctx context.Context, body dto.AuthResetPasswordRequest, ip string) (dto.AuthSendVerifyResponse, error) {
+	op := "services.ResetPassword"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthSendVerifyResponse{}
+
+	if body.Type != "phone" && body.Type != "email" {
+		log.Warn("invalid type", slog.String("type", body.Type))
+		return response, errorsApp.ErrBadRequest.Error
+	}
+
+	user, err := s.getUserByAddress(ctx, body.Type, body.Address)
+	if err == errorsApp.ErrUserNotFound.Error {
+		return s.skipOtp(ctx, body.Address, ip)
+	}
+	if err != nil {
+		return response, err
+	}
+
+	response, err = s.issueOtp(ctx, otpTypeReset, body.Type, body.Address, ip, "Reset password code for "+s.cfg.SERVICE_NAME, "Your password reset code is: ")
+	if err != nil {
+		return response, err
+	}
+
+	log.Debug("reset password code sent", slog.Int64("user_id", user.Id))
+
+	return response, nil
+}
+
+func (s *AuthService) ConfirmResetPassword(ctx context.Context, body dto.AuthConfirmResetPasswordRequest, ip string, user_agent string) error {
+	op := "services.ConfirmResetPassword"
+	log := s.log.With(slog.String("op", op))
+
+	if body.Type != "phone" && body.Type != "email" {
+		log.Warn("invalid type", slog.String("type", body.Type))
+		return errorsApp.ErrBadRequest.Error
+	}
+
+	if err := s.checkOtp(ctx, otpTypeReset, body.Address, body.Code); err != nil {
+		s.audit(ctx, authEvent{Type: models.AuthEventPasswordReset, Identity: body.Address, IP: ip, UserAgent: user_agent, Err: err})
+		return err
+	}
+
+	user, err := s.getUserByAddress(ctx, body.Type, body.Address)
+	if err != nil {
+		return err
+	}
+
+	dbError := s.authStorage.UpdatePassword(ctx, user.Id, body.NewPassword)
+	if dbError != nil {
+		log.Warn("error update password", slog.String("err", dbError.Message))
+		return errorsApp.ErrInternalError.Error
+	}
+
+	// код одноразовый
+	dbError = s.otpStorage.DeleteOtp(ctx, body.Address, otpTypeReset)
+	if dbError != nil {
+		log.Warn("error delete otp", slog.String("err", dbError.Message))
+	}
+
+	// после сброса пароля все существующие сессии недействительны
+	dbError = s.sessionStorage.DeleteSessionsByUserId(ctx, user.Id)
+	if dbError != nil {
+		log.Error("error delete sessions by user id", slog.String("err", dbError.Message))
+		return errorsApp.ErrInternalError.Error
+	}
+
+	log.Info("password reset", slog.Int64("user_id", user.Id))
+	s.audit(ctx, authEvent{Type: models.AuthEventPasswordReset, UserId: user.Id, Identity: body.Address, IP: ip, UserAgent: user_agent})
+	s.securityNotify(user, securityNoticePasswordChanged, ip, user_agent, "")
 
 	return nil
 }
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/config"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
+)
+
+// RbacService отвечает на вопрос "есть ли у роли право", роли и права кэшируются в памяти
+// на RBAC_CACHE_TTL, чтобы не ходить в БД на каждый запрос
+type RbacService struct {
+	log         *slog.Logger
+	rbacStorage rbacStorage
+	cfg         *config.Config
+
+	mu    sync.RWMutex
+	roles map[int64]rbacRole
+}
+
+type rbacStorage interface {
+	GetRoleById(ctx context.Context, id int64) (models.RoleEntity, *errorsApp.DbError)
+	GetPermissionsByRoleId(ctx context.Context, roleId int64) ([]models.PermissionEntity, *errorsApp.DbError)
+}
+
+type rbacRole struct {
+	name        string
+	permissions map[string]bool
+	loadedAt    time.Time
+}
+
+func NewRbacService(log *slog.Logger,
+	rbacStorage rbacStorage,
+	cfg *config.Config) *RbacService {
+	return &RbacService{
+		log:         log,
+		rbacStorage: rbacStorage,
+		cfg:         cfg,
+		roles:       make(map[int64]rbacRole),
+	}
+}
+
+func (s *RbacService) role(ctx context.Context, roleId int64) (rbacRole, error) {
+	op := "services.RbacService.role"
+	log := s.log.With(slog.String("op", op))
+
+	s.mu.RLock()
+	role, ok := s.roles[roleId]
+	s.mu.RUnlock()
+	if ok && time.Since(role.loadedAt) < s.cfg.RBAC_CACHE_TTL {
+		return role, nil
+	}
+
+	roleEntity, dbError := s.rbacStorage.GetRoleById(ctx, roleId)
+	if dbError != nil {
+		log.Warn("error get role by id", slog.Int64("role_id", roleId), slog.String("err", dbError.Message))
+		return role, errorsApp.ErrForbidden.Error
+	}
+	permissions, dbError := s.rbacStorage.GetPermissionsByRoleId(ctx, roleId)
+	if dbError != nil {
+		log.Error("error get permissions by role id", slog.String("err", dbError.Message))
+		return role, errorsApp.ErrInternalError.Error
+	}
+
+	role = rbacRole{
+		name:        roleEntity.Name,
+		permissions: make(map[string]bool, len(permissions)),
+		loadedAt:    time.Now(),
+	}
+	for _, permission := range permissions {
+		role.permissions[permission.Name] = true
+	}
+
+	s.mu.Lock()
+	s.roles[roleId] = role
+	s.mu.Unlock()
+
+	return role, nil
+}
+
+// HasRole - роль пользователя входит в список разрешенных
+func (s *RbacService) HasRole(ctx context.Context, roleId int64, roles ...string) (bool, error) {
+	role, err := s.role(ctx, roleId)
+	if err != nil {
+		return false, err
+	}
+	for _, name := range roles {
+		if role.name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// HasPermissions - у роли есть все перечисленные права
+func (s *RbacService) HasPermissions(ctx context.Context, roleId int64, permissions ...string) (bool, error) {
+	role, err := s.role(ctx, roleId)
+	if err != nil {
+		return false, err
+	}
+	for _, permission := range permissions {
+		if !role.permissions[permission] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
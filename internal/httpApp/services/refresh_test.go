@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/config"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/db/cache"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
+)
+
+type fakeRefreshAuthStorage struct {
+	authStorage
+}
+
+func (f *fakeRefreshAuthStorage) GetUserById(ctx context.Context, id int64) (models.UserEntity, *errorsApp.DbError) {
+	return models.UserEntity{Id: id, Name: "user", Role_id: 2}, nil
+}
+
+func (f *fakeRefreshAuthStorage) GetRoleById(ctx context.Context, id int64) (models.RoleEntity, *errorsApp.DbError) {
+	return models.RoleEntity{Id: id, Name: "user"}, nil
+}
+
+func (f *fakeRefreshAuthStorage) NewAuthEvent(ctx context.Context, event models.AuthEventEntity) *errorsApp.DbError {
+	return nil
+}
+
+// fakeFamilySessionStorage повторяет семантику ротации из cache: старый jti удаляется и запоминается как выведенный
+type fakeFamilySessionStorage struct {
+	sessionStorage
+	sessions map[string]cache.SessionData
+	retired  map[string]string // jti -> family
+}
+
+func (f *fakeFamilySessionStorage) GetSessionByJti(ctx context.Context, jti string) (cache.SessionData, *errorsApp.DbError) {
+	data, ok := f.sessions[jti]
+	if !ok {
+		return data, &errorsApp.DbError{Type: "not_found", Message: "session not found"}
+	}
+	return data, nil
+}
+
+func (f *fakeFamilySessionStorage) RotateSession(ctx context.Context, oldJti string, newJti string, data cache.SessionData, ttlHours int) *errorsApp.DbError {
+	if _, ok := f.sessions[oldJti]; !ok {
+		return &errorsApp.DbError{Type: "not_found", Message: "session not found"}
+	}
+	delete(f.sessions, oldJti)
+	f.retired[oldJti] = data.FamilyID
+	data.Jti = newJti
+	data.ParentJti = oldJti
+	f.sessions[newJti] = data
+	return nil
+}
+
+func (f *fakeFamilySessionStorage) GetRetiredJtiFamily(ctx context.Context, jti string) (string, *errorsApp.DbError) {
+	return f.retired[jti], nil
+}
+
+func (f *fakeFamilySessionStorage) DeleteSessionFamily(ctx context.Context, familyId string, userId int64) *errorsApp.DbError {
+	for jti, data := range f.sessions {
+		if data.FamilyID == familyId {
+			delete(f.sessions, jti)
+		}
+	}
+	return nil
+}
+
+func TestRefreshReuseDetection(t *testing.T) {
+	// family - сессия, чей токен предъявляется; refresh - номер токена в ее цепочке:
+	// 0 - выданный при входе, n - полученный после n-го refresh
+	type step struct {
+		family  string
+		refresh int
+		wantErr error
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name:  "each token refreshes once",
+			steps: []step{{family: "a", refresh: 0}, {family: "a", refresh: 1}, {family: "a", refresh: 2}},
+		},
+		{
+			name: "reuse of rotated token revokes the family",
+			steps: []step{
+				{family: "a", refresh: 0},
+				{family: "a", refresh: 0, wantErr: errorsApp.ErrRefreshTokenReused.Error},
+				{family: "a", refresh: 1, wantErr: errorsApp.ErrSessionNotFound.Error},
+			},
+		},
+		{
+			name: "other family survives reuse",
+			steps: []step{
+				{family: "a", refresh: 0},
+				{family: "a", refresh: 0, wantErr: errorsApp.ErrRefreshTokenReused.Error},
+				{family: "b", refresh: 0},
+			},
+		},
+		{
+			name:  "unknown token is not reuse",
+			steps: []step{{family: "unknown", refresh: 0, wantErr: errorsApp.ErrSessionNotFound.Error}},
+		},
+	}
+
+	cfg := &config.Config{
+		SERVICE_NAME:                  "test",
+		AUTH_ACCESS_TOKEN_EXP_MINUTES: 15,
+		AUTH_REFRESH_TOKEN_EXP_HOURS:  24,
+	}
+	jwtKeys, err := lib.LoadJWTKeySet("HS256", "test-secret", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshToken := func(t *testing.T, jti string) string {
+		token, err := lib.CreateJWT(lib.JWTClaims{UserId: 1, Jti: jti, Iss: cfg.SERVICE_NAME}, jwtKeys, time.Hour, "refresh")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessionStorage := &fakeFamilySessionStorage{
+				sessions: map[string]cache.SessionData{
+					"a": {Jti: "a", UserID: 1, FamilyID: "a"},
+					"b": {Jti: "b", UserID: 1, FamilyID: "b"},
+				},
+				retired: make(map[string]string),
+			}
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			service := NewAuthService(log, &fakeRefreshAuthStorage{}, sessionStorage, nil, nil, jwtKeys, nil, cfg)
+			ctx := context.Background()
+
+			// цепочки выданных refresh-токенов по семействам
+			chains := map[string][]string{
+				"a":       {refreshToken(t, "a")},
+				"b":       {refreshToken(t, "b")},
+				"unknown": {refreshToken(t, "unknown")},
+			}
+			for i, s := range tt.steps {
+				res, err := service.Refresh(ctx, chains[s.family][s.refresh], "127.0.0.1", "test-agent")
+				if !errors.Is(err, s.wantErr) {
+					t.Fatalf("step %d: expected error %v, got %v", i+1, s.wantErr, err)
+				}
+				if err == nil {
+					chains[s.family] = append(chains[s.family], res.RefreshToken)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
+	"github.com/guregu/null/v6"
+)
+
+// сколько последних успешных входов просматривает isNewDevice
+const newDeviceLookback = 100
+
+// securityNotice - вид уведомления о действии с аккаунтом
+type securityNotice struct {
+	Subject string
+	Action  string
+}
+
+var (
+	securityNoticePasswordChanged = securityNotice{Subject: "Password changed", Action: "The password of your account was changed"}
+	securityNoticeNewDevice       = securityNotice{Subject: "New sign-in", Action: "Your account was signed in from a new device"}
+	securityNoticeSessionRevoked  = securityNotice{Subject: "Session revoked", Action: "A session of your account was revoked"}
+	securityNoticePasskeyAdded    = securityNotice{Subject: "Login key added", Action: "A new login key (passkey) was added to your account"}
+)
+
+// один шаблон для email и SMS, поэтому текст без разметки
+var securityNoticeTemplate = template.Must(template.New("security_notice").Parse(`{{.Action}} ({{.Service}}).
+
+Time: {{.Time}}
+Device: {{.Device}}
+IP: {{.IP}}{{if .Location}} ({{.Location}}){{end}}
+{{- if .Target}}
+Revoked session: {{.Target}}
+{{- end}}
+
+If this wasn't you, sign out everywhere: {{.RevokeUrl}}
+Then change your password.
+`))
+
+type securityNoticeData struct {
+	securityNotice
+	Service   string
+	Time      string
+	Device    string
+	IP        string
+	Location  string
+	Target    string
+	RevokeUrl string
+}
+
+// securityNotify отправляет уведомление на все подтвержденные адреса пользователя с устройством и IP запроса
+// и ссылкой, по которой можно завершить все сессии. target - описание затронутой сессии, необязательно
+func (s *AuthService) securityNotify(user models.UserEntity, notice securityNotice, ip string, user_agent string, target string) {
+	op := "services.securityNotify"
+	log := s.log.With(slog.String("op", op))
+
+	country, city := s.geoIP.Lookup(ip)
+	data := securityNoticeData{
+		securityNotice: notice,
+		Service:        s.cfg.SERVICE_NAME,
+		Time:           time.Now().Format(time.RFC1123),
+		Device:         deviceDescription(user_agent),
+		IP:             ip,
+		Location:       joinNotEmpty(city, country),
+		Target:         target,
+		RevokeUrl:      s.revokeAllUrl(user.Id),
+	}
+
+	buf := bytes.Buffer{}
+	if err := securityNoticeTemplate.Execute(&buf, data); err != nil {
+		log.Error("error execute template", slog.String("err", err.Error()))
+		return
+	}
+	s.notifyUser(user, notice.Subject+" - "+s.cfg.SERVICE_NAME, buf.String())
+}
+
+// revokeAllUrl - подписанная ссылка "это был не я", действует SECURITY_NOTIFY_REVOKE_LINK_TTL
+func (s *AuthService) revokeAllUrl(userId int64) string {
+	id := strconv.FormatInt(userId, 10)
+	expires := strconv.FormatInt(time.Now().Add(s.cfg.SECURITY_NOTIFY_REVOKE_LINK_TTL).Unix(), 10)
+	query := url.Values{}
+	query.Set("user_id", id)
+	query.Set("expires", expires)
+	query.Set("signature", lib.SignString("revoke-all:"+id+":"+expires, s.cfg.AUTH_SECRET_KEY))
+	return s.cfg.SECURITY_NOTIFY_BASE_URL + "/api/security/revoke-all?" + query.Encode()
+}
+
+// CheckRevokeLink проверяет подпись и срок ссылки "это был не я" без изменений - для страницы подтверждения
+func (s *AuthService) CheckRevokeLink(ctx context.Context, userId string, expires string, signature string) error {
+	_, err := s.parseRevokeLink(userId, expires, signature)
+	return err
+}
+
+// RevokeAllByLink завершает все сессии пользователя по ссылке из уведомления, без access-токена.
+// Вызывается только POST со страницы подтверждения: GET по ссылке открывают и почтовые сканеры.
+// Повторный вызов безопасен - сессий просто не останется
+func (s *AuthService) RevokeAllByLink(ctx context.Context, userId string, expires string, signature string, ip string, user_agent string) (dto.AuthLogoutAllResponse, error) {
+	op := "services.RevokeAllByLink"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthLogoutAllResponse{}
+
+	id, err := s.parseRevokeLink(userId, expires, signature)
+	if err != nil {
+		return response, err
+	}
+
+	revoked, dbError := s.sessionStorage.DeleteSessionsByUserIdExcept(ctx, id, "")
+	if dbError != nil {
+		log.Error("error delete sessions by user id", slog.String("err", dbError.Message))
+		return response, errorsApp.ErrInternalError.Error
+	}
+	response.Revoked = revoked
+
+	log.Info("all sessions revoked by link", slog.Int64("user_id", id), slog.Int64("revoked", revoked))
+	s.audit(ctx, authEvent{Type: models.AuthEventLogoutAll, UserId: id, IP: ip, UserAgent: user_agent, Reason: "revoke link, revoked " + strconv.FormatInt(revoked, 10)})
+	return response, nil
+}
+
+// parseRevokeLink возвращает id пользователя из ссылки "это был не я", если подпись верна и срок не истек
+func (s *AuthService) parseRevokeLink(userId string, expires string, signature string) (int64, error) {
+	op := "services.parseRevokeLink"
+	log := s.log.With(slog.String("op", op))
+
+	id, err := strconv.ParseInt(userId, 10, 64)
+	if err != nil || !lib.VerifySignedString("revoke-all:"+userId+":"+expires, signature, s.cfg.AUTH_SECRET_KEY) {
+		log.Warn("invalid revoke link signature", slog.String("user_id", userId))
+		return 0, errorsApp.ErrRevokeLinkInvalid.Error
+	}
+	expiresUnix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresUnix {
+		log.Warn("revoke link expired", slog.Int64("user_id", id))
+		return 0, errorsApp.ErrRevokeLinkInvalid.Error
+	}
+	return id, nil
+}
+
+// isNewDevice - вход с устройства (браузер без версии, ОС, тип устройства), с которого у пользователя
+// не было успешных входов среди последних newDeviceLookback. Обновление браузера новым устройством не считается,
+// первый вход пользователя - тоже. Вызывать до записи текущего входа в журнал
+func (s *AuthService) isNewDevice(ctx context.Context, userId int64, user_agent string) bool {
+	op := "services.isNewDevice"
+	log := s.log.With(slog.String("op", op))
+
+	filter := models.AuthEventsFilter{
+		UserId:    null.IntFrom(userId),
+		EventType: string(models.AuthEventLogin),
+		Outcome:   models.AuthEventSuccess,
+		Limit:     newDeviceLookback,
+	}
+	events, _, dbError := s.authStorage.ListAuthEvents(ctx, filter)
+	if dbError != nil {
+		log.Error("error list auth events", slog.String("err", dbError.Message))
+		return false
+	}
+	if len(events) == 0 {
+		return false
+	}
+
+	current := deviceKey(user_agent)
+	for _, event := range events {
+		if deviceKey(event.User_agent.String) == current {
+			return false
+		}
+	}
+	return true
+}
+
+// deviceKey - устройство для сравнения входов, версия браузера не учитывается
+func deviceKey(user_agent string) lib.DeviceInfo {
+	device := lib.ParseUserAgent(user_agent)
+	device.Browser = ""
+	return device
+}
+
+func deviceDescription(user_agent string) string {
+	device := lib.ParseUserAgent(user_agent)
+	description := joinNotEmpty(device.Browser, device.OS, device.Device)
+	if description == "" {
+		return "unknown"
+	}
+	return description
+}
+
+func joinNotEmpty(parts ...string) string {
+	result := ""
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		if result != "" {
+			result += ", "
+		}
+		result += part
+	}
+	return result
+}
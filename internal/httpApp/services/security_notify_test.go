@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/config"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
+	"github.com/guregu/null/v6"
+)
+
+const (
+	uaChromeWindows     = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	uaChromeWindowsNext = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36"
+	uaFirefoxWindows    = "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0"
+	uaChromeMac         = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	uaChromeAndroid     = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36"
+)
+
+// fakeLoginHistoryStorage возвращает успешные входы с заданными user agent
+type fakeLoginHistoryStorage struct {
+	authStorage
+	userAgents []string
+}
+
+func (f *fakeLoginHistoryStorage) ListAuthEvents(ctx context.Context, filter models.AuthEventsFilter) ([]models.AuthEventEntity, int64, *errorsApp.DbError) {
+	events := make([]models.AuthEventEntity, 0, len(f.userAgents))
+	for _, userAgent := range f.userAgents {
+		events = append(events, models.AuthEventEntity{
+			User_id:    filter.UserId,
+			Event_type: models.AuthEventLogin,
+			Outcome:    models.AuthEventSuccess,
+			User_agent: null.StringFrom(userAgent),
+		})
+	}
+	return events, int64(len(events)), nil
+}
+
+func TestIsNewDevice(t *testing.T) {
+	tests := []struct {
+		name      string
+		history   []string
+		userAgent string
+		want      bool
+	}{
+		{name: "first login", history: nil, userAgent: uaChromeWindows, want: false},
+		{name: "same browser", history: []string{uaChromeWindows}, userAgent: uaChromeWindows, want: false},
+		{name: "browser updated", history: []string{uaChromeWindows}, userAgent: uaChromeWindowsNext, want: false},
+		{name: "other browser", history: []string{uaChromeWindows}, userAgent: uaFirefoxWindows, want: true},
+		{name: "other os", history: []string{uaChromeWindows}, userAgent: uaChromeMac, want: true},
+		{name: "other device type", history: []string{uaChromeWindows}, userAgent: uaChromeAndroid, want: true},
+		{name: "known among several", history: []string{uaChromeWindows, uaChromeAndroid}, userAgent: uaChromeAndroid, want: false},
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewAuthService(log, &fakeLoginHistoryStorage{userAgents: tt.history}, nil, nil, nil, nil, nil, &config.Config{})
+			if got := service.isNewDevice(context.Background(), 1, tt.userAgent); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseRevokeLink(t *testing.T) {
+	const secret = "test-secret"
+	sign := func(userId string, expires string, secret string) string {
+		return lib.SignString("revoke-all:"+userId+":"+expires, secret)
+	}
+	valid := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	expired := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		userId    string
+		expires   string
+		signature string
+		wantErr   error
+	}{
+		{name: "valid", userId: "1", expires: valid, signature: sign("1", valid, secret)},
+		{name: "expired", userId: "1", expires: expired, signature: sign("1", expired, secret), wantErr: errorsApp.ErrRevokeLinkInvalid.Error},
+		{name: "another user", userId: "2", expires: valid, signature: sign("1", valid, secret), wantErr: errorsApp.ErrRevokeLinkInvalid.Error},
+		{name: "extended expiry", userId: "1", expires: valid + "0", signature: sign("1", valid, secret), wantErr: errorsApp.ErrRevokeLinkInvalid.Error},
+		{name: "another secret", userId: "1", expires: valid, signature: sign("1", valid, "other-secret"), wantErr: errorsApp.ErrRevokeLinkInvalid.Error},
+		{name: "not a number", userId: "x", expires: valid, signature: sign("x", valid, secret), wantErr: errorsApp.ErrRevokeLinkInvalid.Error},
+		{name: "empty signature", userId: "1", expires: valid, signature: "", wantErr: errorsApp.ErrRevokeLinkInvalid.Error},
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	service := NewAuthService(log, nil, nil, nil, nil, nil, nil, &config.Config{AUTH_SECRET_KEY: secret})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := service.parseRevokeLink(tt.userId, tt.expires, tt.signature)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && strconv.FormatInt(id, 10) != tt.userId {
+				t.Fatalf("expected user id %s, got %d", tt.userId, id)
+			}
+		})
+	}
+}
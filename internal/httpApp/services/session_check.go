@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/config"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+)
+
+// при переполнении кэш чистится от устаревших записей
+const sessionCheckCacheMaxSize = 10000
+
+// SessionCheckService проверяет, что сессия access-токена не отозвана. Ответ Redis кэшируется
+// в памяти на AUTH_SESSION_CHECK_CACHE_TTL - на это время отзыв может запаздывать
+type SessionCheckService struct {
+	log            *slog.Logger
+	sessionStorage sessionCheckStorage
+	cfg            *config.Config
+
+	mu       sync.RWMutex
+	sessions map[string]sessionCheck
+}
+
+type sessionCheckStorage interface {
+	SessionExists(ctx context.Context, jti string) (bool, *errorsApp.DbError)
+}
+
+type sessionCheck struct {
+	alive     bool
+	checkedAt time.Time
+}
+
+func NewSessionCheckService(log *slog.Logger,
+	sessionStorage sessionCheckStorage,
+	cfg *config.Config) *SessionCheckService {
+	return &SessionCheckService{
+		log:            log,
+		sessionStorage: sessionStorage,
+		cfg:            cfg,
+		sessions:       make(map[string]sessionCheck),
+	}
+}
+
+// IsSessionAlive - сессия с jti есть в Redis
+func (s *SessionCheckService) IsSessionAlive(ctx context.Context, jti string) (bool, error) {
+	op := "services.SessionCheckService.IsSessionAlive"
+	log := s.log.With(slog.String("op", op))
+
+	if jti == "" {
+		return false, nil
+	}
+
+	s.mu.RLock()
+	check, ok := s.sessions[jti]
+	s.mu.RUnlock()
+	if ok && time.Since(check.checkedAt) < s.cfg.AUTH_SESSION_CHECK_CACHE_TTL {
+		return check.alive, nil
+	}
+
+	alive, dbError := s.sessionStorage.SessionExists(ctx, jti)
+	if dbError != nil {
+		log.Error("error check session", slog.String("err", dbError.Message))
+		return false, errorsApp.ErrInternalError.Error
+	}
+
+	s.mu.Lock()
+	if len(s.sessions) >= sessionCheckCacheMaxSize {
+		s.purge()
+	}
+	s.sessions[jti] = sessionCheck{alive: alive, checkedAt: time.Now()}
+	s.mu.Unlock()
+
+	return alive, nil
+}
+
+// purge вызывается под s.mu
+func (s *SessionCheckService) purge() {
+	for jti, check := range s.sessions {
+		if time.Since(check.checkedAt) >= s.cfg.AUTH_SESSION_CHECK_CACHE_TTL {
+			delete(s.sessions, jti)
+		}
+	}
+	if len(s.sessions) >= sessionCheckCacheMaxSize {
+		s.sessions = make(map[string]sessionCheck)
+	}
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/db/cache"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
+)
+
+// setSessionClient записывает в сессию IP, UserAgent, разобранное устройство и геолокацию
+func (s *AuthService) setSessionClient(data *cache.SessionData, ip string, user_agent string) {
+	device := lib.ParseUserAgent(user_agent)
+	data.IP = ip
+	data.UserAgent = user_agent
+	data.Browser = device.Browser
+	data.OS = device.OS
+	data.Device = device.Device
+	data.Country, data.City = s.geoIP.Lookup(ip)
+}
+
+func sessionDto(session cache.SessionData, user models.UserEntity) dto.AuthSession {
+	return dto.AuthSession{
+		Jti:               session.Jti,
+		User_id:           session.UserID,
+		User_name:         user.Name,
+		User_email:        user.Email.String,
+		User_phone_number: user.Phone_number.String,
+		Role_id:           session.RoleID,
+		User_agent:        session.UserAgent,
+		IP:                session.IP,
+		Browser:           session.Browser,
+		OS:                session.OS,
+		Device:            session.Device,
+		Country:           session.Country,
+		City:              session.City,
+		Created_at:        session.CreatedAt,
+		Last_used_at:      session.LastUsedAt,
+	}
+}
@@ -0,0 +1,324 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/db/cache"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
+	"github.com/google/uuid"
+)
+
+const recoveryCodesCount = 10
+
+var totpCodeRegexp = regexp.MustCompile(`^[0-9]{6}$`)
+
+func (s *AuthService) TotpStatus(ctx context.Context, userId int64) (dto.AuthTotpStatusResponse, error) {
+	op := "services.TotpStatus"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthTotpStatusResponse{}
+
+	userEntity, dbError := s.authStorage.GetUserById(ctx, userId)
+	if dbError != nil {
+		log.Warn("error get user by id", slog.String("err", dbError.Message))
+		return response, dbError.Error
+	}
+	response.Enabled = userEntity.Totp_enabled_at.Valid
+	response.Enabled_at = userEntity.Totp_enabled_at
+	if !response.Enabled {
+		return response, nil
+	}
+
+	count, dbError := s.authStorage.CountRecoveryCodes(ctx, userId)
+	if dbError != nil {
+		log.Error("error count recovery codes", slog.String("err", dbError.Message))
+		return response, errorsApp.ErrInternalError.Error
+	}
+	response.RecoveryCodesLeft = count
+	return response, nil
+}
+
+// TotpEnroll создает новый секрет, 2FA включится только после TotpConfirm кодом из приложения
+func (s *AuthService) TotpEnroll(ctx context.Context, userId int64) (dto.AuthTotpEnrollResponse, error) {
+	op := "services.TotpEnroll"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthTotpEnrollResponse{}
+
+	userEntity, dbError := s.authStorage.GetUserById(ctx, userId)
+	if dbError != nil {
+		log.Warn("error get user by id", slog.String("err", dbError.Message))
+		return response, dbError.Error
+	}
+	if userEntity.Totp_enabled_at.Valid {
+		return response, errorsApp.ErrTotpAlreadyEnabled.Error
+	}
+
+	issuer := s.cfg.AUTH_TOTP_ISSUER
+	if issuer == "" {
+		issuer = s.cfg.SERVICE_NAME
+	}
+	account := userEntity.Email.String
+	if account == "" {
+		account = userEntity.Phone_number.String
+	}
+	if account == "" {
+		account = userEntity.Name
+	}
+
+	key, err := lib.GenerateTotpKey(issuer, account)
+	if err != nil {
+		log.Error("error generate totp key", slog.String("err", err.Error()))
+		return response, errorsApp.ErrInternalError.Error
+	}
+	encrypted, err := lib.EncryptString(key.Secret, s.cfg.AUTH_TOTP_ENCRYPTION_KEY)
+	if err != nil {
+		log.Error("error encrypt totp secret", slog.String("err", err.Error()))
+		return response, errorsApp.ErrInternalError.Error
+	}
+	if dbError := s.authStorage.SetUserTotpSecret(ctx, userId, encrypted); dbError != nil {
+		log.Error("error save totp secret", slog.String("err", dbError.Message))
+		return response, dbError.Error
+	}
+
+	response.Secret = key.Secret
+	response.OtpauthURI = key.URL
+	response.QrPng = key.QRPng
+	return response, nil
+}
+
+// TotpConfirm включает 2FA после проверки первого кода и возвращает коды восстановления
+func (s *AuthService) TotpConfirm(ctx context.Context, userId int64, code string) (dto.AuthTotpRecoveryCodesResponse, error) {
+	op := "services.TotpConfirm"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthTotpRecoveryCodesResponse{}
+
+	userEntity, dbError := s.authStorage.GetUserById(ctx, userId)
+	if dbError != nil {
+		log.Warn("error get user by id", slog.String("err", dbError.Message))
+		return response, dbError.Error
+	}
+	if userEntity.Totp_enabled_at.Valid {
+		return response, errorsApp.ErrTotpAlreadyEnabled.Error
+	}
+	if !userEntity.Totp_secret.Valid {
+		log.Warn("totp enrollment not started", slog.Int64("user_id", userId))
+		return response, errorsApp.ErrTotpNotEnabled.Error
+	}
+
+	if err := s.checkSecondFactor(ctx, userEntity, code, false); err != nil {
+		return response, err
+	}
+
+	codes, err := lib.GenerateRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		log.Error("error generate recovery codes", slog.String("err", err.Error()))
+		return response, errorsApp.ErrInternalError.Error
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, c := range codes {
+		hashes = append(hashes, lib.HashRecoveryCode(c))
+	}
+	if dbError := s.authStorage.EnableUserTotp(ctx, userId, hashes); dbError != nil {
+		log.Error("error enable totp", slog.String("err", dbError.Message))
+		return response, dbError.Error
+	}
+	log.Info("totp enabled", slog.Int64("user_id", userId))
+
+	response.RecoveryCodes = codes
+	return response, nil
+}
+
+// TotpDisable выключает 2FA, нужен код из приложения или код восстановления
+func (s *AuthService) TotpDisable(ctx context.Context, userId int64, code string) error {
+	op := "services.TotpDisable"
+	log := s.log.With(slog.String("op", op))
+
+	userEntity, dbError := s.authStorage.GetUserById(ctx, userId)
+	if dbError != nil {
+		log.Warn("error get user by id", slog.String("err", dbError.Message))
+		return dbError.Error
+	}
+	if !userEntity.Totp_enabled_at.Valid {
+		return errorsApp.ErrTotpNotEnabled.Error
+	}
+
+	if err := s.checkSecondFactor(ctx, userEntity, code, true); err != nil {
+		return err
+	}
+
+	if dbError := s.authStorage.DisableUserTotp(ctx, userId); dbError != nil {
+		log.Error("error disable totp", slog.String("err", dbError.Message))
+		return dbError.Error
+	}
+	log.Info("totp disabled", slog.Int64("user_id", userId))
+	return nil
+}
+
+// completeLogin - завершение входа после проверки первого фактора: при включенной 2FA
+// вместо токенов выдает challenge для /auth/2fa/verify
+func (s *AuthService) completeLogin(ctx context.Context, userEntity models.UserEntity, ip string, user_agent string) (dto.AuthLoginResponse, error) {
+	op := "services.completeLogin"
+	log := s.log.With(slog.String("op", op))
+
+	if !userEntity.Totp_enabled_at.Valid {
+		return s.issueTokens(ctx, userEntity, ip, user_agent)
+	}
+
+	response := dto.AuthLoginResponse{}
+	// блокировку проверяем до второго фактора, чтобы не подтверждать заблокированному верный пароль
+	if userEntity.Blocked_at.Valid {
+		log.Warn("user is blocked", slog.Int64("user_id", userEntity.Id))
+		s.audit(ctx, authEvent{Type: models.AuthEventLogin, UserId: userEntity.Id, IP: ip, UserAgent: user_agent, Err: errorsApp.ErrUserBlocked.Error})
+		return response, errorsApp.ErrUserBlocked.Error
+	}
+
+	token := uuid.NewString()
+	dbError := s.sessionStorage.SaveMfaChallenge(ctx, cache.MfaChallengeData{
+		Token:     token,
+		UserID:    userEntity.Id,
+		IP:        ip,
+		UserAgent: user_agent,
+	}, s.cfg.AUTH_MFA_CHALLENGE_TTL)
+	if dbError != nil {
+		log.Error("error save mfa challenge", slog.String("err", dbError.Message))
+		return response, errorsApp.ErrInternalError.Error
+	}
+
+	response.Id = userEntity.Id
+	response.Name = userEntity.Name
+	response.MfaRequired = true
+	response.MfaToken = token
+	return response, nil
+}
+
+// MfaVerify проверяет второй фактор по challenge из Login и выдает пару токенов
+func (s *AuthService) MfaVerify(ctx context.Context, body dto.AuthMfaVerifyRequest, ip string, user_agent string) (dto.AuthLoginResponse, error) {
+	op := "services.MfaVerify"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthLoginResponse{}
+
+	challenge, dbError := s.sessionStorage.GetMfaChallenge(ctx, body.MfaToken)
+	if dbError != nil {
+		if dbError.Type == "not_found" {
+			return response, errorsApp.ErrMfaChallengeNotFound.Error
+		}
+		return response, errorsApp.ErrInternalError.Error
+	}
+
+	// challenge привязан к клиенту, прошедшему пароль: с другого IP или браузера он недействителен
+	if challenge.IP != ip || challenge.UserAgent != user_agent {
+		log.Warn("mfa challenge used by another client", slog.Int64("user_id", challenge.UserID), slog.String("ip", ip))
+		return response, errorsApp.ErrMfaChallengeNotFound.Error
+	}
+
+	userEntity, dbError := s.authStorage.GetUserById(ctx, challenge.UserID)
+	if dbError != nil {
+		log.Warn("error get user by id", slog.String("err", dbError.Message))
+		return response, dbError.Error
+	}
+	if !userEntity.Totp_enabled_at.Valid {
+		// 2FA отключили, пока challenge был жив - просим войти заново
+		return response, errorsApp.ErrMfaChallengeNotFound.Error
+	}
+
+	if err := s.checkSecondFactor(ctx, userEntity, body.Code, true); err != nil {
+		s.audit(ctx, authEvent{Type: models.AuthEventLogin, UserId: userEntity.Id, IP: ip, UserAgent: user_agent, Err: err})
+		return response, err
+	}
+
+	// challenge одноразовый: при параллельных запросах токены получит только первый
+	deleted, dbError := s.sessionStorage.DeleteMfaChallenge(ctx, body.MfaToken)
+	if dbError != nil {
+		return response, errorsApp.ErrInternalError.Error
+	}
+	if !deleted {
+		return response, errorsApp.ErrMfaChallengeNotFound.Error
+	}
+
+	return s.issueTokens(ctx, userEntity, ip, user_agent)
+}
+
+// checkSecondFactor проверяет код TOTP (каждый интервал принимается один раз) или, если allowRecovery,
+// одноразовый код восстановления. Неверные коды учитываются как неудачные попытки входа
+func (s *AuthService) checkSecondFactor(ctx context.Context, userEntity models.UserEntity, code string, allowRecovery bool) error {
+	op := "services.checkSecondFactor"
+	log := s.log.With(slog.String("op", op), slog.Int64("user_id", userEntity.Id))
+
+	lockKey := "mfa:" + strconv.FormatInt(userEntity.Id, 10)
+
+	lock, dbError := s.limitStorage.GetLoginLock(ctx, lockKey)
+	if dbError != nil {
+		log.Error("error get mfa lock", slog.String("err", dbError.Message))
+		return errorsApp.ErrInternalError.Error
+	}
+	if lock > 0 {
+		log.Warn("mfa locked", slog.Duration("retry_after", lock))
+		return &errorsApp.RetryError{Err: errorsApp.ErrTooManyAttempts, RetryAfter: lock}
+	}
+
+	valid, err := s.validateSecondFactor(ctx, userEntity, code, allowRecovery)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		log.Warn("invalid second factor code")
+		_, dbError := s.limitStorage.AddLoginFailure(ctx, lockKey, s.cfg.AUTH_LOGIN_ATTEMPTS_WINDOW,
+			s.cfg.AUTH_MFA_MAX_ATTEMPTS, s.cfg.AUTH_LOGIN_LOCK_BASE, s.cfg.AUTH_LOGIN_LOCK_MAX)
+		if dbError != nil {
+			log.Error("error add mfa failure", slog.String("err", dbError.Message))
+		}
+		return errorsApp.ErrTotpInvalidCode.Error
+	}
+
+	if dbError := s.limitStorage.ResetLoginFailures(ctx, lockKey); dbError != nil {
+		log.Warn("error reset mfa failures", slog.String("err", dbError.Message))
+	}
+	return nil
+}
+
+func (s *AuthService) validateSecondFactor(ctx context.Context, userEntity models.UserEntity, code string, allowRecovery bool) (bool, error) {
+	op := "services.validateSecondFactor"
+	log := s.log.With(slog.String("op", op), slog.Int64("user_id", userEntity.Id))
+
+	if totpCodeRegexp.MatchString(code) {
+		secret, err := lib.DecryptString(userEntity.Totp_secret.String, s.cfg.AUTH_TOTP_ENCRYPTION_KEY)
+		if err != nil {
+			log.Error("error decrypt totp secret", slog.String("err", err.Error()))
+			return false, errorsApp.ErrInternalError.Error
+		}
+		step, ok := lib.ValidateTotp(code, secret, time.Now())
+		if !ok {
+			return false, nil
+		}
+		fresh, dbError := s.otpStorage.UseTotpStep(ctx, userEntity.Id, step, 2*time.Minute)
+		if dbError != nil {
+			return false, errorsApp.ErrInternalError.Error
+		}
+		if !fresh {
+			log.Warn("totp code replay")
+		}
+		return fresh, nil
+	}
+
+	if !allowRecovery {
+		return false, nil
+	}
+	used, dbError := s.authStorage.UseRecoveryCode(ctx, userEntity.Id, lib.HashRecoveryCode(code))
+	if dbError != nil {
+		return false, errorsApp.ErrInternalError.Error
+	}
+	if used {
+		log.Info("recovery code used")
+	}
+	return used, nil
+}
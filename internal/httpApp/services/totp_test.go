@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/config"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
+	"github.com/guregu/null/v6"
+	"github.com/pquerna/otp/totp"
+)
+
+type fakeTotpAuthStorage struct {
+	authStorage
+	recoveryHashes map[string]bool
+}
+
+func (f *fakeTotpAuthStorage) UseRecoveryCode(ctx context.Context, id int64, codeHash string) (bool, *errorsApp.DbError) {
+	if !f.recoveryHashes[codeHash] {
+		return false, nil
+	}
+	delete(f.recoveryHashes, codeHash)
+	return true, nil
+}
+
+type fakeTotpOtpStorage struct {
+	otpStorage
+	usedSteps map[int64]bool
+}
+
+func (f *fakeTotpOtpStorage) UseTotpStep(ctx context.Context, userId int64, step int64, ttl time.Duration) (bool, *errorsApp.DbError) {
+	if f.usedSteps[step] {
+		return false, nil
+	}
+	f.usedSteps[step] = true
+	return true, nil
+}
+
+// fakeLimitStorage блокирует ключ после maxAttempts неудач на lockBase
+type fakeLimitStorage struct {
+	failures map[string]int
+	locks    map[string]time.Duration
+}
+
+func newFakeLimitStorage() *fakeLimitStorage {
+	return &fakeLimitStorage{failures: make(map[string]int), locks: make(map[string]time.Duration)}
+}
+
+func (f *fakeLimitStorage) GetLoginLock(ctx context.Context, key string) (time.Duration, *errorsApp.DbError) {
+	return f.locks[key], nil
+}
+
+func (f *fakeLimitStorage) AddLoginFailure(ctx context.Context, key string, window time.Duration, maxAttempts int, lockBase time.Duration, lockMax time.Duration) (time.Duration, *errorsApp.DbError) {
+	f.failures[key]++
+	if f.failures[key] >= maxAttempts {
+		f.locks[key] = lockBase
+	}
+	return f.locks[key], nil
+}
+
+func (f *fakeLimitStorage) ResetLoginFailures(ctx context.Context, keys ...string) *errorsApp.DbError {
+	for _, key := range keys {
+		delete(f.failures, key)
+		delete(f.locks, key)
+	}
+	return nil
+}
+
+func TestCheckSecondFactor(t *testing.T) {
+	const secret = "JBSWY3DPEHPK3PXP"
+	const recoveryCode = "a1b2c-3d4e5"
+
+	cfg := &config.Config{
+		AUTH_TOTP_ENCRYPTION_KEY:   "totp-key",
+		AUTH_MFA_MAX_ATTEMPTS:      2,
+		AUTH_LOGIN_ATTEMPTS_WINDOW: time.Minute,
+		AUTH_LOGIN_LOCK_BASE:       time.Minute,
+		AUTH_LOGIN_LOCK_MAX:        time.Hour,
+	}
+	encrypted, err := lib.EncryptString(secret, cfg.AUTH_TOTP_ENCRYPTION_KEY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := models.UserEntity{Id: 7, Totp_secret: null.StringFrom(encrypted)}
+
+	validCode, err := totp.GenerateCode(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrongCode := "000000"
+	if wrongCode == validCode {
+		wrongCode = "111111"
+	}
+
+	type attempt struct {
+		code          string
+		allowRecovery bool
+		wantErr       error
+	}
+	tests := []struct {
+		name     string
+		attempts []attempt
+	}{
+		{
+			name:     "valid totp code",
+			attempts: []attempt{{code: validCode}},
+		},
+		{
+			name:     "totp code is accepted once per step",
+			attempts: []attempt{{code: validCode}, {code: validCode, wantErr: errorsApp.ErrTotpInvalidCode.Error}},
+		},
+		{
+			name: "wrong codes lock the second factor",
+			attempts: []attempt{
+				{code: wrongCode, wantErr: errorsApp.ErrTotpInvalidCode.Error},
+				{code: wrongCode, wantErr: errorsApp.ErrTotpInvalidCode.Error},
+				{code: validCode, wantErr: errorsApp.ErrTooManyAttempts.Error},
+			},
+		},
+		{
+			name:     "recovery code once when allowed",
+			attempts: []attempt{{code: recoveryCode, allowRecovery: true}, {code: recoveryCode, allowRecovery: true, wantErr: errorsApp.ErrTotpInvalidCode.Error}},
+		},
+		{
+			name:     "recovery code is refused when not allowed",
+			attempts: []attempt{{code: recoveryCode, wantErr: errorsApp.ErrTotpInvalidCode.Error}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authStorage := &fakeTotpAuthStorage{recoveryHashes: map[string]bool{lib.HashRecoveryCode(recoveryCode): true}}
+			otpStorage := &fakeTotpOtpStorage{usedSteps: make(map[int64]bool)}
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			service := NewAuthService(log, authStorage, nil, otpStorage, newFakeLimitStorage(), nil, nil, cfg)
+
+			for i, a := range tt.attempts {
+				err := service.checkSecondFactor(context.Background(), user, a.code, a.allowRecovery)
+				if !errors.Is(err, a.wantErr) {
+					t.Fatalf("attempt %d: expected error %v, got %v", i+1, a.wantErr, err)
+				}
+			}
+		})
+	}
+}
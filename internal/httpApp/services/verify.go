@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"crypto/subtle"
 	"log/slog"
 	"time"
 
@@ -9,10 +10,18 @@ import (
 	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
 	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib"
 	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
 	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/notifications"
 )
 
-func (s *AuthService) SendVerify(ctx context.Context, body dto.AuthSendVerifyRequest) (dto.AuthSendVerifyResponse, error) {
+// типы OTP-кодов, по ним разделяются ключи в Redis.
+// для верификации типом служит сам канал (phone/email)
+const (
+	otpTypeReset = "reset"
+	otpTypeLogin = "login"
+)
+
+func (s *AuthService) SendVerify(ctx context.Context, body dto.AuthSendVerifyRequest, ip string) (dto.AuthSendVerifyResponse, error) {
 	op := "services.SendVerify"
 	log := s.log.With(slog.String("op", op))
 
@@ -51,10 +60,23 @@ func (s *AuthService) SendVerify(ctx context.Context, body dto.AuthSendVerifyReq
 		}
 	}
 
-	// TODO проверять не отправляли ли мы код недавно
-	// получать из базы OTP и не давать отправить если прошло меньше 1 мин
+	return s.issueOtp(ctx, body.Type, body.Type, body.Address, ip, "Verify code for "+s.cfg.SERVICE_NAME, "Your verification code is: ")
+}
+
+// issueOtp генерирует код, сохраняет его в Redis под типом otpType
+// и отправляет на адрес через канал channel (phone/email), text - начало сообщения, к нему добавляется код.
+// Перед отправкой проверяет паузу между отправками и лимиты на адрес и IP, прежний код заменяется новым
+func (s *AuthService) issueOtp(ctx context.Context, otpType string, channel string, address string, ip string, subject string, text string) (dto.AuthSendVerifyResponse, error) {
+	op := "services.issueOtp"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthSendVerifyResponse{}
+
+	if err := s.checkOtpLimits(ctx, address, ip); err != nil {
+		return response, err
+	}
 
-	errDeleteOtp := s.otpStorage.DeleteOtp(ctx, body.Address, body.Type)
+	errDeleteOtp := s.otpStorage.DeleteOtp(ctx, address, otpType)
 	if errDeleteOtp != nil {
 		log.Warn("error delete otp", slog.String("err", errDeleteOtp.Message))
 	}
@@ -62,8 +84,8 @@ func (s *AuthService) SendVerify(ctx context.Context, body dto.AuthSendVerifyReq
 	otp := lib.GenerateOTP()
 	otpData := cache.OtpData{
 		Otp:       otp,
-		Type:      body.Type,
-		Address:   body.Address,
+		Type:      otpType,
+		Address:   address,
 		CreatedAt: time.Now(),
 		ExpireAt:  time.Now().Add(time.Duration(s.cfg.AUTH_OTP_TTL_MINUTES) * time.Minute),
 	}
@@ -71,41 +93,75 @@ func (s *AuthService) SendVerify(ctx context.Context, body dto.AuthSendVerifyReq
 	err := s.otpStorage.SaveOtp(ctx, otpData, s.cfg.AUTH_OTP_TTL_MINUTES)
 	if err != nil {
 		log.Warn("error save otp", slog.String("err", err.Message))
-		if err.Type == "already_otp" {
-			return response, errorsApp.ErrAlreadyOtp.Error
-		}
 		return response, errorsApp.ErrInternalError.Error
 	}
+	response.OtpExpiresAt = otpData.ExpireAt
+	response.ResendAvailableAt = otpData.CreatedAt.Add(s.cfg.AUTH_OTP_RESEND_COOLDOWN)
 
-	if body.Type == "phone" {
-		log.Info("send verify code to user", slog.String("body", body.Address))
+	if channel == "phone" {
+		log.Info("send otp code to user", slog.String("body", address), slog.String("type", otpType))
 		go func() {
-			err := notifications.SMSC_SendSms(s.cfg, s.log, body.Address, "Your verify code is: "+otp)
+			err := notifications.SMSC_SendSms(s.cfg, s.log, address, text+otp)
 			if err != nil {
-				log.Warn("error send verify code to user", slog.String("err", err.Error()))
+				log.Warn("error send otp code to user", slog.String("err", err.Error()))
 			} else {
-				log.Info("send verify code to user", slog.String("body", body.Address))
+				log.Info("send otp code to user", slog.String("body", address))
 			}
 		}()
 	}
-	if body.Type == "email" {
+	if channel == "email" {
 		// Отправляем email асинхронно, чтобы ошибки не блокировали основной поток
 		go func() {
-			err := notifications.SendMail(s.cfg, body.Address, "Verify code for "+s.cfg.SERVICE_NAME, "Your verify code is: "+otp)
+			err := notifications.SendMail(s.cfg, address, subject, text+otp)
 			if err != nil {
-				log.Warn("error send verify code to user", slog.String("err", err.Error()))
+				log.Warn("error send otp code to user", slog.String("err", err.Error()))
 			} else {
-				log.Info("send verify code to user", slog.String("body", body.Address))
+				log.Info("send otp code to user", slog.String("body", address))
 			}
 		}()
 	}
 
-	response.OtpExpiresAt = otpData.ExpireAt
-
 	return response, nil
 }
 
-func (s *AuthService) ConfirmVerify(ctx context.Context, body dto.AuthConfirmVerifyRequest) error {
+// checkOtpLimits занимает паузу между отправками на адрес и учитывает отправку в часовом/суточном
+// лимите с IP и на адрес. Возвращает RetryError, если пауза не прошла или лимит исчерпан.
+// Пауза ставится атомарно до отправки, поэтому параллельные запросы не отправят второй код,
+// счетчики увеличиваются, только если не исчерпан ни один лимит
+func (s *AuthService) checkOtpLimits(ctx context.Context, address string, ip string) error {
+	op := "services.checkOtpLimits"
+	log := s.log.With(slog.String("op", op))
+
+	cooldown, dbError := s.otpStorage.ClaimOtpCooldown(ctx, address, s.cfg.AUTH_OTP_RESEND_COOLDOWN)
+	if dbError != nil {
+		log.Error("error claim otp cooldown", slog.String("err", dbError.Message))
+		return errorsApp.ErrInternalError.Error
+	}
+	if cooldown > 0 {
+		log.Warn("otp resend cooldown", slog.String("address", address), slog.Duration("retry_after", cooldown))
+		return &errorsApp.RetryError{Err: errorsApp.ErrOtpCooldown, RetryAfter: cooldown}
+	}
+
+	// лимиты с IP первыми: перебор адресов с одного IP не должен расходовать лимиты чужих адресов
+	quotas := []cache.OtpQuota{
+		{Key: "hour:ip:" + ip, Window: time.Hour, Limit: s.cfg.AUTH_OTP_IP_HOURLY_LIMIT},
+		{Key: "day:ip:" + ip, Window: 24 * time.Hour, Limit: s.cfg.AUTH_OTP_IP_DAILY_LIMIT},
+		{Key: "hour:address:" + address, Window: time.Hour, Limit: s.cfg.AUTH_OTP_HOURLY_LIMIT},
+		{Key: "day:address:" + address, Window: 24 * time.Hour, Limit: s.cfg.AUTH_OTP_DAILY_LIMIT},
+	}
+	res, dbError := s.otpStorage.HitOtpQuotas(ctx, quotas)
+	if dbError != nil {
+		log.Error("error hit otp quotas", slog.String("err", dbError.Message))
+		return errorsApp.ErrInternalError.Error
+	}
+	if !res.Allowed {
+		log.Warn("otp quota exceeded", slog.String("key", res.Key))
+		return &errorsApp.RetryError{Err: errorsApp.ErrOtpQuota, RetryAfter: res.Reset}
+	}
+	return nil
+}
+
+func (s *AuthService) ConfirmVerify(ctx context.Context, body dto.AuthConfirmVerifyRequest, ip string, user_agent string) error {
 	op := "services.ConfirmVerify"
 	log := s.log.With(slog.String("op", op))
 
@@ -123,16 +179,12 @@ func (s *AuthService) ConfirmVerify(ctx context.Context, body dto.AuthConfirmVer
 		return errorsApp.ErrBadRequest.Error
 	}
 
-	otpData, err := s.otpStorage.GetOtp(ctx, body.Address, body.Type)
-	if err != nil {
-		log.Warn("error get otp", slog.String("err", err.Message))
-		return errorsApp.ErrInternalError.Error
-	}
-	if otpData.Otp != body.Code {
-		log.Warn("invalid otp", slog.String("otp", body.Code))
-		return errorsApp.ErrAuthentication.Error
+	if err := s.checkOtp(ctx, body.Type, body.Address, body.Code); err != nil {
+		s.audit(ctx, authEvent{Type: models.AuthEventVerify, Identity: body.Address, IP: ip, UserAgent: user_agent, Err: err})
+		return err
 	}
 
+	var userId int64
 	if body.Type == "phone" {
 		// сначала ищем пользователя по телефону
 		user, err := s.authStorage.GetUserByPhoneNumber(ctx, body.Address)
@@ -145,6 +197,7 @@ func (s *AuthService) ConfirmVerify(ctx context.Context, body dto.AuthConfirmVer
 			return errorsApp.ErrAuthentication.Error
 		}
 		// если пользователь найден, обновляем время верификации
+		userId = user.Id
 		err2 := s.authStorage.UpdateUserPhoneVerifyTimestamp(ctx, user.Id)
 		if err2 != nil {
 			log.Warn("error update user phone verify timestamp", slog.String("err", err2.Message))
@@ -163,6 +216,7 @@ func (s *AuthService) ConfirmVerify(ctx context.Context, body dto.AuthConfirmVer
 			return errorsApp.ErrAuthentication.Error
 		}
 		// если пользователь найден, обновляем время верификации
+		userId = user.Id
 		err2 := s.authStorage.UpdateUserEmailVerifyTimestamp(ctx, user.Id)
 		if err2 != nil {
 			log.Warn("error update user email verify timestamp", slog.String("err", err2.Message))
@@ -170,5 +224,94 @@ func (s *AuthService) ConfirmVerify(ctx context.Context, body dto.AuthConfirmVer
 		}
 	}
 
+	s.audit(ctx, authEvent{Type: models.AuthEventVerify, UserId: userId, Identity: body.Address, IP: ip, UserAgent: user_agent})
 	return nil
 }
+
+// checkOtp сверяет код с сохраненным. Каждая попытка учитывается до сравнения,
+// поэтому параллельные запросы не дают больше AUTH_OTP_MAX_ATTEMPTS попыток.
+// После последней неверной попытки код удаляется
+func (s *AuthService) checkOtp(ctx context.Context, otpType string, address string, code string) error {
+	op := "services.checkOtp"
+	log := s.log.With(slog.String("op", op))
+
+	otpData, dbError := s.otpStorage.GetOtp(ctx, address, otpType)
+	if dbError != nil {
+		log.Warn("error get otp", slog.String("err", dbError.Message))
+		return errorsApp.ErrVerifyNotFound.Error
+	}
+
+	attempts, dbError := s.otpStorage.IncrOtpAttempts(ctx, address, otpType)
+	if dbError != nil {
+		log.Warn("error incr otp attempts", slog.String("err", dbError.Message))
+		if dbError.Type == "not_found" {
+			return errorsApp.ErrVerifyNotFound.Error
+		}
+		return errorsApp.ErrInternalError.Error
+	}
+	maxAttempts := int64(s.cfg.AUTH_OTP_MAX_ATTEMPTS)
+	if maxAttempts > 0 && attempts > maxAttempts {
+		log.Warn("otp attempts exceeded", slog.String("address", address), slog.Int64("attempts", attempts))
+		s.invalidateOtp(ctx, address, otpType)
+		return errorsApp.ErrOtpAttemptsExceeded.Error
+	}
+
+	if subtle.ConstantTimeCompare([]byte(otpData.Otp), []byte(code)) != 1 {
+		log.Warn("invalid otp", slog.String("address", address), slog.Int64("attempts", attempts))
+		if maxAttempts > 0 && attempts == maxAttempts {
+			s.invalidateOtp(ctx, address, otpType)
+			return errorsApp.ErrOtpAttemptsExceeded.Error
+		}
+		return errorsApp.ErrAuthentication.Error
+	}
+	return nil
+}
+
+func (s *AuthService) invalidateOtp(ctx context.Context, address string, otpType string) {
+	dbError := s.otpStorage.DeleteOtp(ctx, address, otpType)
+	if dbError != nil {
+		s.log.Warn("error delete otp", slog.String("err", dbError.Message))
+	}
+}
+
+// skipOtp отвечает на запрос кода для неизвестного адреса так же, как issueOtp, но ничего не отправляет,
+// чтобы по ответу нельзя было узнать, зарегистрирован ли адрес. Паузы и лимиты действуют так же
+func (s *AuthService) skipOtp(ctx context.Context, address string, ip string) (dto.AuthSendVerifyResponse, error) {
+	response := dto.AuthSendVerifyResponse{}
+
+	if err := s.checkOtpLimits(ctx, address, ip); err != nil {
+		return response, err
+	}
+
+	now := time.Now()
+	response.OtpExpiresAt = now.Add(time.Duration(s.cfg.AUTH_OTP_TTL_MINUTES) * time.Minute)
+	response.ResendAvailableAt = now.Add(s.cfg.AUTH_OTP_RESEND_COOLDOWN)
+	return response, nil
+}
+
+// getUserByAddress ищет пользователя по телефону или email в зависимости от канала
+func (s *AuthService) getUserByAddress(ctx context.Context, channel string, address string) (models.UserEntity, error) {
+	op := "services.getUserByAddress"
+	log := s.log.With(slog.String("op", op))
+
+	var user models.UserEntity
+	var dbError *errorsApp.DbError
+	switch channel {
+	case "phone":
+		user, dbError = s.authStorage.GetUserByPhoneNumber(ctx, address)
+	case "email":
+		user, dbError = s.authStorage.GetUserByEmail(ctx, address)
+	default:
+		log.Warn("invalid type", slog.String("type", channel))
+		return user, errorsApp.ErrBadRequest.Error
+	}
+	if dbError != nil {
+		if dbError.Type == "not_found" {
+			log.Warn("user not found", slog.String("address", address))
+			return user, errorsApp.ErrUserNotFound.Error
+		}
+		log.Warn("error get user by address", slog.String("err", dbError.Message))
+		return user, errorsApp.ErrInternalError.Error
+	}
+	return user, nil
+}
@@ -0,0 +1,476 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/config"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/db/cache"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/httpApp/dto"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/lib/errorsApp"
+	"github.com/AlmasNurbayev/go_fiber_boilerplate/internal/models"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+const (
+	webauthnCeremonyRegister = "register"
+	webauthnCeremonyLogin    = "login"
+)
+
+// newWebAuthn возвращает nil, если WebAuthn не настроен (WEBAUTHN_RP_ID пуст) или конфиг некорректен
+func newWebAuthn(cfg *config.Config, log *slog.Logger) *webauthn.WebAuthn {
+	if cfg.WEBAUTHN_RP_ID == "" {
+		return nil
+	}
+	name := cfg.WEBAUTHN_RP_NAME
+	if name == "" {
+		name = cfg.SERVICE_NAME
+	}
+	timeout := webauthn.TimeoutConfig{
+		Enforce:    true,
+		Timeout:    cfg.WEBAUTHN_CHALLENGE_TTL,
+		TimeoutUVD: cfg.WEBAUTHN_CHALLENGE_TTL,
+	}
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.WEBAUTHN_RP_ID,
+		RPDisplayName: name,
+		RPOrigins:     cfg.WEBAUTHN_RP_ORIGINS,
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        timeout,
+			Registration: timeout,
+		},
+	})
+	if err != nil {
+		log.Error("webauthn disabled, invalid config", slog.String("err", err.Error()))
+		return nil
+	}
+	return w
+}
+
+// webauthnUser - пользователь с ключами в виде, нужном библиотеке webauthn
+type webauthnUser struct {
+	entity      models.UserEntity
+	credentials []models.WebauthnCredentialEntity
+}
+
+func (u webauthnUser) WebAuthnID() []byte {
+	return []byte(strconv.FormatInt(u.entity.Id, 10))
+}
+
+func (u webauthnUser) WebAuthnName() string {
+	if u.entity.Email.Valid {
+		return u.entity.Email.String
+	}
+	if u.entity.Phone_number.Valid {
+		return u.entity.Phone_number.String
+	}
+	return u.entity.Name
+}
+
+func (u webauthnUser) WebAuthnDisplayName() string {
+	return u.entity.Name
+}
+
+func (u webauthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.credentials))
+	for _, c := range u.credentials {
+		transports := make([]protocol.AuthenticatorTransport, 0, len(c.Transports))
+		for _, t := range c.Transports {
+			transports = append(transports, protocol.AuthenticatorTransport(t))
+		}
+		credentials = append(credentials, webauthn.Credential{
+			ID:              c.Credential_id,
+			PublicKey:       c.Public_key,
+			AttestationType: c.Attestation_type,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				UserPresent:    c.User_present,
+				UserVerified:   c.User_verified,
+				BackupEligible: c.Backup_eligible,
+				BackupState:    c.Backup_state,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    c.Aaguid,
+				SignCount: uint32(c.Sign_count),
+			},
+		})
+	}
+	return credentials
+}
+
+// loadWebauthnUser загружает пользователя вместе с его ключами
+func (s *AuthService) loadWebauthnUser(ctx context.Context, userEntity models.UserEntity) (webauthnUser, error) {
+	op := "services.loadWebauthnUser"
+	log := s.log.With(slog.String("op", op))
+
+	credentials, dbError := s.authStorage.GetWebauthnCredentialsByUserId(ctx, userEntity.Id)
+	if dbError != nil {
+		log.Error("error get webauthn credentials", slog.String("err", dbError.Message))
+		return webauthnUser{}, errorsApp.ErrInternalError.Error
+	}
+	return webauthnUser{entity: userEntity, credentials: credentials}, nil
+}
+
+// otpTypeWebauthnRegister - код добавления ключа привязан к пользователю, как и код удаления аккаунта
+func otpTypeWebauthnRegister(userId int64) string {
+	return "webauthn:" + strconv.FormatInt(userId, 10)
+}
+
+// WebauthnRegisterCode отправляет код подтверждения добавления ключа на подтвержденный адрес пользователя,
+// нужен тем, у кого нет пароля (oauth, вход по коду, passkey)
+func (s *AuthService) WebauthnRegisterCode(ctx context.Context, userId int64, body dto.AuthWebauthnRegisterCodeRequest, ip string) (dto.AuthSendVerifyResponse, error) {
+	op := "services.WebauthnRegisterCode"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthSendVerifyResponse{}
+	if s.webauthn == nil {
+		return response, errorsApp.ErrWebauthnNotConfigured.Error
+	}
+
+	user, dbError := s.authStorage.GetUserById(ctx, userId)
+	if dbError != nil {
+		log.Warn("error get user by id", slog.String("err", dbError.Message))
+		return response, errorsApp.ErrUserNotFound.Error
+	}
+	if !addressVerified(user, body.Type) {
+		log.Warn("address not verified", slog.Int64("user_id", userId), slog.String("type", body.Type))
+		return response, errorsApp.ErrBadRequest.Error
+	}
+
+	return s.issueOtp(ctx, otpTypeWebauthnRegister(userId), body.Type, userAddress(user, body.Type), ip, "Confirm new key for "+s.cfg.SERVICE_NAME, "Your code to add a login key is: ")
+}
+
+// WebauthnRegisterOptions начинает регистрацию нового ключа для текущего пользователя после подтверждения
+// паролем или кодом: ключ дает вход без пароля, одного access-токена для его добавления недостаточно
+func (s *AuthService) WebauthnRegisterOptions(ctx context.Context, userId int64, body dto.AuthWebauthnRegisterOptionsRequest) (dto.AuthWebauthnOptionsResponse, error) {
+	op := "services.WebauthnRegisterOptions"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthWebauthnOptionsResponse{}
+	if s.webauthn == nil {
+		return response, errorsApp.ErrWebauthnNotConfigured.Error
+	}
+
+	userEntity, dbError := s.authStorage.GetUserById(ctx, userId)
+	if dbError != nil {
+		log.Warn("error get user by id", slog.String("err", dbError.Message))
+		return response, dbError.Error
+	}
+	if err := s.reauthenticate(ctx, userEntity, otpTypeWebauthnRegister(userId), body.Password, body.Type, body.Code); err != nil {
+		return response, err
+	}
+	user, err := s.loadWebauthnUser(ctx, userEntity)
+	if err != nil {
+		return response, err
+	}
+
+	creation, session, err := s.webauthn.BeginRegistration(user,
+		// уже зарегистрированные ключи повторно не добавляются
+		webauthn.WithExclusions(webauthn.Credentials(user.WebAuthnCredentials()).CredentialDescriptors()),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		log.Error("error begin webauthn registration", slog.String("err", err.Error()))
+		return response, errorsApp.ErrInternalError.Error
+	}
+
+	return s.saveWebauthnChallenge(ctx, webauthnCeremonyRegister, userId, session, creation)
+}
+
+// WebauthnRegisterFinish проверяет ответ аутентификатора и сохраняет ключ
+func (s *AuthService) WebauthnRegisterFinish(ctx context.Context, userId int64, body dto.AuthWebauthnFinishRequest, ip string, user_agent string) (dto.AuthWebauthnCredential, error) {
+	op := "services.WebauthnRegisterFinish"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthWebauthnCredential{}
+	if s.webauthn == nil {
+		return response, errorsApp.ErrWebauthnNotConfigured.Error
+	}
+
+	challenge, session, err := s.popWebauthnChallenge(ctx, body.ChallengeToken, webauthnCeremonyRegister)
+	if err != nil {
+		return response, err
+	}
+	// challenge регистрации привязан к пользователю, начавшему церемонию
+	if challenge.UserID != userId {
+		log.Warn("webauthn challenge of another user", slog.Int64("user_id", userId))
+		return response, errorsApp.ErrWebauthnFailed.Error
+	}
+
+	userEntity, dbError := s.authStorage.GetUserById(ctx, userId)
+	if dbError != nil {
+		log.Warn("error get user by id", slog.String("err", dbError.Message))
+		return response, dbError.Error
+	}
+	user, err := s.loadWebauthnUser(ctx, userEntity)
+	if err != nil {
+		return response, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(body.Credential)
+	if err != nil {
+		log.Warn("error parse webauthn credential", slog.String("err", err.Error()))
+		return response, errorsApp.ErrWebauthnFailed.Error
+	}
+	credential, err := s.webauthn.CreateCredential(user, session, parsed)
+	if err != nil {
+		log.Warn("error create webauthn credential", slog.String("err", err.Error()))
+		return response, errorsApp.ErrWebauthnFailed.Error
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, t := range credential.Transport {
+		transports = append(transports, string(t))
+	}
+	entity, dbError := s.authStorage.NewWebauthnCredential(ctx, models.WebauthnCredentialEntity{
+		User_id:          userId,
+		Credential_id:    credential.ID,
+		Public_key:       credential.PublicKey,
+		Attestation_type: credential.AttestationType,
+		Aaguid:           credential.Authenticator.AAGUID,
+		Sign_count:       int64(credential.Authenticator.SignCount),
+		Transports:       transports,
+		User_present:     credential.Flags.UserPresent,
+		User_verified:    credential.Flags.UserVerified,
+		Backup_eligible:  credential.Flags.BackupEligible,
+		Backup_state:     credential.Flags.BackupState,
+		Name:             body.Name,
+	})
+	if dbError != nil {
+		log.Error("error save webauthn credential", slog.String("err", dbError.Message))
+		return response, dbError.Error
+	}
+	log.Info("webauthn credential registered", slog.Int64("user_id", userId), slog.Int64("id", entity.Id))
+	s.securityNotify(userEntity, securityNoticePasskeyAdded, ip, user_agent, "")
+
+	return webauthnCredentialDto(entity), nil
+}
+
+// WebauthnLoginOptions начинает вход по ключу. Без email/телефона - вход по passkey (discoverable credential)
+func (s *AuthService) WebauthnLoginOptions(ctx context.Context, body dto.AuthWebauthnLoginOptionsRequest) (dto.AuthWebauthnOptionsResponse, error) {
+	op := "services.WebauthnLoginOptions"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthWebauthnOptionsResponse{}
+	if s.webauthn == nil {
+		return response, errorsApp.ErrWebauthnNotConfigured.Error
+	}
+
+	var userEntity models.UserEntity
+	var err error
+	switch {
+	case body.Email.Valid:
+		userEntity, err = s.getUserByAddress(ctx, "email", body.Email.String)
+	case body.Phone_number.Valid:
+		userEntity, err = s.getUserByAddress(ctx, "phone", body.Phone_number.String)
+	}
+	if err != nil && err != errorsApp.ErrUserNotFound.Error {
+		return response, err
+	}
+
+	if userEntity.Id != 0 {
+		user, err := s.loadWebauthnUser(ctx, userEntity)
+		if err != nil {
+			return response, err
+		}
+		if len(user.credentials) > 0 {
+			assertion, session, err := s.webauthn.BeginLogin(user)
+			if err != nil {
+				log.Error("error begin webauthn login", slog.String("err", err.Error()))
+				return response, errorsApp.ErrInternalError.Error
+			}
+			return s.saveWebauthnChallenge(ctx, webauthnCeremonyLogin, userEntity.Id, session, assertion)
+		}
+		log.Warn("user has no webauthn credentials", slog.Int64("user_id", userEntity.Id))
+	}
+
+	// неизвестный адрес и пользователь без ключей получают тот же ответ, что и вход по passkey без адреса,
+	// чтобы по ответу нельзя было узнать, зарегистрирован ли адрес и есть ли у него ключи
+	assertion, session, err := s.webauthn.BeginDiscoverableLogin()
+	if err != nil {
+		log.Error("error begin webauthn login", slog.String("err", err.Error()))
+		return response, errorsApp.ErrInternalError.Error
+	}
+	return s.saveWebauthnChallenge(ctx, webauthnCeremonyLogin, 0, session, assertion)
+}
+
+// WebauthnLoginFinish проверяет подпись ключа и завершает вход так же, как Login
+func (s *AuthService) WebauthnLoginFinish(ctx context.Context, body dto.AuthWebauthnFinishRequest, ip string, user_agent string) (dto.AuthLoginResponse, error) {
+	op := "services.WebauthnLoginFinish"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthLoginResponse{}
+	if s.webauthn == nil {
+		return response, errorsApp.ErrWebauthnNotConfigured.Error
+	}
+
+	challenge, session, err := s.popWebauthnChallenge(ctx, body.ChallengeToken, webauthnCeremonyLogin)
+	if err != nil {
+		return response, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(body.Credential)
+	if err != nil {
+		log.Warn("error parse webauthn assertion", slog.String("err", err.Error()))
+		s.audit(ctx, authEvent{Type: models.AuthEventLogin, UserId: challenge.UserID, IP: ip, UserAgent: user_agent, Err: errorsApp.ErrWebauthnFailed.Error})
+		return response, errorsApp.ErrWebauthnFailed.Error
+	}
+
+	var user webauthnUser
+	var credential *webauthn.Credential
+	if challenge.UserID != 0 {
+		userEntity, dbError := s.authStorage.GetUserById(ctx, challenge.UserID)
+		if dbError != nil {
+			log.Warn("error get user by id", slog.String("err", dbError.Message))
+			return response, errorsApp.ErrWebauthnFailed.Error
+		}
+		user, err = s.loadWebauthnUser(ctx, userEntity)
+		if err != nil {
+			return response, err
+		}
+		credential, err = s.webauthn.ValidateLogin(user, session, parsed)
+	} else {
+		// passkey: пользователь определяется по userHandle из ответа аутентификатора
+		var found webauthn.User
+		found, credential, err = s.webauthn.ValidatePasskeyLogin(func(_, userHandle []byte) (webauthn.User, error) {
+			userId, err := strconv.ParseInt(string(userHandle), 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			userEntity, dbError := s.authStorage.GetUserById(ctx, userId)
+			if dbError != nil {
+				return nil, dbError.Error
+			}
+			return s.loadWebauthnUser(ctx, userEntity)
+		}, session, parsed)
+		if err == nil {
+			user = found.(webauthnUser)
+		}
+	}
+	if err != nil {
+		log.Warn("webauthn assertion failed", slog.String("err", err.Error()))
+		// для passkey пользователь неизвестен, пока подпись не проверена
+		s.audit(ctx, authEvent{Type: models.AuthEventLogin, UserId: challenge.UserID, IP: ip, UserAgent: user_agent, Err: errorsApp.ErrWebauthnFailed.Error})
+		return response, errorsApp.ErrWebauthnFailed.Error
+	}
+
+	if credential.Authenticator.CloneWarning {
+		// счетчик подписей не вырос - ключ мог быть скопирован
+		log.Warn("webauthn clone warning", slog.Int64("user_id", user.entity.Id))
+		s.audit(ctx, authEvent{Type: models.AuthEventLogin, UserId: user.entity.Id, IP: ip, UserAgent: user_agent, Err: errorsApp.ErrWebauthnFailed.Error})
+		return response, errorsApp.ErrWebauthnFailed.Error
+	}
+
+	stored, dbError := s.authStorage.GetWebauthnCredentialByCredentialId(ctx, credential.ID)
+	if dbError != nil {
+		log.Error("error get webauthn credential", slog.String("err", dbError.Message))
+		return response, errorsApp.ErrWebauthnFailed.Error
+	}
+	dbError = s.authStorage.UpdateWebauthnCredentialUsage(ctx, stored.Id, int64(credential.Authenticator.SignCount), credential.Flags.BackupState)
+	if dbError != nil {
+		log.Warn("error update webauthn credential usage", slog.String("err", dbError.Message))
+	}
+
+	return s.completeLogin(ctx, user.entity, ip, user_agent)
+}
+
+func (s *AuthService) WebauthnCredentials(ctx context.Context, userId int64) (dto.AuthWebauthnCredentialsResponse, error) {
+	op := "services.WebauthnCredentials"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthWebauthnCredentialsResponse{Credentials: make([]dto.AuthWebauthnCredential, 0)}
+
+	credentials, dbError := s.authStorage.GetWebauthnCredentialsByUserId(ctx, userId)
+	if dbError != nil {
+		log.Error("error get webauthn credentials", slog.String("err", dbError.Message))
+		return response, errorsApp.ErrInternalError.Error
+	}
+	for _, c := range credentials {
+		response.Credentials = append(response.Credentials, webauthnCredentialDto(c))
+	}
+	return response, nil
+}
+
+func (s *AuthService) DeleteWebauthnCredential(ctx context.Context, userId int64, id int64) error {
+	op := "services.DeleteWebauthnCredential"
+	log := s.log.With(slog.String("op", op))
+
+	dbError := s.authStorage.DeleteWebauthnCredential(ctx, id, userId)
+	if dbError != nil {
+		if dbError.Type == "not_found" {
+			return errorsApp.ErrWebauthnCredentialNotFound.Error
+		}
+		log.Error("error delete webauthn credential", slog.String("err", dbError.Message))
+		return errorsApp.ErrInternalError.Error
+	}
+	return nil
+}
+
+// saveWebauthnChallenge сохраняет состояние церемонии в Redis и возвращает options для браузера
+func (s *AuthService) saveWebauthnChallenge(ctx context.Context, ceremony string, userId int64, session *webauthn.SessionData, options any) (dto.AuthWebauthnOptionsResponse, error) {
+	op := "services.saveWebauthnChallenge"
+	log := s.log.With(slog.String("op", op))
+
+	response := dto.AuthWebauthnOptionsResponse{}
+
+	sessionJson, err := json.Marshal(session)
+	if err != nil {
+		log.Error("error marshal webauthn session", slog.String("err", err.Error()))
+		return response, errorsApp.ErrInternalError.Error
+	}
+
+	token := uuid.NewString()
+	dbError := s.sessionStorage.SaveWebauthnChallenge(ctx, cache.WebauthnChallengeData{
+		Token:    token,
+		Ceremony: ceremony,
+		UserID:   userId,
+		Session:  sessionJson,
+	}, s.cfg.WEBAUTHN_CHALLENGE_TTL)
+	if dbError != nil {
+		log.Error("error save webauthn challenge", slog.String("err", dbError.Message))
+		return response, errorsApp.ErrInternalError.Error
+	}
+
+	response.ChallengeToken = token
+	response.Options = options
+	return response, nil
+}
+
+// popWebauthnChallenge достает (и удаляет) состояние церемонии, тип церемонии должен совпадать
+func (s *AuthService) popWebauthnChallenge(ctx context.Context, token string, ceremony string) (cache.WebauthnChallengeData, webauthn.SessionData, error) {
+	op := "services.popWebauthnChallenge"
+	log := s.log.With(slog.String("op", op))
+
+	session := webauthn.SessionData{}
+
+	challenge, dbError := s.sessionStorage.PopWebauthnChallenge(ctx, token)
+	if dbError != nil {
+		if dbError.Type == "not_found" {
+			return challenge, session, errorsApp.ErrWebauthnFailed.Error
+		}
+		return challenge, session, errorsApp.ErrInternalError.Error
+	}
+	if challenge.Ceremony != ceremony {
+		log.Warn("webauthn ceremony mismatch", slog.String("ceremony", challenge.Ceremony))
+		return challenge, session, errorsApp.ErrWebauthnFailed.Error
+	}
+	if err := json.Unmarshal(challenge.Session, &session); err != nil {
+		log.Error("error unmarshal webauthn session", slog.String("err", err.Error()))
+		return challenge, session, errorsApp.ErrInternalError.Error
+	}
+	return challenge, session, nil
+}
+
+func webauthnCredentialDto(c models.WebauthnCredentialEntity) dto.AuthWebauthnCredential {
+	return dto.AuthWebauthnCredential{
+		Id:           c.Id,
+		Name:         c.Name,
+		Transports:   c.Transports,
+		Last_used_at: c.Last_used_at,
+		Create_date:  c.Create_date,
+	}
+}
@@ -0,0 +1,88 @@
+package lib
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestDecryptString(t *testing.T) {
+	const key = "totp-key"
+	encrypted, err := EncryptString("JBSWY3DPEHPK3PXP", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := append([]byte{}, raw...)
+	tampered[len(tampered)-1] ^= 0x01
+
+	tests := []struct {
+		name      string
+		encrypted string
+		key       string
+		want      string
+		wantErr   bool
+	}{
+		{name: "same key", encrypted: encrypted, key: key, want: "JBSWY3DPEHPK3PXP"},
+		{name: "wrong key", encrypted: encrypted, key: "other-key", wantErr: true},
+		{name: "tampered ciphertext", encrypted: base64.StdEncoding.EncodeToString(tampered), key: key, wantErr: true},
+		{name: "shorter than nonce", encrypted: base64.StdEncoding.EncodeToString(raw[:4]), key: key, wantErr: true},
+		{name: "not base64", encrypted: "%%%", key: key, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecryptString(tt.encrypted, tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncryptStringUsesRandomNonce(t *testing.T) {
+	first, err := EncryptString("secret", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := EncryptString("secret", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Fatal("same plaintext encrypted to the same ciphertext")
+	}
+}
+
+func TestVerifySignedString(t *testing.T) {
+	const secret = "link-secret"
+	signature := SignString("revoke-all:7:1700000000", secret)
+
+	tests := []struct {
+		name      string
+		value     string
+		signature string
+		secret    string
+		want      bool
+	}{
+		{name: "valid", value: "revoke-all:7:1700000000", signature: signature, secret: secret, want: true},
+		{name: "another user id", value: "revoke-all:8:1700000000", signature: signature, secret: secret},
+		{name: "extended expiry", value: "revoke-all:7:1800000000", signature: signature, secret: secret},
+		{name: "signed with another secret", value: "revoke-all:7:1700000000", signature: SignString("revoke-all:7:1700000000", "other"), secret: secret},
+		{name: "forged signature", value: "revoke-all:7:1700000000", signature: "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA", secret: secret},
+		{name: "empty signature", value: "revoke-all:7:1700000000", signature: "", secret: secret},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifySignedString(tt.value, tt.signature, tt.secret); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -83,4 +83,124 @@ var (
 		Code:    400,
 		Message: "otp already sent, wait TTL",
 		Error:   errors.New("otp already sent, wait TTL")}
+
+	ErrOauthFailed = HttpError{
+		Code:    401,
+		Message: "oauth authentication failed",
+		Error:   errors.New("oauth authentication failed")}
+
+	ErrOauthNotConfigured = HttpError{
+		Code:    404,
+		Message: "oauth provider not configured",
+		Error:   errors.New("oauth provider not configured")}
+
+	ErrOauthAlreadyLinked = HttpError{
+		Code:    409,
+		Message: "oauth account already linked to another user",
+		Error:   errors.New("oauth account already linked to another user")}
+
+	ErrOauthAccountNotFound = HttpError{
+		Code:    404,
+		Message: "oauth account not found",
+		Error:   errors.New("oauth account not found")}
+
+	ErrLastLoginMethod = HttpError{
+		Code:    409,
+		Message: "cannot remove the last login method",
+		Error:   errors.New("cannot remove the last login method")}
+
+	ErrUserBlocked = HttpError{
+		Code:    403,
+		Message: "user is blocked",
+		Error:   errors.New("user is blocked")}
+
+	ErrTooManyAttempts = HttpError{
+		Code:    429,
+		Message: "too many login attempts, try later",
+		Error:   errors.New("too many login attempts, try later")}
+
+	ErrRateLimited = HttpError{
+		Code:    429,
+		Message: "too many requests",
+		Error:   errors.New("too many requests")}
+
+	ErrOtpCooldown = HttpError{
+		Code:    429,
+		Message: "otp already sent, wait before resend",
+		Error:   errors.New("otp already sent, wait before resend")}
+
+	ErrOtpQuota = HttpError{
+		Code:    429,
+		Message: "otp send limit exceeded",
+		Error:   errors.New("otp send limit exceeded")}
+
+	ErrOtpAttemptsExceeded = HttpError{
+		Code:    410,
+		Message: "too many wrong codes, request a new code",
+		Error:   errors.New("too many wrong codes, request a new code")}
+
+	ErrRefreshTokenReused = HttpError{
+		Code:    401,
+		Message: "refresh token reuse detected, sessions revoked",
+		Error:   errors.New("refresh token reuse detected, sessions revoked")}
+
+	ErrTotpAlreadyEnabled = HttpError{
+		Code:    409,
+		Message: "two-factor authentication already enabled",
+		Error:   errors.New("two-factor authentication already enabled")}
+
+	ErrTotpNotEnabled = HttpError{
+		Code:    409,
+		Message: "two-factor authentication is not enabled",
+		Error:   errors.New("two-factor authentication is not enabled")}
+
+	ErrTotpInvalidCode = HttpError{
+		Code:    401,
+		Message: "invalid two-factor code",
+		Error:   errors.New("invalid two-factor code")}
+
+	ErrMfaChallengeNotFound = HttpError{
+		Code:    401,
+		Message: "mfa challenge expired or not found",
+		Error:   errors.New("mfa challenge expired or not found")}
+
+	ErrWebauthnNotConfigured = HttpError{
+		Code:    404,
+		Message: "webauthn not configured",
+		Error:   errors.New("webauthn not configured")}
+
+	ErrWebauthnFailed = HttpError{
+		Code:    401,
+		Message: "webauthn verification failed",
+		Error:   errors.New("webauthn verification failed")}
+
+	ErrWebauthnCredentialNotFound = HttpError{
+		Code:    404,
+		Message: "webauthn credential not found",
+		Error:   errors.New("webauthn credential not found")}
+
+	ErrContactTaken = HttpError{
+		Code:    409,
+		Message: "email or phone number already in use",
+		Error:   errors.New("email or phone number already in use")}
+
+	ErrDataExportNotFound = HttpError{
+		Code:    404,
+		Message: "data export not found or link expired",
+		Error:   errors.New("data export not found or link expired")}
+
+	ErrDataExportLimit = HttpError{
+		Code:    429,
+		Message: "data export was already requested, try later",
+		Error:   errors.New("data export was already requested, try later")}
+
+	ErrRevokeLinkInvalid = HttpError{
+		Code:    400,
+		Message: "link is invalid or expired",
+		Error:   errors.New("link is invalid or expired")}
+
+	ErrOauthEmailNotVerified = HttpError{
+		Code:    409,
+		Message: "account with this email exists, verify the email or log in and link the provider",
+		Error:   errors.New("account with this email exists, verify the email or log in and link the provider")}
 )
@@ -0,0 +1,25 @@
+package errorsApp
+
+import (
+	"math"
+	"time"
+)
+
+// RetryError - HttpError с временем, через которое можно повторить запрос (заголовок Retry-After)
+type RetryError struct {
+	Err        HttpError
+	RetryAfter time.Duration
+}
+
+func (e *RetryError) Error() string {
+	return e.Err.Message
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err.Error
+}
+
+// Seconds - значение для Retry-After, округленное вверх
+func (e *RetryError) Seconds() int64 {
+	return int64(math.Ceil(e.RetryAfter.Seconds()))
+}
@@ -0,0 +1,61 @@
+package lib
+
+import (
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// GeoIP - поиск страны и города по IP в офлайн-базе формата MaxMind (GeoLite2-City.mmdb).
+// nil-значение допустимо и ничего не находит
+type GeoIP struct {
+	reader *geoip2.Reader
+	lang   string
+}
+
+// OpenGeoIP открывает базу, пустой путь - геолокация отключена (nil, nil)
+func OpenGeoIP(path string, lang string) (*GeoIP, error) {
+	if path == "" {
+		return nil, nil
+	}
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &GeoIP{reader: reader, lang: lang}, nil
+}
+
+// Lookup возвращает страну и город, пустые строки - не найдено
+func (g *GeoIP) Lookup(ip string) (string, string) {
+	if g == nil {
+		return "", ""
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return "", ""
+	}
+	record, err := g.reader.City(parsed)
+	if err != nil {
+		return "", ""
+	}
+
+	country := g.name(record.Country.Names)
+	if country == "" {
+		country = record.Country.IsoCode
+	}
+	return country, g.name(record.City.Names)
+}
+
+func (g *GeoIP) name(names map[string]string) string {
+	if name, ok := names[g.lang]; ok {
+		return name
+	}
+	return names["en"]
+}
+
+func (g *GeoIP) Close() error {
+	if g == nil {
+		return nil
+	}
+	return g.reader.Close()
+}
@@ -0,0 +1,230 @@
+package lib
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTKey - ключ подписи токенов. Для HS256 SignKey и VerifyKey - один секрет,
+// для RS256/EdDSA VerifyKey - публичная часть, которую можно отдавать наружу
+type JWTKey struct {
+	Kid       string
+	Method    jwt.SigningMethod
+	SignKey   any
+	VerifyKey any
+}
+
+// JWTKeySet - активный ключ подписи и ключи только для проверки (ротация по kid).
+// Набор может перечитываться на лету, поэтому доступ под мьютексом
+type JWTKeySet struct {
+	mu      sync.RWMutex
+	signing *JWTKey
+	keys    map[string]*JWTKey
+	// ключ для токенов без kid: в режиме одного ключа - он сам,
+	// в AUTH_JWT_KEYS_DIR - прежний HS256-секрет, пока не истек его срок проверки
+	noKid *JWTKey
+}
+
+// JWK - публичный ключ в формате RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadJWTKeySet создает набор ключей по алгоритму из конфига.
+// HS256 использует secret, RS256 и EdDSA - приватный ключ из PEM-файла
+func LoadJWTKeySet(alg string, secret string, privateKeyPath string, kid string) (*JWTKeySet, error) {
+	key, err := LoadJWTKey(alg, secret, privateKeyPath, kid)
+	if err != nil {
+		return nil, err
+	}
+	return &JWTKeySet{
+		signing: key,
+		keys:    map[string]*JWTKey{key.Kid: key},
+		noKid:   key,
+	}, nil
+}
+
+func LoadJWTKey(alg string, secret string, privateKeyPath string, kid string) (*JWTKey, error) {
+	switch alg {
+	case "", jwt.SigningMethodHS256.Alg():
+		if secret == "" {
+			return nil, fmt.Errorf("jwt secret is empty")
+		}
+		return &JWTKey{
+			Kid:       kid, // пустой kid - заголовок не добавляется
+			Method:    jwt.SigningMethodHS256,
+			SignKey:   []byte(secret),
+			VerifyKey: []byte(secret),
+		}, nil
+	case jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg():
+		pemData, err := os.ReadFile(privateKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("read jwt private key: %w", err)
+		}
+		return ParseJWTPrivateKey(alg, pemData, kid)
+	default:
+		return nil, fmt.Errorf("unsupported jwt alg: %s", alg)
+	}
+}
+
+// ParseJWTPrivateKey разбирает PEM приватного ключа, kid по умолчанию - thumbprint (RFC 7638)
+func ParseJWTPrivateKey(alg string, pemData []byte, kid string) (*JWTKey, error) {
+	key := &JWTKey{Kid: kid}
+
+	switch alg {
+	case jwt.SigningMethodRS256.Alg():
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(pemData)
+		if err != nil {
+			return nil, fmt.Errorf("parse rsa private key: %w", err)
+		}
+		key.Method = jwt.SigningMethodRS256
+		key.SignKey = privateKey
+		key.VerifyKey = &privateKey.PublicKey
+	case jwt.SigningMethodEdDSA.Alg():
+		privateKey, err := jwt.ParseEdPrivateKeyFromPEM(pemData)
+		if err != nil {
+			return nil, fmt.Errorf("parse ed25519 private key: %w", err)
+		}
+		edKey, ok := privateKey.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("private key is not ed25519")
+		}
+		key.Method = jwt.SigningMethodEdDSA
+		key.SignKey = edKey
+		key.VerifyKey = edKey.Public()
+	default:
+		return nil, fmt.Errorf("unsupported jwt alg: %s", alg)
+	}
+
+	if key.Kid == "" {
+		thumbprint, err := jwkThumbprint(key.publicJWK())
+		if err != nil {
+			return nil, err
+		}
+		key.Kid = thumbprint
+	}
+	return key, nil
+}
+
+// Signing - текущий ключ для подписи новых токенов
+func (ks *JWTKeySet) Signing() *JWTKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.signing
+}
+
+// VerifyKey ищет ключ проверки по kid. Токены без kid проверяются ключом noKid, если он есть
+func (ks *JWTKeySet) VerifyKey(kid string) (*JWTKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	if kid == "" {
+		return ks.noKid, ks.noKid != nil
+	}
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+// Methods - допустимые алгоритмы для jwt.WithValidMethods
+func (ks *JWTKeySet) Methods() []string {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	methods := make([]string, 0, 1)
+	seen := map[string]bool{}
+	for _, key := range ks.keys {
+		if !seen[key.Method.Alg()] {
+			seen[key.Method.Alg()] = true
+			methods = append(methods, key.Method.Alg())
+		}
+	}
+	if ks.noKid != nil && !seen[ks.noKid.Method.Alg()] {
+		methods = append(methods, ks.noKid.Method.Alg())
+	}
+	return methods
+}
+
+// JWKS - публичные ключи для /.well-known/jwks.json, симметричные ключи не публикуются
+func (ks *JWTKeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	res := JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+	for _, key := range ks.keys {
+		jwk := key.publicJWK()
+		if jwk.Kty == "" {
+			continue
+		}
+		res.Keys = append(res.Keys, jwk)
+	}
+	return res
+}
+
+func (k *JWTKey) publicJWK() JWK {
+	switch pub := k.VerifyKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: k.Kid,
+			Use: "sig",
+			Alg: k.Method.Alg(),
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Kid: k.Kid,
+			Use: "sig",
+			Alg: k.Method.Alg(),
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(pub),
+		}
+	}
+	// симметричный ключ
+	return JWK{}
+}
+
+// jwkThumbprint - RFC 7638, обязательные поля в лексикографическом порядке
+func jwkThumbprint(jwk JWK) (string, error) {
+	var canonical any
+	switch jwk.Kty {
+	case "RSA":
+		canonical = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "OKP":
+		canonical = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	default:
+		return "", fmt.Errorf("unsupported jwk kty: %s", jwk.Kty)
+	}
+	data, err := json.Marshal(canonical)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
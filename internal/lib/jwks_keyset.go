@@ -0,0 +1,146 @@
+package lib
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTKeysetManifestFile - описание набора ключей в каталоге AUTH_JWT_KEYS_DIR,
+// рядом лежат PEM-файлы приватных ключей
+const JWTKeysetManifestFile = "keyset.json"
+
+// JWTLegacyKid - запись набора с прежним HS256-секретом (AUTH_SECRET_KEY) в файле.
+// Только проверяет токены без kid, выпущенные до перехода на набор ключей, и никогда не подписывает
+const JWTLegacyKid = "legacy-hs256"
+
+type JWTKeysetManifest struct {
+	Active string           `json:"active"`
+	Keys   []JWTKeysetEntry `json:"keys"`
+}
+
+type JWTKeysetEntry struct {
+	Kid         string     `json:"kid"`
+	Alg         string     `json:"alg"`
+	File        string     `json:"file"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RetiredAt   *time.Time `json:"retired_at,omitempty"`   // когда перестал подписывать
+	VerifyUntil *time.Time `json:"verify_until,omitempty"` // до какого момента принимаются выпущенные им токены
+}
+
+func ReadJWTKeysetManifest(dir string) (JWTKeysetManifest, error) {
+	manifest := JWTKeysetManifest{}
+	data, err := os.ReadFile(filepath.Join(dir, JWTKeysetManifestFile))
+	if err != nil {
+		return manifest, err
+	}
+	err = json.Unmarshal(data, &manifest)
+	return manifest, err
+}
+
+// WriteJWTKeysetManifest пишет через временный файл, чтобы сервер не прочитал его наполовину
+func WriteJWTKeysetManifest(dir string, manifest JWTKeysetManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, JWTKeysetManifestFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, JWTKeysetManifestFile))
+}
+
+// LoadJWTKeySetFromDir загружает активный ключ и все ключи, срок проверки которых не истек
+func LoadJWTKeySetFromDir(dir string) (*JWTKeySet, error) {
+	ks := &JWTKeySet{}
+	if err := ks.ReloadFromDir(dir); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// ReloadFromDir перечитывает каталог и атомарно подменяет набор ключей
+func (ks *JWTKeySet) ReloadFromDir(dir string) error {
+	manifest, err := ReadJWTKeysetManifest(dir)
+	if err != nil {
+		return fmt.Errorf("read keyset manifest: %w", err)
+	}
+
+	now := time.Now()
+	keys := make(map[string]*JWTKey, len(manifest.Keys))
+	var signing, noKid *JWTKey
+	for _, entry := range manifest.Keys {
+		if entry.VerifyUntil != nil && entry.VerifyUntil.Before(now) {
+			continue
+		}
+		pemData, err := os.ReadFile(filepath.Join(dir, entry.File))
+		if err != nil {
+			return fmt.Errorf("read key %s: %w", entry.Kid, err)
+		}
+		if entry.Kid == JWTLegacyKid {
+			if entry.Kid == manifest.Active {
+				return fmt.Errorf("legacy key %s cannot be active", entry.Kid)
+			}
+			noKid, err = LoadJWTKey(entry.Alg, string(pemData), "", "")
+			if err != nil {
+				return fmt.Errorf("load key %s: %w", entry.Kid, err)
+			}
+			continue
+		}
+		key, err := ParseJWTPrivateKey(entry.Alg, pemData, entry.Kid)
+		if err != nil {
+			return fmt.Errorf("parse key %s: %w", entry.Kid, err)
+		}
+		keys[key.Kid] = key
+		if entry.Kid == manifest.Active {
+			signing = key
+		}
+	}
+	if signing == nil {
+		return fmt.Errorf("active key %q not found in keyset", manifest.Active)
+	}
+
+	ks.mu.Lock()
+	ks.signing = signing
+	ks.keys = keys
+	ks.noKid = noKid
+	ks.mu.Unlock()
+	return nil
+}
+
+// GenerateJWTPrivateKeyPEM создает новый приватный ключ для RS256 или EdDSA в PKCS#8 PEM
+func GenerateJWTPrivateKeyPEM(alg string) ([]byte, error) {
+	var privateKey any
+	switch alg {
+	case jwt.SigningMethodRS256.Alg():
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		privateKey = key
+	case jwt.SigningMethodEdDSA.Alg():
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		privateKey = key
+	default:
+		return nil, fmt.Errorf("unsupported jwt alg for keyset: %s", alg)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
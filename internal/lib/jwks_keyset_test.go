@@ -0,0 +1,175 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testIssuer = "test"
+
+// writeTestKeyset пишет в dir PEM-ключи EdDSA для kids и legacy-секрет, затем манифест
+func writeTestKeyset(t *testing.T, dir string, manifest JWTKeysetManifest) map[string]*JWTKey {
+	t.Helper()
+
+	keys := make(map[string]*JWTKey)
+	for i, entry := range manifest.Keys {
+		file := entry.Kid + ".pem"
+		var data []byte
+		if entry.Kid == JWTLegacyKid {
+			file = entry.Kid + ".secret"
+			data = []byte("legacy-secret")
+		} else {
+			pemData, err := GenerateJWTPrivateKeyPEM("EdDSA")
+			if err != nil {
+				t.Fatal(err)
+			}
+			key, err := ParseJWTPrivateKey("EdDSA", pemData, entry.Kid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			keys[entry.Kid] = key
+			data = pemData
+		}
+		if err := os.WriteFile(filepath.Join(dir, file), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		manifest.Keys[i].File = file
+	}
+	if err := WriteJWTKeysetManifest(dir, manifest); err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func signTestToken(t *testing.T, key *JWTKey) string {
+	t.Helper()
+	token, err := CreateJWT(JWTClaims{UserId: 1, Iss: testIssuer, Jti: "jti"}, &JWTKeySet{signing: key}, time.Minute, "access")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestReloadFromDirVerifiesTokens(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	dir := t.TempDir()
+	keys := writeTestKeyset(t, dir, JWTKeysetManifest{
+		Active: "k2",
+		Keys: []JWTKeysetEntry{
+			{Kid: "k2", Alg: "EdDSA"},
+			{Kid: "k1", Alg: "EdDSA", RetiredAt: &past, VerifyUntil: &future},
+			{Kid: "k0", Alg: "EdDSA", RetiredAt: &past, VerifyUntil: &past},
+			{Kid: JWTLegacyKid, Alg: "HS256", RetiredAt: &past, VerifyUntil: &future},
+		},
+	})
+	ks, err := LoadJWTKeySetFromDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacy, err := LoadJWTKey("HS256", "legacy-secret", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacyWithKid, err := LoadJWTKey("HS256", "legacy-secret", "", JWTLegacyKid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown := writeTestKeyset(t, t.TempDir(), JWTKeysetManifest{Active: "k9", Keys: []JWTKeysetEntry{{Kid: "k9", Alg: "EdDSA"}}})["k9"]
+	forged := writeTestKeyset(t, t.TempDir(), JWTKeysetManifest{Active: "k2", Keys: []JWTKeysetEntry{{Kid: "k2", Alg: "EdDSA"}}})["k2"]
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "active key", token: signTestToken(t, keys["k2"])},
+		{name: "retired key within verify_until", token: signTestToken(t, keys["k1"])},
+		{name: "retired key after verify_until", token: signTestToken(t, keys["k0"]), wantErr: true},
+		{name: "legacy secret without kid", token: signTestToken(t, legacy)},
+		{name: "legacy secret with its kid", token: signTestToken(t, legacyWithKid), wantErr: true},
+		{name: "unknown kid", token: signTestToken(t, unknown), wantErr: true},
+		{name: "known kid signed by another key", token: signTestToken(t, forged), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseJWT(tt.token, ks, testIssuer)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if signing := ks.Signing(); signing.Kid != "k2" {
+		t.Fatalf("signing kid %q, want k2", signing.Kid)
+	}
+	for _, jwk := range ks.JWKS().Keys {
+		if jwk.Kid == JWTLegacyKid || jwk.Kid == "k0" {
+			t.Fatalf("jwks publishes %s", jwk.Kid)
+		}
+	}
+}
+
+func TestReloadFromDirRejectsInvalidManifest(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		manifest JWTKeysetManifest
+	}{
+		{name: "legacy key is active", manifest: JWTKeysetManifest{Active: JWTLegacyKid, Keys: []JWTKeysetEntry{{Kid: JWTLegacyKid, Alg: "HS256", VerifyUntil: &future}}}},
+		{name: "active key is missing", manifest: JWTKeysetManifest{Active: "k2", Keys: []JWTKeysetEntry{{Kid: "k1", Alg: "EdDSA"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestKeyset(t, dir, tt.manifest)
+			if _, err := LoadJWTKeySetFromDir(dir); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestReloadFromDirDropsRemovedKeys(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	dir := t.TempDir()
+	keys := writeTestKeyset(t, dir, JWTKeysetManifest{
+		Active: "k2",
+		Keys: []JWTKeysetEntry{
+			{Kid: "k2", Alg: "EdDSA"},
+			{Kid: "k1", Alg: "EdDSA", RetiredAt: &past, VerifyUntil: &future},
+		},
+	})
+	ks, err := LoadJWTKeySetFromDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := signTestToken(t, keys["k1"])
+	if _, err := parseJWT(token, ks, testIssuer); err != nil {
+		t.Fatalf("before reload: %v", err)
+	}
+
+	manifest, err := ReadJWTKeysetManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest.Keys = manifest.Keys[:1]
+	if err := WriteJWTKeysetManifest(dir, manifest); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.ReloadFromDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseJWT(token, ks, testIssuer); err == nil {
+		t.Fatal("token of removed key still verifies after reload")
+	}
+}
@@ -17,7 +17,7 @@ type JWTClaims struct {
 	Exp      int64  `json:"exp"`
 }
 
-func CreateJWT(claim JWTClaims, secretKey string, exp time.Duration, typ string) (string, error) {
+func CreateJWT(claim JWTClaims, keys *JWTKeySet, exp time.Duration, typ string) (string, error) {
 
 	var claims = jwt.MapClaims{
 		"iss":       claim.Iss,
@@ -29,133 +29,140 @@ func CreateJWT(claim JWTClaims, secretKey string, exp time.Duration, typ string)
 		"user_name": claim.UserName,
 		"role_id":   claim.RoleId,
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString([]byte(secretKey))
+	key := keys.Signing()
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.Kid != "" {
+		token.Header["kid"] = key.Kid
+	}
+	signedToken, err := token.SignedString(key.SignKey)
 	if err != nil {
 		return "", err
 	}
 	return signedToken, nil
 }
 
-func GetClaimsFromRefreshToken(token, secretKey string, issuer string) (JWTClaims, error) {
+// parseJWT проверяет подпись ключом, выбранным по kid из заголовка
+func parseJWT(token string, keys *JWTKeySet, issuer string) (jwt.MapClaims, error) {
 	parsedToken, err := jwt.Parse(token,
-		func(_ *jwt.Token) (interface{}, error) { return []byte(secretKey), nil },
-		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}),
+		func(t *jwt.Token) (interface{}, error) {
+			kid, _ := t.Header["kid"].(string)
+			key, ok := keys.VerifyKey(kid)
+			if !ok {
+				return nil, fmt.Errorf("unknown kid: %s", kid)
+			}
+			// алгоритм токена должен совпадать с алгоритмом ключа
+			if t.Method.Alg() != key.Method.Alg() {
+				return nil, fmt.Errorf("unexpected signing method: %s", t.Method.Alg())
+			}
+			return key.VerifyKey, nil
+		},
+		jwt.WithValidMethods(keys.Methods()),
 		jwt.WithIssuer(issuer),
 		jwt.WithExpirationRequired(),
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := parsedToken.Claims.(jwt.MapClaims)
+	if !ok || !parsedToken.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+	return claims, nil
+}
+
+func GetClaimsFromRefreshToken(token string, keys *JWTKeySet, issuer string) (JWTClaims, error) {
 	res := JWTClaims{}
+	claims, err := parseJWT(token, keys, issuer)
 	if err != nil {
 		return res, err
 	}
 
-	if claims, ok := parsedToken.Claims.(jwt.MapClaims); ok && parsedToken.Valid {
-		if typ, ok := claims["typ"].(string); !ok || typ != "refresh" {
-			return res, fmt.Errorf("invalid token type")
-		}
-
-		userId, ok := claims["user_id"].(float64)
-		if !ok {
-			return res, fmt.Errorf("user_id not found or invalid")
-		}
-		res.UserId = int64(userId)
-
-		userName, ok := claims["user_name"].(string)
-		if !ok {
-			return res, fmt.Errorf("user_name not found or invalid")
-		}
-		res.UserName = userName
-
-		jti, ok := claims["jti"].(string)
-		if !ok {
-			return res, fmt.Errorf("jti not found or invalid")
-		}
-		res.Jti = jti
-
-		roleId, ok := claims["role_id"].(float64)
-		if !ok {
-			return res, fmt.Errorf("role_id not found or invalid")
-		}
-		res.RoleId = int64(roleId)
-		if res.UserId == 0 {
-			return res, fmt.Errorf("user_id not found in token claims")
-		}
-		return res, nil
-	} else {
-		return res, fmt.Errorf("invalid token")
+	if typ, ok := claims["typ"].(string); !ok || typ != "refresh" {
+		return res, fmt.Errorf("invalid token type")
+	}
+
+	userId, ok := claims["user_id"].(float64)
+	if !ok {
+		return res, fmt.Errorf("user_id not found or invalid")
 	}
+	res.UserId = int64(userId)
+
+	userName, ok := claims["user_name"].(string)
+	if !ok {
+		return res, fmt.Errorf("user_name not found or invalid")
+	}
+	res.UserName = userName
+
+	jti, ok := claims["jti"].(string)
+	if !ok {
+		return res, fmt.Errorf("jti not found or invalid")
+	}
+	res.Jti = jti
+
+	roleId, ok := claims["role_id"].(float64)
+	if !ok {
+		return res, fmt.Errorf("role_id not found or invalid")
+	}
+	res.RoleId = int64(roleId)
+	if res.UserId == 0 {
+		return res, fmt.Errorf("user_id not found in token claims")
+	}
+	return res, nil
 }
 
-func GetUserIdFromAccessToken(token, secretKey string, issuer string) (int64, error) {
-	parsedToken, err := jwt.Parse(token,
-		func(_ *jwt.Token) (interface{}, error) { return []byte(secretKey), nil },
-		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}),
-		jwt.WithIssuer(issuer),
-		jwt.WithExpirationRequired(),
-	)
+func GetUserIdFromAccessToken(token string, keys *JWTKeySet, issuer string) (int64, error) {
+	claims, err := parseJWT(token, keys, issuer)
 	if err != nil {
 		return 0, err
 	}
 
-	if claims, ok := parsedToken.Claims.(jwt.MapClaims); ok && parsedToken.Valid {
-		userIdFloat, ok := claims["user_id"].(float64)
-		if !ok {
-			return 0, fmt.Errorf("user_id not found in token claims")
-		}
-		if claims["typ"] != "access" {
-			return 0, fmt.Errorf("invalid token type")
-		}
-		userId := int64(userIdFloat)
-		return userId, nil
-	} else {
-		return 0, fmt.Errorf("invalid token")
+	userIdFloat, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, fmt.Errorf("user_id not found in token claims")
+	}
+	if claims["typ"] != "access" {
+		return 0, fmt.Errorf("invalid token type")
 	}
+	userId := int64(userIdFloat)
+	return userId, nil
 }
 
-func GetClaimsFromAccessToken(token, secretKey string, issuer string) (JWTClaims, error) {
-	parsedToken, err := jwt.Parse(token,
-		func(_ *jwt.Token) (interface{}, error) { return []byte(secretKey), nil },
-		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}),
-		jwt.WithIssuer(issuer),
-		jwt.WithExpirationRequired(),
-	)
+func GetClaimsFromAccessToken(token string, keys *JWTKeySet, issuer string) (JWTClaims, error) {
 	res := JWTClaims{}
+	claims, err := parseJWT(token, keys, issuer)
 	if err != nil {
 		return res, err
 	}
 
-	if claims, ok := parsedToken.Claims.(jwt.MapClaims); ok && parsedToken.Valid {
-		if typ, ok := claims["typ"].(string); !ok || typ != "access" {
-			return res, fmt.Errorf("invalid token type")
-		}
-
-		userId, ok := claims["user_id"].(float64)
-		if !ok {
-			return res, fmt.Errorf("user_id not found or invalid")
-		}
-		res.UserId = int64(userId)
-
-		jti, ok := claims["jti"].(string)
-		if !ok {
-			return res, fmt.Errorf("jti	not found or invalid")
-		}
-		res.Jti = jti
-
-		userName, ok := claims["user_name"].(string)
-		if !ok {
-			return res, fmt.Errorf("user_name not found or invalid")
-		}
-		res.UserName = userName
-		roleId, ok := claims["role_id"].(float64)
-		if !ok {
-			return res, fmt.Errorf("role_id not found or invalid")
-		}
-		res.RoleId = int64(roleId)
-		if res.UserId == 0 {
-			return res, fmt.Errorf("user_id not found in token claims")
-		}
-		return res, nil
-	} else {
-		return res, fmt.Errorf("invalid token")
+	if typ, ok := claims["typ"].(string); !ok || typ != "access" {
+		return res, fmt.Errorf("invalid token type")
+	}
+
+	userId, ok := claims["user_id"].(float64)
+	if !ok {
+		return res, fmt.Errorf("user_id not found or invalid")
+	}
+	res.UserId = int64(userId)
+
+	jti, ok := claims["jti"].(string)
+	if !ok {
+		return res, fmt.Errorf("jti	not found or invalid")
+	}
+	res.Jti = jti
+
+	userName, ok := claims["user_name"].(string)
+	if !ok {
+		return res, fmt.Errorf("user_name not found or invalid")
+	}
+	res.UserName = userName
+	roleId, ok := claims["role_id"].(float64)
+	if !ok {
+		return res, fmt.Errorf("role_id not found or invalid")
+	}
+	res.RoleId = int64(roleId)
+	if res.UserId == 0 {
+		return res, fmt.Errorf("user_id not found in token claims")
 	}
+	return res, nil
 }
@@ -11,6 +11,8 @@ type PrometheusType struct {
 	Registry     *prometheus.Registry
 	CounterVec   *prometheus.CounterVec
 	HistogramVec *prometheus.HistogramVec
+	// запросы, отклоненные middleware.RateLimit
+	RateLimitRejected *prometheus.CounterVec
 }
 
 func NewPromRegistry(log *slog.Logger) PrometheusType {
@@ -30,12 +32,20 @@ func NewPromRegistry(log *slog.Logger) PrometheusType {
 		},
 		[]string{"method", "route", "statusCode", "originalUrl"},
 	)
+	rateLimitRejected := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_rate_limit_rejected_total",
+			Help: "Total number of HTTP requests rejected by rate limiter",
+		},
+		[]string{"limiter", "key_by"},
+	)
 
 	registry.MustRegister(
 		collectors.NewGoCollector(),
 		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
 		httpRequestDuration,
 		httpRequestCounter,
+		rateLimitRejected,
 	)
 	log.Info("init prometheus registry")
 
@@ -43,5 +53,7 @@ func NewPromRegistry(log *slog.Logger) PrometheusType {
 		Registry:     registry,
 		CounterVec:   httpRequestCounter,
 		HistogramVec: httpRequestDuration,
+
+		RateLimitRejected: rateLimitRejected,
 	}
 }
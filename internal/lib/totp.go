@@ -0,0 +1,98 @@
+package lib
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	totpPeriod = 30 // секунд, стандартное значение приложений-аутентификаторов
+	totpSkew   = 1  // допускаем соседние интервалы из-за рассинхронизации часов
+)
+
+// TotpKey - новый секрет TOTP для привязки приложения-аутентификатора
+type TotpKey struct {
+	Secret string // base32
+	URL    string // otpauth://totp/...
+	QRPng  string // base64 PNG с QR-кодом URL
+}
+
+func GenerateTotpKey(issuer string, account string) (TotpKey, error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      issuer,
+		AccountName: account,
+		Period:      totpPeriod,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return TotpKey{}, err
+	}
+
+	img, err := key.Image(256, 256)
+	if err != nil {
+		return TotpKey{}, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return TotpKey{}, err
+	}
+
+	return TotpKey{
+		Secret: key.Secret(),
+		URL:    key.URL(),
+		QRPng:  base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// ValidateTotp проверяет код (RFC 6238) и возвращает номер интервала, которому он соответствует,
+// чтобы вызывающий мог запретить повторное использование кода
+func ValidateTotp(code string, secret string, now time.Time) (int64, bool) {
+	step := now.Unix() / totpPeriod
+	for i := -totpSkew; i <= totpSkew; i++ {
+		s := step + int64(i)
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(s*totpPeriod, 0), totp.ValidateOpts{
+			Period:    totpPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// GenerateRecoveryCodes возвращает n одноразовых кодов вида xxxxx-xxxxx
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for range n {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		h := hex.EncodeToString(b)
+		codes = append(codes, h[:5]+"-"+h[5:])
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode - коды случайные и длинные, поэтому достаточно sha256 без соли
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,56 @@
+package lib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+func TestValidateTotp(t *testing.T) {
+	const secret = "JBSWY3DPEHPK3PXP"
+	now := time.Unix(1_700_000_000, 0)
+	step := now.Unix() / totpPeriod
+
+	codeAt := func(s int64) string {
+		code, err := totp.GenerateCodeCustom(secret, time.Unix(s*totpPeriod, 0), totp.ValidateOpts{
+			Period:    totpPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		secret   string
+		wantStep int64
+		wantOk   bool
+	}{
+		{name: "current step", code: codeAt(step), secret: secret, wantStep: step, wantOk: true},
+		{name: "previous step within skew", code: codeAt(step - 1), secret: secret, wantStep: step - 1, wantOk: true},
+		{name: "next step within skew", code: codeAt(step + 1), secret: secret, wantStep: step + 1, wantOk: true},
+		{name: "two steps back is outside skew", code: codeAt(step - 2), secret: secret},
+		{name: "two steps ahead is outside skew", code: codeAt(step + 2), secret: secret},
+		{name: "code of another secret", code: codeAt(step), secret: "KRSXG5CTMVRXEZLU"},
+		{name: "empty code", code: "", secret: secret},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := ValidateTotp(tt.code, tt.secret, now)
+			if ok != tt.wantOk {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOk)
+			}
+			// номер интервала нужен для запрета повторного использования кода
+			if gotStep != tt.wantStep {
+				t.Fatalf("step = %d, want %d", gotStep, tt.wantStep)
+			}
+		})
+	}
+}
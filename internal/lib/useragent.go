@@ -0,0 +1,41 @@
+package lib
+
+import (
+	"strings"
+
+	"github.com/mssola/useragent"
+)
+
+// DeviceInfo - разобранный User-Agent для отображения в списке сессий
+type DeviceInfo struct {
+	Browser     string // имя и версия, например "Chrome 120.0.0.0"
+	BrowserName string // имя без версии, для сравнения устройств
+	OS          string
+	Device      string // desktop, mobile, tablet, bot
+}
+
+func ParseUserAgent(userAgent string) DeviceInfo {
+	info := DeviceInfo{}
+	if userAgent == "" {
+		return info
+	}
+
+	ua := useragent.New(userAgent)
+	name, version := ua.Browser()
+	info.Browser = strings.TrimSpace(name + " " + version)
+	info.BrowserName = name
+	info.OS = ua.OS()
+
+	switch {
+	case ua.Bot():
+		info.Device = "bot"
+	case strings.Contains(userAgent, "iPad") || strings.Contains(userAgent, "Tablet") ||
+		(strings.Contains(userAgent, "Android") && !strings.Contains(userAgent, "Mobile")):
+		info.Device = "tablet"
+	case ua.Mobile():
+		info.Device = "mobile"
+	default:
+		info.Device = "desktop"
+	}
+	return info
+}
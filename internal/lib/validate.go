@@ -1,6 +1,7 @@
 package lib
 
 import (
+	"net/mail"
 	"reflect"
 	"strings"
 
@@ -62,6 +63,28 @@ func PhoneValidatorKZ(fl validator.FieldLevel) bool {
 	}
 }
 
+// ContactAddressValidator проверяет адрес по полю Type той же структуры:
+// phone - телефон KZ, email - адрес без имени и угловых скобок
+func ContactAddressValidator(fl validator.FieldLevel) bool {
+	if fl.Field().Kind() != reflect.String {
+		return false
+	}
+	address := fl.Field().String()
+	channel := fl.Parent().FieldByName("Type")
+	if !channel.IsValid() || channel.Kind() != reflect.String {
+		return false
+	}
+	switch channel.String() {
+	case "phone":
+		return isValidPhoneKZ(address)
+	case "email":
+		parsed, err := mail.ParseAddress(address)
+		return err == nil && parsed.Address == address
+	default:
+		return false
+	}
+}
+
 func ExtractBearerToken(c fiber.Ctx) (string, *errorsApp.HttpError) {
 	auth := c.Get("Authorization")
 	if auth == "" {
@@ -0,0 +1,41 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func TestContactAddressValidator(t *testing.T) {
+	type request struct {
+		Type    string `validate:"required,oneof=phone email"`
+		Address string `validate:"required,contactAddress"`
+	}
+	tests := []struct {
+		name    string
+		request request
+		wantErr bool
+	}{
+		{name: "phone", request: request{Type: "phone", Address: "77012345678"}},
+		{name: "phone too short", request: request{Type: "phone", Address: "7701234567"}, wantErr: true},
+		{name: "phone not kz", request: request{Type: "phone", Address: "87012345678"}, wantErr: true},
+		{name: "phone with email type", request: request{Type: "email", Address: "77012345678"}, wantErr: true},
+		{name: "email", request: request{Type: "email", Address: "test@mail.com"}},
+		{name: "email without domain", request: request{Type: "email", Address: "test@"}, wantErr: true},
+		{name: "email with display name", request: request{Type: "email", Address: "Test <test@mail.com>"}, wantErr: true},
+		{name: "email with phone type", request: request{Type: "phone", Address: "test@mail.com"}, wantErr: true},
+	}
+
+	validate := validator.New()
+	if err := validate.RegisterValidation("contactAddress", ContactAddressValidator); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate.Struct(tt.request)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/guregu/null/v6"
+)
+
+type AuthEventType string
+
+const (
+	AuthEventLogin          AuthEventType = "login"
+	AuthEventRefresh        AuthEventType = "refresh"
+	AuthEventLogout         AuthEventType = "logout"
+	AuthEventLogoutAll      AuthEventType = "logout_all"
+	AuthEventSessionRevoke  AuthEventType = "session_revoke"
+	AuthEventPasswordChange AuthEventType = "password_change"
+	AuthEventPasswordReset  AuthEventType = "password_reset"
+	AuthEventVerify         AuthEventType = "verify"
+)
+
+const (
+	AuthEventSuccess = "success"
+	AuthEventFailure = "failure"
+)
+
+type AuthEventEntity struct {
+	Id          int64         `db:"id"`
+	User_id     null.Int      `db:"user_id"` // пусто, если пользователь не найден
+	Event_type  AuthEventType `db:"event_type"`
+	Outcome     string        `db:"outcome"`
+	Reason      null.String   `db:"reason"`
+	Identity    null.String   `db:"identity"` // email/телефон из запроса
+	Ip          null.String   `db:"ip"`
+	User_agent  null.String   `db:"user_agent"`
+	Create_date time.Time     `db:"create_date"`
+}
+
+// AuthEventsFilter - фильтры и пагинация журнала, нулевые значения не фильтруют
+type AuthEventsFilter struct {
+	UserId    null.Int
+	EventType string
+	Outcome   string
+	Ip        string
+	Identity  string
+	UserAgent string
+	From      null.Time
+	To        null.Time
+	Limit     int
+	Offset    int
+}
@@ -17,4 +17,20 @@ type UserEntity struct {
 	Create_date       time.Time   `db:"create_date"`
 	Email_verified_at null.Time   `db:"email_verified_at"`
 	Phone_verified_at null.Time   `db:"phone_verified_at"`
+	Blocked_at        null.Time   `db:"blocked_at"`
+	Totp_secret       null.String `db:"totp_secret"` // зашифрован AUTH_TOTP_ENCRYPTION_KEY
+	Totp_enabled_at   null.Time   `db:"totp_enabled_at"`
+	// запрос на удаление: до истечения льготного периода вход восстанавливает аккаунт
+	Deletion_requested_at null.Time `db:"deletion_requested_at"`
+	Deleted_at            null.Time `db:"deleted_at"` // строка обезличена
+}
+
+// UsersFilter - фильтры и пагинация для списка пользователей, нулевые значения не фильтруют
+type UsersFilter struct {
+	Verified    null.Bool // подтвержден email или телефон
+	RoleName    string
+	CreatedFrom null.Time
+	CreatedTo   null.Time
+	Limit       int
+	Offset      int
 }
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/guregu/null/v6"
+)
+
+type WebauthnCredentialEntity struct {
+	Id               int64     `db:"id"`
+	User_id          int64     `db:"user_id"`
+	Credential_id    []byte    `db:"credential_id"`
+	Public_key       []byte    `db:"public_key"`
+	Attestation_type string    `db:"attestation_type"`
+	Aaguid           []byte    `db:"aaguid"`
+	Sign_count       int64     `db:"sign_count"`
+	Transports       []string  `db:"transports"`
+	User_present     bool      `db:"user_present"`
+	User_verified    bool      `db:"user_verified"`
+	Backup_eligible  bool      `db:"backup_eligible"`
+	Backup_state     bool      `db:"backup_state"`
+	Name             string    `db:"name"`
+	Last_used_at     null.Time `db:"last_used_at"`
+	Changed_date     time.Time `db:"changed_date"`
+	Create_date      time.Time `db:"create_date"`
+}
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS "permissions" (
+ id BIGINT GENERATED BY DEFAULT AS IDENTITY  PRIMARY KEY,
+ name TEXT NOT NULL UNIQUE,
+ description TEXT NOT NULL,
+ changed_date TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP, 
+ create_date TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS "role_permissions" (
+ role_id BIGINT REFERENCES roles(id) ON DELETE CASCADE NOT NULL,
+ permission_id BIGINT REFERENCES permissions(id) ON DELETE CASCADE NOT NULL,
+ create_date TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+ PRIMARY KEY (role_id, permission_id)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN deletion_requested_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMPTZ;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS auth_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    event_type TEXT NOT NULL,
+    outcome TEXT NOT NULL,
+    reason TEXT,
+    identity TEXT,
+    ip TEXT,
+    user_agent TEXT,
+    create_date TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS auth_events_user_id_idx ON auth_events (user_id, create_date);
+CREATE INDEX IF NOT EXISTS auth_events_create_date_idx ON auth_events (create_date);
@@ -0,0 +1,3 @@
+DELETE FROM "role_permissions" WHERE role_id IN (1, 2) AND permission_id IN (1, 2, 3, 4);
+
+DELETE FROM "permissions" WHERE id IN (1, 2, 3, 4);
\ No newline at end of file
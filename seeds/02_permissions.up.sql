@@ -0,0 +1,22 @@
+INSERT INTO "permissions"
+(id, name, description)
+VALUES 
+(1, 'users:read', 'Read users'),
+(2, 'users:write', 'Change users and their roles'),
+(3, 'users:delete', 'Delete and block users'),
+(4, 'sessions:revoke', 'Revoke sessions of any user')
+ON CONFLICT (id) DO NOTHING;
+
+SELECT setval(pg_get_serial_sequence('permissions', 'id'), 
+              (SELECT MAX(id) FROM "permissions"));
+
+-- admin - все права, viewer - только чтение, user - без прав
+INSERT INTO "role_permissions"
+(role_id, permission_id)
+VALUES 
+(1, 1),
+(1, 2),
+(1, 3),
+(1, 4),
+(2, 1)
+ON CONFLICT DO NOTHING;